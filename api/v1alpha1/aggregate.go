@@ -0,0 +1,88 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Aggregate asks for figures over the records of a resource, in a row for
+// each distinct value of the GroupBy fields, or in a single row without
+// them: how many there are with Count, and the sum, average, least and
+// greatest value of the fields in Sum, Avg, Min and Max. Nulls count
+// towards Count only.
+type Aggregate struct {
+	GroupBy []string
+	Count   bool
+	Sum     []string
+	Avg     []string
+	Min     []string
+	Max     []string
+}
+
+// AggregateRow is a row of an Aggregate: the values of the fields it
+// groups by, and the figures asked for, by field. An average over no
+// values, or the least of none, is null.
+type AggregateRow struct {
+	Group map[string]any     `json:"group,omitempty"`
+	Count *int               `json:"count,omitempty"`
+	Sum   map[string]float64 `json:"sum,omitempty"`
+	Avg   map[string]any     `json:"avg,omitempty"`
+	Min   map[string]any     `json:"min,omitempty"`
+	Max   map[string]any     `json:"max,omitempty"`
+}
+
+// IsZero reports whether a asks for nothing.
+func (a Aggregate) IsZero() bool {
+	return !a.Count && len(a.Sum) == 0 && len(a.Avg) == 0 && len(a.Min) == 0 && len(a.Max) == 0
+}
+
+// Fields are the fields a reads.
+func (a Aggregate) Fields() []string {
+	fields := []string{}
+
+	for _, fs := range [][]string{a.GroupBy, a.Sum, a.Avg, a.Min, a.Max} {
+		for _, f := range fs {
+			if !slices.Contains(fields, f) {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	return fields
+}
+
+// Validate checks that a asks for something, and that each field it names
+// is one of schemas of a type it can use: numbers, text and refs to group
+// by and for the least and greatest, and numbers only to sum and average.
+func (a Aggregate) Validate(schemas []FieldSchema) error {
+	if a.IsZero() {
+		return fmt.Errorf("expected count, sum, avg, min or max")
+	}
+
+	checks := []struct {
+		param  string
+		fields []string
+		types  []string
+	}{
+		{"group_by", a.GroupBy, []string{"number", "text", "ref"}},
+		{"sum", a.Sum, []string{"number"}},
+		{"avg", a.Avg, []string{"number"}},
+		{"min", a.Min, []string{"number", "text", "ref"}},
+		{"max", a.Max, []string{"number", "text", "ref"}},
+	}
+
+	for _, check := range checks {
+		for _, field := range check.fields {
+			idx := slices.IndexFunc(schemas, func(fs FieldSchema) bool { return fs.Field == field && !fs.Dropped })
+			if idx < 0 {
+				return fmt.Errorf("unknown field %q for %s", field, check.param)
+			}
+
+			if t := BaseType(schemas[idx].Type); !slices.Contains(check.types, t) {
+				return fmt.Errorf("field %q is a %s, which %s cannot take", field, t, check.param)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package v1alpha1
+
+// The ops of a bulk request.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// BulkOp is one operation of a bulk request. A create takes Record, an
+// update the Id of the record and the fields of Record to change, and a
+// delete the Id only.
+type BulkOp struct {
+	Op     string   `json:"op"`
+	Id     string   `json:"_id,omitempty"`
+	Record Resource `json:"record,omitempty"`
+}
+
+// BulkResult is what became of the BulkOp in the same place of a bulk
+// request: its status and the id of its record, with the record an update
+// left, or the problem that failed it.
+type BulkResult struct {
+	Op     string   `json:"op"`
+	Status int      `json:"status"`
+	Id     string   `json:"_id,omitempty"`
+	Record Resource `json:"record,omitempty"`
+	Error  *Problem `json:"error,omitempty"`
+}
@@ -0,0 +1,27 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+)
+
+var now = time.Now
+
+// EvalDefault evaluates the default expression of a field. An expression is
+// either a literal in the field's storage encoding or now(), which yields the
+// current time as unix seconds for numbers and RFC 3339 for text.
+func EvalDefault(fs FieldSchema) (any, error) {
+	if fs.Default == "now()" {
+		t := now().UTC()
+		switch fs.Type {
+		case "number":
+			return float64(t.Unix()), nil
+		case "text":
+			return t.Format(time.RFC3339), nil
+		default:
+			return nil, fmt.Errorf("now() is not a valid default for field \"%s\" of type %s", fs.Field, fs.Type)
+		}
+	}
+
+	return FormatResourceField(fs, fs.Default)
+}
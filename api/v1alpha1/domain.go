@@ -1,14 +1,56 @@
 package v1alpha1
 
+// NullValue is the on-disk encoding of a null field. Cells that would
+// otherwise collide with it are escaped with an extra leading backslash.
+const NullValue = `\N`
+
+// Delete policies for ref fields, applied when the referenced record is
+// deleted. Restrict is the default.
+const (
+	OnDeleteRestrict = "restrict"
+	OnDeleteCascade  = "cascade"
+	OnDeleteSetNull  = "set-null"
+)
+
 type Resource map[string]any
 
 type Record []string
 
+// Neighbor is a record returned by a nearest-neighbor query along with its
+// distance from the query vector.
+type Neighbor struct {
+	Record   Resource `json:"record"`
+	Distance float64  `json:"distance"`
+}
+
 type FieldSchema struct {
-	Resource string
-	Field    string
-	Type     string
-	Min      float64
-	Max      float64
-	Regex    string
+	Id       string `json:"id,omitempty"`
+	Version  int    `json:"version,omitempty"`
+	Resource string `json:"resource"`
+	Field    string `json:"field"`
+	Type     string `json:"type"`
+	// Min and Max bound a number, unless both are zero. Of a file field,
+	// Max is the most bytes the file may hold, or no limit when zero.
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+	// Regex is the pattern text and refs must match. Of a file field, it is
+	// the pattern the MIME type sniffed from the content must match, like
+	// ^image/(png|jpeg)$.
+	Regex    string `json:"regex,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Nullable bool   `json:"nullable,omitempty"`
+	Default  string `json:"default,omitempty"`
+	OnDelete string `json:"on_delete,omitempty"`
+	// Dropped fields keep their column so later fields do not shift, but
+	// are no longer read or written.
+	Dropped bool `json:"-"`
+}
+
+// ResourceMeta describes a resource to clients: its live fields and the
+// actions the caller may take on at least some of its records.
+type ResourceMeta struct {
+	Name    string        `json:"name"`
+	Version int           `json:"version"`
+	Actions []string      `json:"actions"`
+	Fields  []FieldSchema `json:"fields"`
 }
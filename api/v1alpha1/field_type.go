@@ -0,0 +1,12 @@
+package v1alpha1
+
+import "strings"
+
+// BaseType strips any parameters from a field type, so ref:_users
+// becomes ref.
+func BaseType(t string) string {
+	if i := strings.IndexAny(t, ":("); i > 0 {
+		return t[:i]
+	}
+	return t
+}
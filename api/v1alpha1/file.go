@@ -0,0 +1,43 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// File is the value of a file field. The content itself lives in a blob
+// store under Hash; only this metadata is kept in the record.
+type File struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+	Hash     string `json:"hash"`
+}
+
+func formatFileRecordField(fs FieldSchema, v any) (string, error) {
+	switch f := v.(type) {
+	case nil:
+		return "", nil
+	case File:
+		bs, err := json.Marshal(f)
+		if err != nil {
+			return "", err
+		}
+		return string(bs), nil
+	default:
+		return "", fmt.Errorf("internal error: expected File for field '%s', got %T", fs.Field, v)
+	}
+}
+
+func formatFileResourceField(fs FieldSchema, strValue string) (any, error) {
+	if len(strValue) == 0 {
+		return nil, nil
+	}
+
+	var f File
+	if err := json.Unmarshal([]byte(strValue), &f); err != nil {
+		return nil, fmt.Errorf("invalid file metadata for field '%s': %w", fs.Field, err)
+	}
+
+	return f, nil
+}
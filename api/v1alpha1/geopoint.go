@@ -0,0 +1,179 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const earthRadiusKm = 6371.0088
+
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// BBox is a latitude/longitude rectangle. MinLon may be greater than MaxLon
+// for boxes that cross the antimeridian.
+type BBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+func (b BBox) Contains(p GeoPoint) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+}
+
+func (b BBox) Center() GeoPoint {
+	maxLon := b.MaxLon
+	if b.MinLon > maxLon {
+		maxLon += 360
+	}
+	lon := (b.MinLon + maxLon) / 2
+	if lon > 180 {
+		lon -= 360
+	}
+	return GeoPoint{Lat: (b.MinLat + b.MaxLat) / 2, Lon: lon}
+}
+
+// Distance is the great-circle distance between two points in kilometers.
+func Distance(a GeoPoint, b GeoPoint) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// RadiusBBox is the smallest box containing every point within radiusKm of
+// center.
+func RadiusBBox(center GeoPoint, radiusKm float64) BBox {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+
+	box := BBox{
+		MinLat: math.Max(-90, center.Lat-dLat),
+		MaxLat: math.Min(90, center.Lat+dLat),
+		MinLon: -180,
+		MaxLon: 180,
+	}
+
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box // the circle covers a pole
+	}
+
+	dLon := math.Asin(math.Sin(radiusKm/earthRadiusKm)/math.Cos(center.Lat*math.Pi/180)) * 180 / math.Pi
+	if math.IsNaN(dLon) || dLon >= 180 {
+		return box
+	}
+
+	box.MinLon = wrapLon(center.Lon - dLon)
+	box.MaxLon = wrapLon(center.Lon + dLon)
+
+	return box
+}
+
+func ValidGeoPoint(p GeoPoint) bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// ParseGeoPoint reads the "lat,lon" form used in storage and query strings.
+func ParseGeoPoint(s string) (GeoPoint, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return GeoPoint{}, fmt.Errorf("invalid point %q: expected lat,lon", s)
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return GeoPoint{}, fmt.Errorf("invalid latitude in %q", s)
+	}
+
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return GeoPoint{}, fmt.Errorf("invalid longitude in %q", s)
+	}
+
+	p := GeoPoint{Lat: lat, Lon: lon}
+
+	if !ValidGeoPoint(p) {
+		return GeoPoint{}, fmt.Errorf("point %q is out of range", s)
+	}
+
+	return p, nil
+}
+
+// ParseBBox reads the "minLon,minLat,maxLon,maxLat" form used by GeoJSON.
+func ParseBBox(s string) (BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return BBox{}, fmt.Errorf("invalid bbox %q: expected minLon,minLat,maxLon,maxLat", s)
+	}
+
+	vs := make([]float64, 4)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("invalid bbox %q", s)
+		}
+		vs[i] = v
+	}
+
+	box := BBox{MinLon: vs[0], MinLat: vs[1], MaxLon: vs[2], MaxLat: vs[3]}
+
+	if !ValidGeoPoint(GeoPoint{Lat: box.MinLat, Lon: box.MinLon}) || !ValidGeoPoint(GeoPoint{Lat: box.MaxLat, Lon: box.MaxLon}) || box.MinLat > box.MaxLat {
+		return BBox{}, fmt.Errorf("bbox %q is out of range", s)
+	}
+
+	return box, nil
+}
+
+func FormatGeoPoint(p GeoPoint) string {
+	return fmt.Sprintf("%g,%g", p.Lat, p.Lon)
+}
+
+func toGeoPoint(v any) (GeoPoint, bool) {
+	switch p := v.(type) {
+	case GeoPoint:
+		return p, true
+	case map[string]any:
+		lat, latOk := p["lat"].(float64)
+		lon, lonOk := p["lon"].(float64)
+		return GeoPoint{Lat: lat, Lon: lon}, latOk && lonOk && len(p) == 2
+	case []any:
+		if len(p) != 2 {
+			return GeoPoint{}, false
+		}
+		lat, latOk := p[0].(float64)
+		lon, lonOk := p[1].(float64)
+		return GeoPoint{Lat: lat, Lon: lon}, latOk && lonOk
+	case []float64:
+		if len(p) != 2 {
+			return GeoPoint{}, false
+		}
+		return GeoPoint{Lat: p[0], Lon: p[1]}, true
+	default:
+		return GeoPoint{}, false
+	}
+}
+
+func wrapLon(lon float64) float64 {
+	if lon < -180 {
+		return lon + 360
+	}
+	if lon > 180 {
+		return lon - 360
+	}
+	return lon
+}
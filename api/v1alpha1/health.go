@@ -0,0 +1,41 @@
+package v1alpha1
+
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// Health is the result of checking the components of the backend. It is
+// ok when every component is.
+type Health struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// ComponentHealth is the result of checking one component, with what
+// failed when it is not ok.
+type ComponentHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewHealth sums up the checks of components, a nil error meaning the
+// component is ok.
+func NewHealth(checks map[string]error) Health {
+	h := Health{
+		Status:     HealthOK,
+		Components: map[string]ComponentHealth{},
+	}
+
+	for component, err := range checks {
+		if err == nil {
+			h.Components[component] = ComponentHealth{Status: HealthOK}
+			continue
+		}
+
+		h.Status = HealthFailing
+		h.Components[component] = ComponentHealth{Status: HealthFailing, Error: err.Error()}
+	}
+
+	return h
+}
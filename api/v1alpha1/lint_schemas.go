@@ -0,0 +1,280 @@
+package v1alpha1
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// SchemaProblem is something wrong with a row of _schemas. Problems of
+// severity error keep the server from starting.
+type SchemaProblem struct {
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Resource string `json:"resource,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (p SchemaProblem) String() string {
+	s := p.Severity + ": "
+
+	if p.Line > 0 {
+		s = fmt.Sprintf("%d: %s", p.Line, s)
+	}
+
+	switch {
+	case len(p.Field) > 0:
+		s += p.Resource + "." + p.Field + ": "
+	case len(p.Resource) > 0:
+		s += p.Resource + ": "
+	}
+
+	return s + p.Message
+}
+
+// HasFatal reports whether any of the problems is an error.
+func HasFatal(problems []SchemaProblem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// LintSchemas reads the rows of _schemas and reports every problem it
+// finds, in line order. The error is only set when r cannot be read as CSV
+// at all.
+func LintSchemas(r io.Reader) ([]SchemaProblem, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	rows := []schemaRow{}
+	problems := []SchemaProblem{}
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			problems = append(problems, SchemaProblem{
+				Line:     parseErr.StartLine,
+				Severity: SeverityError,
+				Message:  parseErr.Err.Error(),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		rows = append(rows, schemaRow{line: line, rec: rec})
+	}
+
+	return lintSchemaRows(rows, problems), nil
+}
+
+// schemaRow is a row of _schemas along with the line it was read from.
+type schemaRow struct {
+	line int
+	rec  Record
+}
+
+func lintSchemaRows(rows []schemaRow, problems []SchemaProblem) []SchemaProblem {
+	type row struct {
+		line    int
+		version int
+		fs      FieldSchema
+	}
+
+	report := func(line int, severity string, fs FieldSchema, format string, args ...any) {
+		problems = append(problems, SchemaProblem{
+			Line:     line,
+			Severity: severity,
+			Resource: fs.Resource,
+			Field:    fs.Field,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	latest := map[string]row{}
+	order := []string{}
+
+	for _, sr := range rows {
+		line, rec := sr.line, sr.rec
+
+		if len(rec) < 5 {
+			report(line, SeverityError, FieldSchema{}, "expected at least 5 columns (_id, _v, resource, field, type), found %d", len(rec))
+			continue
+		}
+
+		if len(rec) > len(SchemaFields) {
+			report(line, SeverityWarning, FieldSchema{}, "found %d columns, only the first %d are read", len(rec), len(SchemaFields))
+		}
+
+		fs := ToFieldSchema(rec)
+
+		if len(fs.Id) == 0 {
+			report(line, SeverityError, fs, "row has no _id")
+			continue
+		}
+
+		version, err := strconv.Atoi(rec[1])
+		if err != nil || version < 0 {
+			report(line, SeverityError, fs, "_v %q is not a version number", rec[1])
+			continue
+		}
+
+		for _, col := range []struct {
+			i    int
+			name string
+		}{{5, "min"}, {6, "max"}} {
+			if col.i < len(rec) && len(rec[col.i]) > 0 {
+				if _, err := strconv.ParseFloat(rec[col.i], 64); err != nil {
+					report(line, SeverityError, fs, "%s %q is not a number", col.name, rec[col.i])
+				}
+			}
+		}
+
+		for _, col := range []struct {
+			i    int
+			name string
+		}{{8, "required"}, {9, "nullable"}, {12, "dropped"}} {
+			if col.i < len(rec) && len(rec[col.i]) > 0 {
+				if _, err := strconv.ParseBool(rec[col.i]); err != nil {
+					report(line, SeverityError, fs, "%s %q is not a boolean", col.name, rec[col.i])
+				}
+			}
+		}
+
+		prev, seen := latest[fs.Id]
+		switch {
+		case !seen:
+			order = append(order, fs.Id)
+		case version <= prev.version:
+			report(line, SeverityError, fs, "_id %q is already used on line %d", fs.Id, prev.line)
+			continue
+		case prev.fs.Resource != fs.Resource:
+			report(line, SeverityError, fs, "_id %q moves field from resource %q", fs.Id, prev.fs.Resource)
+			continue
+		}
+
+		latest[fs.Id] = row{line: line, version: version, fs: fs}
+	}
+
+	// what follows is about the schemas as they stand: the latest version of
+	// every row, in the order rows were first written
+
+	resources := map[string][]row{}
+	resourceOrder := []string{}
+
+	for _, id := range order {
+		r := latest[id]
+		if r.version == 0 {
+			continue // deleted
+		}
+		if _, ok := resources[r.fs.Resource]; !ok {
+			resourceOrder = append(resourceOrder, r.fs.Resource)
+		}
+		resources[r.fs.Resource] = append(resources[r.fs.Resource], r)
+	}
+
+	for _, id := range order {
+		r := latest[id]
+		if r.version == 0 || r.fs.Dropped {
+			continue
+		}
+
+		if err := ValidateFieldSchema(r.fs); err != nil {
+			for _, err := range unjoin(err) {
+				report(r.line, SeverityError, r.fs, "%v", err)
+			}
+		}
+
+		if target, ok := RefResource(r.fs); ok {
+			if _, ok := resources[target]; !ok {
+				report(r.line, SeverityError, r.fs, "references unknown resource %q", target)
+			}
+		}
+	}
+
+	for _, resource := range resourceOrder {
+		rows := resources[resource]
+
+		live := []row{}
+		names := map[string]int{}
+
+		for _, r := range rows {
+			if r.fs.Dropped {
+				continue
+			}
+			if line, ok := names[r.fs.Field]; ok {
+				report(r.line, SeverityError, r.fs, "field is already defined on line %d", line)
+				continue
+			}
+			names[r.fs.Field] = r.line
+			live = append(live, r)
+		}
+
+		for i, want := range []FieldSchema{{Field: "_id", Type: "text"}, {Field: "_v", Type: "number"}} {
+			if i >= len(rows) || rows[i].fs.Field != want.Field || rows[i].fs.Type != want.Type || rows[i].fs.Dropped {
+				line := rows[0].line
+				if i < len(rows) {
+					line = rows[i].line
+				}
+				report(line, SeverityError, FieldSchema{Resource: resource}, "column %d must be %s of type %s", i+1, want.Field, want.Type)
+			}
+		}
+
+		if len(live) <= 2 {
+			report(rows[0].line, SeverityWarning, FieldSchema{Resource: resource}, "resource has no fields besides _id and _v")
+		}
+	}
+
+	for _, resource := range []string{"_users", "_permissions"} {
+		if _, ok := resources[resource]; !ok {
+			problems = append(problems, SchemaProblem{
+				Severity: SeverityError,
+				Resource: resource,
+				Message:  "resource is required but not defined",
+			})
+		}
+	}
+
+	// problems that belong to no line go last
+	key := func(p SchemaProblem) int {
+		if p.Line == 0 {
+			return math.MaxInt
+		}
+		return p.Line
+	}
+
+	slices.SortStableFunc(problems, func(a, b SchemaProblem) int {
+		return cmp.Compare(key(a), key(b))
+	})
+
+	return problems
+}
+
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
@@ -0,0 +1,691 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// OpenAPI describes the records API of the given resources as an OpenAPI
+// 3.1 document. An operation is only described when some permission grants
+// it, and it asks for credentials unless a permission makes it public.
+func OpenAPI(schemas map[string][]FieldSchema, permissions []Resource) map[string]any {
+	paths := map[string]any{}
+	components := map[string]any{}
+
+	for _, resource := range slices.Sorted(maps.Keys(schemas)) {
+		name := TypeName(resource)
+		ref := map[string]any{"$ref": "#/components/schemas/" + resource}
+
+		collection := map[string]any{}
+		item := map[string]any{}
+
+		if op, ok := operation(permissions, resource, "read"); ok {
+			op["operationId"] = "list" + name
+			op["summary"] = "List " + resource
+			op["parameters"] = []any{
+				queryParameter("sort", "Comma-separated fields to sort the records by, each descending when it starts with -. Ties are broken by _id. Without it, records come in the order they were created."),
+				queryParameter("nulls", "Where null and empty values sort: last, the default, or first."),
+				queryParameter("collation", "How text compares when sorting: binary, the default, nocase, or a language like en or de."),
+				queryParameter("sort_by", "Field to sort the records by, ascending. Use sort instead."),
+				queryParameter("expand", "Comma-separated ref fields to replace with the records they reference."),
+				queryParameter("fields", "Comma-separated fields to return, or to leave out when each starts with -. _id is always returned."),
+			}
+			op["responses"] = responses(map[string]any{
+				"200": jsonResponse("The records.", map[string]any{"type": "array", "items": ref}),
+			}, "400", "401", "403", "404")
+			collection["get"] = op
+		}
+
+		if op, ok := operation(permissions, resource, "create"); ok {
+			op["operationId"] = "create" + name
+			op["summary"] = "Create a record of " + resource
+			op["requestBody"] = jsonBody(ref)
+			op["responses"] = responses(map[string]any{
+				"201": jsonResponse("The id of the new record.", map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"_id": map[string]any{"type": "string"}},
+					"required":   []string{"_id"},
+				}),
+			}, "400", "401", "403", "404")
+			collection["post"] = op
+		}
+
+		if op, ok := operation(permissions, resource, "read"); ok {
+			op["operationId"] = "get" + name
+			op["summary"] = "Get a record of " + resource
+			op["parameters"] = []any{
+				queryParameter("expand", "Comma-separated ref fields to replace with the records they reference."),
+				queryParameter("fields", "Comma-separated fields to return, or to leave out when each starts with -. _id is always returned."),
+			}
+			op["responses"] = responses(map[string]any{
+				"200": jsonResponse("The record.", ref),
+			}, "400", "401", "403", "404")
+			item["get"] = op
+		}
+
+		if op, ok := operation(permissions, resource, "update"); ok {
+			op["operationId"] = "update" + name
+			op["summary"] = "Replace a record of " + resource
+			op["requestBody"] = jsonBody(ref)
+			op["responses"] = responses(map[string]any{
+				"200": jsonResponse("The record as stored.", ref),
+			}, "400", "401", "403", "404")
+			item["put"] = op
+		}
+
+		if op, ok := operation(permissions, resource, "delete"); ok {
+			op["operationId"] = "delete" + name
+			op["summary"] = "Delete a record of " + resource
+			op["responses"] = responses(map[string]any{
+				"204": map[string]any{"description": "The record was deleted."},
+			}, "401", "403", "404", "409")
+			item["delete"] = op
+		}
+
+		if op, ok := operation(permissions, resource, "read"); ok {
+			op["operationId"] = "aggregate" + name
+			op["summary"] = "Aggregate records of " + resource
+			op["parameters"] = []any{
+				queryParameter("group_by", "Comma-separated fields to group the records by."),
+				queryParameter("count", "Whether to count the records of each group: true, the same as no value, or false."),
+				queryParameter("sum", "Comma-separated number fields to add up."),
+				queryParameter("avg", "Comma-separated number fields to average."),
+				queryParameter("min", "Comma-separated fields to find the least value of."),
+				queryParameter("max", "Comma-separated fields to find the greatest value of."),
+				queryParameter("near", "Only records within radius of this lat,lon."),
+				queryParameter("radius", "Kilometers from near."),
+				queryParameter("bbox", "Only records within this minLon,minLat,maxLon,maxLat."),
+				queryParameter("geo_field", "The geopoint field near and bbox apply to, by default the first."),
+			}
+			op["responses"] = responses(map[string]any{
+				"200": jsonResponse("A row for each group, or one for all the records the caller may read.", map[string]any{
+					"type":  "array",
+					"items": aggregateRowJSONSchema(),
+				}),
+			}, "400", "401", "403", "404")
+			paths["/api/"+resource+"/_aggregate"] = map[string]any{"get": op}
+		}
+
+		if op, ok := operation(permissions, resource, "read"); ok && hasType(schemas[resource], "vector") {
+			op["operationId"] = "nearest" + name
+			op["summary"] = "Find the records of " + resource + " nearest a vector"
+			op["requestBody"] = jsonBody(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"field":  map[string]any{"type": "string", "description": "The vector field to search, by default the first."},
+					"vector": map[string]any{"type": "array", "items": map[string]any{"type": "number"}},
+					"k":      map[string]any{"type": "integer", "description": "How many records to find, 10 by default."},
+					"metric": map[string]any{"enum": []string{MetricCosine, MetricL2}, "description": "How distance is measured, cosine by default."},
+				},
+				"required": []string{"vector"},
+			})
+			op["responses"] = responses(map[string]any{
+				"200": jsonResponse("The nearest records, nearest first.", map[string]any{
+					"type":  "array",
+					"items": neighborJSONSchema(ref),
+				}),
+			}, "400", "401", "403", "404")
+			paths["/api/"+resource+"/_knn"] = map[string]any{"post": op}
+		}
+
+		if files := fieldsOfType(schemas[resource], "file"); len(files) > 0 {
+			file := map[string]any{}
+
+			if op, ok := operation(permissions, resource, "update"); ok {
+				op["operationId"] = "upload" + name + "File"
+				op["summary"] = "Upload the file of a field of a record of " + resource
+				op["requestBody"] = map[string]any{
+					"required": true,
+					"content": map[string]any{
+						"multipart/form-data": map[string]any{"schema": map[string]any{
+							"type":       "object",
+							"properties": map[string]any{"file": map[string]any{"type": "string", "contentMediaType": "application/octet-stream"}},
+							"required":   []string{"file"},
+						}},
+					},
+				}
+				op["responses"] = responses(map[string]any{
+					"200": jsonResponse("The file as stored.", FieldJSONSchema(FieldSchema{Type: "file"})),
+				}, "400", "401", "403", "404", "413")
+				file["post"] = op
+			}
+
+			if op, ok := operation(permissions, resource, "read"); ok {
+				op["operationId"] = "download" + name + "File"
+				op["summary"] = "Download the file of a field of a record of " + resource
+				op["responses"] = responses(map[string]any{
+					"200": map[string]any{
+						"description": "The content of the file, of the type sniffed when it was uploaded.",
+						"content": map[string]any{
+							"*/*": map[string]any{"schema": map[string]any{"type": "string", "contentMediaType": "application/octet-stream"}},
+						},
+					},
+				}, "401", "403", "404")
+				file["get"] = op
+			}
+
+			if len(file) > 0 {
+				file["parameters"] = []any{
+					pathParameter("id", map[string]any{"type": "string"}),
+					pathParameter("field", map[string]any{"enum": files}),
+				}
+				paths["/api/"+resource+"/{id}/files/{field}"] = file
+			}
+		}
+
+		if op, ok := bulkOperation(permissions, resource); ok {
+			op["operationId"] = "bulk" + name
+			op["summary"] = "Create, update and delete records of " + resource
+			op["parameters"] = []any{
+				queryParameter("atomic", "When true, an op that fails fails them all and nothing is written."),
+			}
+			op["requestBody"] = jsonBody(map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"op":     map[string]any{"enum": []string{BulkCreate, BulkUpdate, BulkDelete}},
+						"_id":    map[string]any{"type": "string", "description": "Id of the record to update or delete."},
+						"record": ref,
+					},
+					"required": []string{"op"},
+				},
+			})
+			results := map[string]any{"type": "array", "items": bulkResultJSONSchema(ref)}
+			op["responses"] = responses(map[string]any{
+				"200": jsonResponse("The result of each op, in the order of the ops.", results),
+				"207": jsonResponse("The result of each op, in the order of the ops, when some failed.", results),
+			}, "400", "401", "404")
+			paths["/api/"+resource+"/_bulk"] = map[string]any{"post": op}
+		}
+
+		if len(collection) > 0 {
+			paths["/api/"+resource] = collection
+		}
+
+		if len(item) > 0 {
+			item["parameters"] = []any{pathParameter("id", map[string]any{"type": "string"})}
+			paths["/api/"+resource+"/{id}"] = item
+		}
+
+		if len(collection) > 0 || len(item) > 0 {
+			components[resource] = ResourceJSONSchema(schemas[resource])
+		}
+	}
+
+	// anyone may ask, and learns of the resources they may act on
+	if len(paths) > 0 {
+		metaRef := map[string]any{"$ref": "#/components/schemas/" + resourceMetaSchema}
+		security := []any{map[string]any{}, map[string]any{"basicAuth": []string{}}}
+
+		paths["/api/_meta/resources"] = map[string]any{"get": map[string]any{
+			"tags":        []string{"_meta"},
+			"security":    security,
+			"operationId": "listResources",
+			"summary":     "Describe the resources the caller may act on",
+			"responses": responses(map[string]any{
+				"200": jsonResponse("The resources, by name.", map[string]any{"type": "array", "items": metaRef}),
+			}),
+		}}
+		paths["/api/_meta/resources/{name}"] = map[string]any{
+			"parameters": []any{pathParameter("name", map[string]any{"type": "string"})},
+			"get": map[string]any{
+				"tags":        []string{"_meta"},
+				"security":    security,
+				"operationId": "getResource",
+				"summary":     "Describe a resource the caller may act on",
+				"responses": responses(map[string]any{
+					"200": jsonResponse("The resource.", metaRef),
+				}, "401", "403", "404"),
+			},
+		}
+
+		components[resourceMetaSchema] = ResourceMetaJSONSchema()
+	}
+
+	// resource names starting with _ are reserved, so no resource takes it
+	components[problemSchema] = ProblemJSONSchema()
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "backend",
+			"version": "v1alpha1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": components,
+			"responses": map[string]any{
+				"BadRequest":      problemResponse("The request does not match the schema."),
+				"Unauthorized":    problemResponse("Credentials are missing or wrong."),
+				"Forbidden":       problemResponse("The caller may not do this."),
+				"NotFound":        problemResponse("The resource or record does not exist."),
+				"Conflict":        problemResponse("Other records still reference the record."),
+				"TooLarge":        problemResponse("The content is over the limit of the field."),
+				"TooManyRequests": problemResponse("The caller must wait as long as Retry-After says before trying again."),
+			},
+			"securitySchemes": map[string]any{
+				"basicAuth": map[string]any{
+					"type":   "http",
+					"scheme": "basic",
+				},
+			},
+		},
+	}
+}
+
+// ResourceJSONSchema is the JSON Schema of a record. _id, _v and file fields
+// are read-only: the server sets them.
+func ResourceJSONSchema(fields []FieldSchema) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	for _, fs := range fields {
+		if fs.Dropped {
+			continue
+		}
+
+		prop := FieldJSONSchema(fs)
+		if fs.Field == "_id" || fs.Field == "_v" || BaseType(fs.Type) == "file" {
+			prop["readOnly"] = true
+		}
+		properties[fs.Field] = prop
+
+		if fs.Required && len(fs.Default) == 0 {
+			required = append(required, fs.Field)
+		}
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// FieldJSONSchema maps the type and constraints of a field to JSON Schema
+// keywords, following the rules ParseResource applies.
+func FieldJSONSchema(fs FieldSchema) map[string]any {
+	s := map[string]any{}
+
+	switch BaseType(fs.Type) {
+	case "number":
+		s["type"] = "number"
+		if fs.Min != 0 || fs.Max != 0 {
+			s["minimum"] = fs.Min
+			if fs.Max >= fs.Min {
+				s["maximum"] = fs.Max
+			}
+		}
+	case "text":
+		s["type"] = "string"
+		if len(fs.Regex) > 0 {
+			s["pattern"] = fs.Regex
+		}
+	case "ref":
+		target, _ := RefResource(fs)
+		s["type"] = "string"
+		s["description"] = fmt.Sprintf("Id of a record of %s.", target)
+		if len(fs.Regex) > 0 {
+			s["pattern"] = fs.Regex
+		}
+	case "list":
+		s["type"] = "array"
+		s["items"] = map[string]any{"type": "string"}
+	case "file":
+		s["type"] = "object"
+		s["properties"] = map[string]any{
+			"name":      map[string]any{"type": "string"},
+			"size":      map[string]any{"type": "integer"},
+			"mime_type": map[string]any{"type": "string"},
+			"hash":      map[string]any{"type": "string"},
+		}
+	case "geopoint":
+		s["type"] = "object"
+		s["properties"] = map[string]any{
+			"lat": map[string]any{"type": "number", "minimum": -90, "maximum": 90},
+			"lon": map[string]any{"type": "number", "minimum": -180, "maximum": 180},
+		}
+		s["required"] = []string{"lat", "lon"}
+	case "vector":
+		s["type"] = "array"
+		s["items"] = map[string]any{"type": "number"}
+		if dim, ok := VectorDim(fs); ok {
+			s["minItems"] = dim
+			s["maxItems"] = dim
+		}
+	}
+
+	if fs.Nullable {
+		if t, ok := s["type"].(string); ok {
+			s["type"] = []string{t, "null"}
+		}
+	}
+
+	switch {
+	case fs.Default == "now()":
+		s["description"] = "Defaults to the current time."
+	case len(fs.Default) > 0:
+		if v, err := FormatResourceField(fs, fs.Default); err == nil {
+			s["default"] = v
+		}
+	}
+
+	return s
+}
+
+// TypeName turns a resource name into an exported identifier, so _users
+// becomes Users and line_items becomes LineItems.
+func TypeName(resource string) string {
+	var b strings.Builder
+
+	for _, part := range strings.FieldsFunc(resource, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+
+	return b.String()
+}
+
+// operation starts an operation on resource when a permission grants action,
+// with the security and description that follow from the permissions.
+func operation(permissions []Resource, resource string, action string) (map[string]any, bool) {
+	public := false
+	roles := []string{}
+	fields := []string{}
+
+	for _, p := range permissions {
+		if p["resource"] != resource || (p["action"] != "*" && p["action"] != action) {
+			continue
+		}
+
+		role, _ := p["role"].(string)
+		field, _ := p["field"].(string)
+
+		switch {
+		case len(role) == 0 && len(field) == 0:
+			public = true
+		case len(role) > 0:
+			roles = append(roles, role)
+		case action != "create":
+			fields = append(fields, field)
+		}
+	}
+
+	op := map[string]any{
+		"tags": []string{resource},
+	}
+
+	switch {
+	case public:
+		op["security"] = []any{map[string]any{}}
+		return op, true
+	case len(roles) == 0 && len(fields) == 0:
+		return nil, false
+	}
+
+	op["security"] = []any{map[string]any{"basicAuth": []string{}}}
+
+	grants := []string{}
+	if len(roles) > 0 {
+		grants = append(grants, "users with role "+strings.Join(roles, " or "))
+	}
+	if len(fields) > 0 {
+		grants = append(grants, "users named in "+strings.Join(fields, " or ")+" of the record")
+	}
+	op["description"] = "Allowed for " + strings.Join(grants, " and for ") + "."
+
+	return op, true
+}
+
+// bulkOperation starts the bulk operation on resource when a permission
+// grants any of create, update and delete. Each op is authorized as the
+// request that does it alone.
+func bulkOperation(permissions []Resource, resource string) (map[string]any, bool) {
+	public, authn := false, false
+	grants := []string{}
+
+	for _, action := range []string{BulkCreate, BulkUpdate, BulkDelete} {
+		op, ok := operation(permissions, resource, action)
+		if !ok {
+			continue
+		}
+
+		desc, ok := op["description"].(string)
+		if !ok {
+			public = true
+			desc = "Allowed for anyone."
+		} else {
+			authn = true
+		}
+
+		grants = append(grants, action+": "+desc)
+	}
+
+	if len(grants) == 0 {
+		return nil, false
+	}
+
+	// credentials are optional when some ops need none
+	security := []any{}
+	if public {
+		security = append(security, map[string]any{})
+	}
+	if authn {
+		security = append(security, map[string]any{"basicAuth": []string{}})
+	}
+
+	return map[string]any{
+		"tags":        []string{resource},
+		"security":    security,
+		"description": "Each op is authorized on its own. " + strings.Join(grants, " "),
+	}, true
+}
+
+// neighborJSONSchema is the JSON Schema of a Neighbor whose record is ref.
+func neighborJSONSchema(ref map[string]any) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"record":   ref,
+			"distance": map[string]any{"type": "number"},
+		},
+		"required": []string{"record", "distance"},
+	}
+}
+
+// resourceMetaSchema is the name of the schema of ResourceMeta among the
+// components.
+const resourceMetaSchema = "_resource_meta"
+
+// ResourceMetaJSONSchema is the JSON Schema of a ResourceMeta.
+func ResourceMetaJSONSchema() map[string]any {
+	str := map[string]any{"type": "string"}
+
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name":    str,
+			"version": map[string]any{"type": "integer"},
+			"actions": map[string]any{"type": "array", "items": map[string]any{"enum": []string{"read", "create", "update", "delete"}}},
+			"fields": map[string]any{"type": "array", "items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"resource":  str,
+					"field":     str,
+					"type":      str,
+					"min":       map[string]any{"type": "number"},
+					"max":       map[string]any{"type": "number"},
+					"regex":     str,
+					"required":  map[string]any{"type": "boolean"},
+					"nullable":  map[string]any{"type": "boolean"},
+					"default":   str,
+					"on_delete": str,
+				},
+				"required": []string{"resource", "field", "type"},
+			}},
+		},
+		"required": []string{"name", "version", "actions", "fields"},
+	}
+}
+
+// bulkResultJSONSchema is the JSON Schema of a BulkResult whose record
+// is ref.
+func bulkResultJSONSchema(ref map[string]any) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"op":     map[string]any{"type": "string"},
+			"status": map[string]any{"type": "integer"},
+			"_id":    map[string]any{"type": "string"},
+			"record": ref,
+			"error":  map[string]any{"$ref": "#/components/schemas/" + problemSchema},
+		},
+		"required": []string{"op", "status"},
+	}
+}
+
+// aggregateRowJSONSchema is the JSON Schema of an AggregateRow.
+func aggregateRowJSONSchema() map[string]any {
+	byField := func(values map[string]any) map[string]any {
+		return map[string]any{"type": "object", "additionalProperties": values}
+	}
+
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"group": byField(map[string]any{}),
+			"count": map[string]any{"type": "integer"},
+			"sum":   byField(map[string]any{"type": "number"}),
+			"avg":   byField(map[string]any{"type": []string{"number", "null"}}),
+			"min":   byField(map[string]any{}),
+			"max":   byField(map[string]any{}),
+		},
+	}
+}
+
+// hasType reports whether some live field of fields is of base type t.
+func hasType(fields []FieldSchema, t string) bool {
+	return len(fieldsOfType(fields, t)) > 0
+}
+
+// fieldsOfType is the live fields of fields of base type t, by name.
+func fieldsOfType(fields []FieldSchema, t string) []string {
+	names := []string{}
+	for _, fs := range fields {
+		if !fs.Dropped && BaseType(fs.Type) == t {
+			names = append(names, fs.Field)
+		}
+	}
+	return names
+}
+
+func pathParameter(name string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"name":     name,
+		"in":       "path",
+		"required": true,
+		"schema":   schema,
+	}
+}
+
+func queryParameter(name string, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      map[string]any{"type": "string"},
+	}
+}
+
+func jsonBody(schema map[string]any) map[string]any {
+	return map[string]any{
+		"required": true,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schema},
+		},
+	}
+}
+
+func jsonResponse(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": schema},
+		},
+	}
+}
+
+// problemSchema is the name of the schema of Problem among the components.
+const problemSchema = "_problem"
+
+func problemResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			ProblemContentType: map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/" + problemSchema}},
+		},
+	}
+}
+
+// ProblemJSONSchema is the JSON Schema of a Problem.
+func ProblemJSONSchema() map[string]any {
+	str := map[string]any{"type": "string"}
+
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"type":     str,
+			"title":    str,
+			"status":   map[string]any{"type": "integer"},
+			"detail":   str,
+			"instance": str,
+			"code": map[string]any{"enum": []string{
+				CodeInvalidJSON, CodeInvalidRequest, CodeValidationFailed, CodeInvalidReference,
+				CodeUnauthenticated, CodeForbidden, CodeNotFound, CodeMethodNotAllowed, CodeConflict,
+				CodeTooLarge, CodeRateLimited, CodeLockedOut, CodeTimeout, CodeInternal,
+			}},
+			"errors": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"field": str,
+						"rule": map[string]any{"enum": []string{
+							RuleRequired, RuleNullable, RuleType, RuleMin, RuleMax, RulePattern, RuleRange, RuleDimension, RuleFinite,
+						}},
+						"expected": str,
+						"message":  str,
+					},
+					"required": []string{"field", "rule", "message"},
+				},
+			},
+		},
+		"required": []string{"type", "title", "status", "code"},
+	}
+}
+
+// responses adds the shared error responses by status code to ok.
+func responses(ok map[string]any, codes ...string) map[string]any {
+	names := map[string]string{
+		"400": "BadRequest",
+		"401": "Unauthorized",
+		"403": "Forbidden",
+		"404": "NotFound",
+		"409": "Conflict",
+		"413": "TooLarge",
+		"429": "TooManyRequests",
+	}
+
+	// any request can be rate limited
+	codes = append(codes, "429")
+
+	for _, code := range codes {
+		ok[code] = map[string]any{"$ref": "#/components/responses/" + names[code]}
+	}
+
+	return ok
+}
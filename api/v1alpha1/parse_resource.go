@@ -1,43 +1,65 @@
 package v1alpha1
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
+	"strconv"
 )
 
+// ParseResource validates client input against the schema. File fields are
+// left out: they are only ever set by uploading content. A record that
+// breaks rules of the schema gets a *ValidationError with every field that
+// does.
 func ParseResource(s []FieldSchema, res Resource) (Resource, error) {
 	parsed := Resource{}
+	invalid := &ValidationError{}
 
 	for _, fs := range s {
-		if fs.Field == "_id" || fs.Field == "_v" {
+		if fs.Field == "_id" || fs.Field == "_v" || BaseType(fs.Type) == "file" {
 			continue
 		}
 
-		v := res[fs.Field]
+		v, present := res[fs.Field]
+
+		if v == nil {
+			var err error
+			if v, err = resolveMissing(fs, present); err != nil {
+				if fe := (*FieldError)(nil); errors.As(err, &fe) {
+					invalid.Errors = append(invalid.Errors, *fe)
+					continue
+				}
+				return nil, err
+			}
+			if v == nil {
+				parsed[fs.Field] = nil
+				continue
+			}
+		}
 
 		var parsedValue any
 		var err error
 
-		switch fs.Type {
+		switch BaseType(fs.Type) {
 		case "number":
-			if v == nil {
-				v = 0.0
-			}
 			parsedValue, err = ParseField[float64](fs, v)
-		case "text":
-			if v == nil {
-				v = ""
-			}
+		case "text", "ref":
 			parsedValue, err = ParseField[string](fs, v)
 		case "list":
-			if v == nil {
-				v = []string{}
-			}
 			parsedValue, err = ParseField[[]string](fs, v)
+		case "geopoint":
+			parsedValue, err = ParseField[GeoPoint](fs, v)
+		case "vector":
+			parsedValue, err = ParseField[[]float64](fs, v)
 		default:
 			err = fmt.Errorf("unknown field type %s during record parsing", fs.Type)
 		}
 
+		if fe := (*FieldError)(nil); errors.As(err, &fe) {
+			invalid.Errors = append(invalid.Errors, *fe)
+			continue
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -45,11 +67,54 @@ func ParseResource(s []FieldSchema, res Resource) (Resource, error) {
 		parsed[fs.Field] = parsedValue
 	}
 
+	if len(invalid.Errors) > 0 {
+		return nil, invalid
+	}
+
 	return parsed, nil
 }
 
+// resolveMissing decides what a field that was left out (or explicitly set
+// to null) becomes. A nil result means the field is stored as null. Only a
+// nullable field may be set to null.
+func resolveMissing(fs FieldSchema, present bool) (any, error) {
+	switch {
+	case present && fs.Nullable:
+		return nil, nil
+	case present:
+		return nil, &FieldError{
+			Field:   fs.Field,
+			Rule:    RuleNullable,
+			Message: fmt.Sprintf("field \"%s\" is not nullable", fs.Field),
+		}
+	case len(fs.Default) > 0:
+		return EvalDefault(fs)
+	case fs.Required:
+		return nil, &FieldError{
+			Field:   fs.Field,
+			Rule:    RuleRequired,
+			Message: fmt.Sprintf("field \"%s\" is required", fs.Field),
+		}
+	case fs.Nullable:
+		return nil, nil
+	}
+
+	switch BaseType(fs.Type) {
+	case "number":
+		return 0.0, nil
+	case "text", "ref":
+		return "", nil
+	case "list":
+		return []string{}, nil
+	case "geopoint", "vector":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown field type %s during record parsing", fs.Type)
+	}
+}
+
 type FieldType interface {
-	float64 | string | []string
+	float64 | string | []string | GeoPoint | []float64
 }
 
 func ParseField[T FieldType](fs FieldSchema, v any) (T, error) {
@@ -59,18 +124,22 @@ func ParseField[T FieldType](fs FieldSchema, v any) (T, error) {
 	case float64:
 		n, ok := v.(float64)
 		if !ok {
-			return result, fmt.Errorf("failed to parse field \"%s\" as a number", fs.Field)
+			return result, fieldError(fs, RuleType, "number", "failed to parse field \"%s\" as a number")
 		}
-		ok = (fs.Min == 0 && fs.Max == 0) ||
-			(n >= fs.Min && (fs.Max < fs.Min || n <= fs.Max))
-		if !ok {
-			return result, fmt.Errorf("failed to parse field \"%s\" as a valid number", fs.Field)
+		if fs.Min == 0 && fs.Max == 0 {
+			return any(n).(T), nil
+		}
+		if n < fs.Min {
+			return result, fieldError(fs, RuleMin, formatNumber(fs.Min), "failed to parse field \"%s\" as a valid number")
+		}
+		if fs.Max >= fs.Min && n > fs.Max {
+			return result, fieldError(fs, RuleMax, formatNumber(fs.Max), "failed to parse field \"%s\" as a valid number")
 		}
 		return any(n).(T), nil
 	case string:
 		t, ok := v.(string)
 		if !ok {
-			return result, fmt.Errorf("failed to parse field \"%s\" as a string", fs.Field)
+			return result, fieldError(fs, RuleType, "string", "failed to parse field \"%s\" as a string")
 		}
 		if len(fs.Regex) > 0 {
 			matched, err := regexp.MatchString(fs.Regex, t)
@@ -78,17 +147,54 @@ func ParseField[T FieldType](fs FieldSchema, v any) (T, error) {
 				return result, fmt.Errorf("invalid regex for field \"%s\": %w", fs.Field, err)
 			}
 			if !matched {
-				return result, fmt.Errorf("failed to parse field \"%s\" as a valid string", fs.Field)
+				return result, fieldError(fs, RulePattern, fs.Regex, "failed to parse field \"%s\" as a valid string")
 			}
 		}
 		return any(t).(T), nil
 	case []string:
 		l, ok := v.([]string)
 		if !ok {
-			return result, fmt.Errorf("failed to parse field \"%s\" as a list", fs.Field)
+			return result, fieldError(fs, RuleType, "list", "failed to parse field \"%s\" as a list")
 		}
 		return any(l).(T), nil
+	case GeoPoint:
+		p, ok := toGeoPoint(v)
+		if !ok {
+			return result, fieldError(fs, RuleType, "geopoint", "failed to parse field \"%s\" as a point")
+		}
+		if !ValidGeoPoint(p) {
+			return result, fieldError(fs, RuleRange, "latitude from -90 to 90 and longitude from -180 to 180", "failed to parse field \"%s\" as a valid point")
+		}
+		return any(p).(T), nil
+	case []float64:
+		vec, ok := toVector(v)
+		if !ok {
+			return result, fieldError(fs, RuleType, "vector", "failed to parse field \"%s\" as a vector")
+		}
+		if dim, ok := VectorDim(fs); !ok || len(vec) != dim {
+			return result, fieldError(fs, RuleDimension, fs.Type, "failed to parse field \"%s\" as a vector of type "+fs.Type)
+		}
+		for _, f := range vec {
+			if math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > math.MaxFloat32 {
+				return result, fieldError(fs, RuleFinite, "finite float32 numbers", "failed to parse field \"%s\" as a valid vector")
+			}
+		}
+		return any(vec).(T), nil
 	default:
 		return result, fmt.Errorf("unsupported generic type %T", result)
 	}
 }
+
+// fieldError is a *FieldError for fs, with msg naming the field with %s.
+func fieldError(fs FieldSchema, rule string, expected string, msg string) error {
+	return &FieldError{
+		Field:    fs.Field,
+		Rule:     rule,
+		Expected: expected,
+		Message:  fmt.Sprintf(msg, fs.Field),
+	}
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
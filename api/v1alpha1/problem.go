@@ -0,0 +1,95 @@
+package v1alpha1
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of a Problem.
+const ProblemContentType = "application/problem+json"
+
+// The codes of a Problem. They are stable, so clients can tell failures
+// apart by them rather than by the wording of the detail.
+const (
+	CodeInvalidJSON      = "invalid_json"
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeInvalidReference = "invalid_reference"
+	CodeUnauthenticated  = "unauthenticated"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeTooLarge         = "too_large"
+	CodeAborted          = "aborted"
+	CodeRateLimited      = "rate_limited"
+	CodeLockedOut        = "locked_out"
+	CodeTimeout          = "timeout"
+	CodeInternal         = "internal"
+)
+
+// Problem is an error response, as RFC 7807 describes it. Its type is
+// about:blank, so its title is the text of its status, and Code tells apart
+// the failures that share a status. Errors lists every field of a record
+// that broke a rule of its schema.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func NewProblem(status int, code string, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// The rules a FieldError can break.
+const (
+	RuleRequired  = "required"
+	RuleNullable  = "nullable"
+	RuleType      = "type"
+	RuleMin       = "min"
+	RuleMax       = "max"
+	RulePattern   = "pattern"
+	RuleRange     = "range"
+	RuleDimension = "dimension"
+	RuleFinite    = "finite"
+)
+
+// FieldError is a value that breaks a rule of the schema of its field.
+// Expected is what the rule asks for, like a type, a bound or a pattern.
+type FieldError struct {
+	Field    string `json:"field"`
+	Rule     string `json:"rule"`
+	Expected string `json:"expected,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationError is every FieldError of a record, in the order of its
+// schema.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Message)
+	}
+
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,74 @@
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Projection picks the fields of the resources to return: those in Fields
+// only or, with Exclude, every field but those. _id is always kept. The
+// zero Projection keeps every field.
+type Projection struct {
+	Fields  []string
+	Exclude bool
+}
+
+// ParseProjection reads a projection like title,year to keep those fields,
+// or like -body,-tags to keep every field but those.
+func ParseProjection(s string) (Projection, error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return Projection{}, nil
+	}
+
+	p := Projection{}
+
+	for i, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+
+		exclude := strings.HasPrefix(part, "-")
+		if i == 0 {
+			p.Exclude = exclude
+		} else if exclude != p.Exclude {
+			return Projection{}, errors.New("expected fields to keep or fields to exclude, not both")
+		}
+
+		field := strings.TrimPrefix(part, "-")
+		if len(field) == 0 {
+			return Projection{}, fmt.Errorf("expected a field name, like title or -title, got %q", part)
+		}
+
+		p.Fields = append(p.Fields, field)
+	}
+
+	return p, nil
+}
+
+// IsZero reports whether p keeps every field.
+func (p Projection) IsZero() bool {
+	return len(p.Fields) == 0
+}
+
+// Validate checks that every field of p is one of schemas, and that _id
+// is not excluded.
+func (p Projection) Validate(schemas []FieldSchema) error {
+	for _, field := range p.Fields {
+		if !slices.ContainsFunc(schemas, func(fs FieldSchema) bool { return fs.Field == field && !fs.Dropped }) {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		if p.Exclude && field == "_id" {
+			return errors.New("field \"_id\" is always returned")
+		}
+	}
+
+	return nil
+}
+
+// Keeps reports whether p keeps field.
+func (p Projection) Keeps(field string) bool {
+	if p.IsZero() || field == "_id" {
+		return true
+	}
+	return slices.Contains(p.Fields, field) != p.Exclude
+}
@@ -0,0 +1,319 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/v1alpha1/recordspb/records.proto
+
+package recordspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListRecordsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Resource string                 `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	// sort is the keys to sort by, like -priority,description.
+	Sort string `protobuf:"bytes,2,opt,name=sort,proto3" json:"sort,omitempty"`
+	// read_mask is the fields to return, or every field when empty. _id is
+	// always returned.
+	ReadMask      *fieldmaskpb.FieldMask `protobuf:"bytes,3,opt,name=read_mask,json=readMask,proto3" json:"read_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRecordsRequest) Reset() {
+	*x = ListRecordsRequest{}
+	mi := &file_api_v1alpha1_recordspb_records_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRecordsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRecordsRequest) ProtoMessage() {}
+
+func (x *ListRecordsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_recordspb_records_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRecordsRequest.ProtoReflect.Descriptor instead.
+func (*ListRecordsRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_recordspb_records_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListRecordsRequest) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *ListRecordsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListRecordsRequest) GetReadMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.ReadMask
+	}
+	return nil
+}
+
+type ListRecordsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Records       []*structpb.Struct     `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRecordsResponse) Reset() {
+	*x = ListRecordsResponse{}
+	mi := &file_api_v1alpha1_recordspb_records_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRecordsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRecordsResponse) ProtoMessage() {}
+
+func (x *ListRecordsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_recordspb_records_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRecordsResponse.ProtoReflect.Descriptor instead.
+func (*ListRecordsResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_recordspb_records_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListRecordsResponse) GetRecords() []*structpb.Struct {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+type GetRecordRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Resource string                 `protobuf:"bytes,1,opt,name=resource,proto3" json:"resource,omitempty"`
+	Id       string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// read_mask is the fields to return, or every field when empty. _id is
+	// always returned.
+	ReadMask      *fieldmaskpb.FieldMask `protobuf:"bytes,3,opt,name=read_mask,json=readMask,proto3" json:"read_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRecordRequest) Reset() {
+	*x = GetRecordRequest{}
+	mi := &file_api_v1alpha1_recordspb_records_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRecordRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRecordRequest) ProtoMessage() {}
+
+func (x *GetRecordRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_recordspb_records_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRecordRequest.ProtoReflect.Descriptor instead.
+func (*GetRecordRequest) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_recordspb_records_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetRecordRequest) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *GetRecordRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetRecordRequest) GetReadMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.ReadMask
+	}
+	return nil
+}
+
+type GetRecordResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Record        *structpb.Struct       `protobuf:"bytes,1,opt,name=record,proto3" json:"record,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRecordResponse) Reset() {
+	*x = GetRecordResponse{}
+	mi := &file_api_v1alpha1_recordspb_records_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRecordResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRecordResponse) ProtoMessage() {}
+
+func (x *GetRecordResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_v1alpha1_recordspb_records_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRecordResponse.ProtoReflect.Descriptor instead.
+func (*GetRecordResponse) Descriptor() ([]byte, []int) {
+	return file_api_v1alpha1_recordspb_records_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRecordResponse) GetRecord() *structpb.Struct {
+	if x != nil {
+		return x.Record
+	}
+	return nil
+}
+
+var File_api_v1alpha1_recordspb_records_proto protoreflect.FileDescriptor
+
+const file_api_v1alpha1_recordspb_records_proto_rawDesc = "" +
+	"\n" +
+	"$api/v1alpha1/recordspb/records.proto\x12\x10backend.v1alpha1\x1a google/protobuf/field_mask.proto\x1a\x1cgoogle/protobuf/struct.proto\"}\n" +
+	"\x12ListRecordsRequest\x12\x1a\n" +
+	"\bresource\x18\x01 \x01(\tR\bresource\x12\x12\n" +
+	"\x04sort\x18\x02 \x01(\tR\x04sort\x127\n" +
+	"\tread_mask\x18\x03 \x01(\v2\x1a.google.protobuf.FieldMaskR\breadMask\"H\n" +
+	"\x13ListRecordsResponse\x121\n" +
+	"\arecords\x18\x01 \x03(\v2\x17.google.protobuf.StructR\arecords\"w\n" +
+	"\x10GetRecordRequest\x12\x1a\n" +
+	"\bresource\x18\x01 \x01(\tR\bresource\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x127\n" +
+	"\tread_mask\x18\x03 \x01(\v2\x1a.google.protobuf.FieldMaskR\breadMask\"D\n" +
+	"\x11GetRecordResponse\x12/\n" +
+	"\x06record\x18\x01 \x01(\v2\x17.google.protobuf.StructR\x06record2\xbb\x01\n" +
+	"\aRecords\x12Z\n" +
+	"\vListRecords\x12$.backend.v1alpha1.ListRecordsRequest\x1a%.backend.v1alpha1.ListRecordsResponse\x12T\n" +
+	"\tGetRecord\x12\".backend.v1alpha1.GetRecordRequest\x1a#.backend.v1alpha1.GetRecordResponseB1Z/github.com/w-h-a/backend/api/v1alpha1/recordspbb\x06proto3"
+
+var (
+	file_api_v1alpha1_recordspb_records_proto_rawDescOnce sync.Once
+	file_api_v1alpha1_recordspb_records_proto_rawDescData []byte
+)
+
+func file_api_v1alpha1_recordspb_records_proto_rawDescGZIP() []byte {
+	file_api_v1alpha1_recordspb_records_proto_rawDescOnce.Do(func() {
+		file_api_v1alpha1_recordspb_records_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_v1alpha1_recordspb_records_proto_rawDesc), len(file_api_v1alpha1_recordspb_records_proto_rawDesc)))
+	})
+	return file_api_v1alpha1_recordspb_records_proto_rawDescData
+}
+
+var file_api_v1alpha1_recordspb_records_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_api_v1alpha1_recordspb_records_proto_goTypes = []any{
+	(*ListRecordsRequest)(nil),    // 0: backend.v1alpha1.ListRecordsRequest
+	(*ListRecordsResponse)(nil),   // 1: backend.v1alpha1.ListRecordsResponse
+	(*GetRecordRequest)(nil),      // 2: backend.v1alpha1.GetRecordRequest
+	(*GetRecordResponse)(nil),     // 3: backend.v1alpha1.GetRecordResponse
+	(*fieldmaskpb.FieldMask)(nil), // 4: google.protobuf.FieldMask
+	(*structpb.Struct)(nil),       // 5: google.protobuf.Struct
+}
+var file_api_v1alpha1_recordspb_records_proto_depIdxs = []int32{
+	4, // 0: backend.v1alpha1.ListRecordsRequest.read_mask:type_name -> google.protobuf.FieldMask
+	5, // 1: backend.v1alpha1.ListRecordsResponse.records:type_name -> google.protobuf.Struct
+	4, // 2: backend.v1alpha1.GetRecordRequest.read_mask:type_name -> google.protobuf.FieldMask
+	5, // 3: backend.v1alpha1.GetRecordResponse.record:type_name -> google.protobuf.Struct
+	0, // 4: backend.v1alpha1.Records.ListRecords:input_type -> backend.v1alpha1.ListRecordsRequest
+	2, // 5: backend.v1alpha1.Records.GetRecord:input_type -> backend.v1alpha1.GetRecordRequest
+	1, // 6: backend.v1alpha1.Records.ListRecords:output_type -> backend.v1alpha1.ListRecordsResponse
+	3, // 7: backend.v1alpha1.Records.GetRecord:output_type -> backend.v1alpha1.GetRecordResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_api_v1alpha1_recordspb_records_proto_init() }
+func file_api_v1alpha1_recordspb_records_proto_init() {
+	if File_api_v1alpha1_recordspb_records_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_v1alpha1_recordspb_records_proto_rawDesc), len(file_api_v1alpha1_recordspb_records_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_v1alpha1_recordspb_records_proto_goTypes,
+		DependencyIndexes: file_api_v1alpha1_recordspb_records_proto_depIdxs,
+		MessageInfos:      file_api_v1alpha1_recordspb_records_proto_msgTypes,
+	}.Build()
+	File_api_v1alpha1_recordspb_records_proto = out.File
+	file_api_v1alpha1_recordspb_records_proto_goTypes = nil
+	file_api_v1alpha1_recordspb_records_proto_depIdxs = nil
+}
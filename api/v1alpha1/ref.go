@@ -0,0 +1,14 @@
+package v1alpha1
+
+import "strings"
+
+const refPrefix = "ref:"
+
+// RefResource reports the resource a ref:<resource> field points at.
+func RefResource(fs FieldSchema) (string, bool) {
+	if !strings.HasPrefix(fs.Type, refPrefix) {
+		return "", false
+	}
+	target := strings.TrimPrefix(fs.Type, refPrefix)
+	return target, len(target) > 0
+}
@@ -0,0 +1,352 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UserFields and PermissionFields define _users and _permissions when a
+// schema file leaves them out.
+var (
+	UserFields = []FieldSchema{
+		{Id: "_users._id", Version: 1, Resource: "_users", Field: "_id", Type: "text", Regex: "^.+$"},
+		{Id: "_users._v", Version: 1, Resource: "_users", Field: "_v", Type: "number", Min: 1},
+		{Id: "_users.salt", Version: 1, Resource: "_users", Field: "salt", Type: "text"},
+		{Id: "_users.password", Version: 1, Resource: "_users", Field: "password", Type: "text", Regex: "^.+$"},
+		{Id: "_users.roles", Version: 1, Resource: "_users", Field: "roles", Type: "list"},
+	}
+
+	PermissionFields = []FieldSchema{
+		{Id: "_permissions._id", Version: 1, Resource: "_permissions", Field: "_id", Type: "text", Regex: "^.+$"},
+		{Id: "_permissions._v", Version: 1, Resource: "_permissions", Field: "_v", Type: "number", Min: 1},
+		{Id: "_permissions.resource", Version: 1, Resource: "_permissions", Field: "resource", Type: "text", Regex: "^.+$"},
+		{Id: "_permissions.action", Version: 1, Resource: "_permissions", Field: "action", Type: "text", Regex: "^.+$"},
+		{Id: "_permissions.field", Version: 1, Resource: "_permissions", Field: "field", Type: "text"},
+		{Id: "_permissions.role", Version: 1, Resource: "_permissions", Field: "role", Type: "text"},
+	}
+)
+
+// SchemaFile is the YAML or JSON alternative to _schemas.csv and
+// _permissions.csv. Resources keep their order, and so do their fields,
+// which fixes their columns.
+type SchemaFile struct {
+	Resources   []ResourceDefinition   `json:"resources" yaml:"resources"`
+	Permissions []PermissionDefinition `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+}
+
+type ResourceDefinition struct {
+	Name   string            `json:"name" yaml:"name"`
+	Fields []FieldDefinition `json:"fields" yaml:"fields"`
+}
+
+// FieldDefinition is a FieldSchema as written in a schema file. The id
+// defaults to <resource>.<name>.
+type FieldDefinition struct {
+	Id       string  `json:"id,omitempty" yaml:"id,omitempty"`
+	Name     string  `json:"name" yaml:"name"`
+	Type     string  `json:"type" yaml:"type"`
+	Min      float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max      float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	Regex    string  `json:"regex,omitempty" yaml:"regex,omitempty"`
+	Required bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Nullable bool    `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	Default  string  `json:"default,omitempty" yaml:"default,omitempty"`
+	OnDelete string  `json:"on_delete,omitempty" yaml:"on_delete,omitempty"`
+	Dropped  bool    `json:"dropped,omitempty" yaml:"dropped,omitempty"`
+}
+
+// PermissionDefinition is a row of _permissions. The id defaults to p<n>
+// for the n-th permission.
+type PermissionDefinition struct {
+	Id          string `json:"id,omitempty" yaml:"id,omitempty"`
+	Resource    string `json:"resource" yaml:"resource"`
+	Action      string `json:"action" yaml:"action"`
+	Field       string `json:"field,omitempty" yaml:"field,omitempty"`
+	Role        string `json:"role,omitempty" yaml:"role,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// ParseSchemaFile reads a schema file. JSON is read as the subset of YAML
+// it is. Unknown keys are errors.
+func ParseSchemaFile(data []byte) (SchemaFile, error) {
+	var f SchemaFile
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&f); err != nil {
+		return SchemaFile{}, err
+	}
+
+	return f, nil
+}
+
+// MarshalSchemaFile writes a schema file as "json" or "yaml".
+func MarshalSchemaFile(f SchemaFile, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		bs, err := json.MarshalIndent(f, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(bs, '\n'), nil
+	case "yaml":
+		return yaml.Marshal(f)
+	default:
+		return nil, fmt.Errorf("unknown schema file format %q", format)
+	}
+}
+
+// FieldSchemas lays out the resources of the file. Resources that do not
+// start with _id get _id and _v in front, and _users and _permissions fall
+// back to UserFields and PermissionFields.
+func (f SchemaFile) FieldSchemas() map[string][]FieldSchema {
+	schemas := map[string][]FieldSchema{}
+
+	for _, rd := range f.Resources {
+		schemas[rd.Name] = rd.fieldSchemas()
+	}
+
+	if _, ok := schemas["_users"]; !ok {
+		schemas["_users"] = UserFields
+	}
+
+	if _, ok := schemas["_permissions"]; !ok {
+		schemas["_permissions"] = PermissionFields
+	}
+
+	return schemas
+}
+
+func (rd ResourceDefinition) fieldSchemas() []FieldSchema {
+	fields := []FieldSchema{}
+
+	if len(rd.Fields) == 0 || rd.Fields[0].Name != "_id" {
+		fields = append(fields,
+			FieldSchema{Id: rd.Name + "._id", Version: 1, Resource: rd.Name, Field: "_id", Type: "text", Regex: "^.+$"},
+			FieldSchema{Id: rd.Name + "._v", Version: 1, Resource: rd.Name, Field: "_v", Type: "number", Min: 1},
+		)
+	}
+
+	for _, fd := range rd.Fields {
+		fields = append(fields, fd.fieldSchema(rd.Name))
+	}
+
+	return fields
+}
+
+func (fd FieldDefinition) fieldSchema(resource string) FieldSchema {
+	id := fd.Id
+	if len(id) == 0 {
+		id = resource + "." + fd.Name
+	}
+
+	return FieldSchema{
+		Id:       id,
+		Version:  1,
+		Resource: resource,
+		Field:    fd.Name,
+		Type:     fd.Type,
+		Min:      fd.Min,
+		Max:      fd.Max,
+		Regex:    fd.Regex,
+		Required: fd.Required,
+		Nullable: fd.Nullable,
+		Default:  fd.Default,
+		OnDelete: fd.OnDelete,
+		Dropped:  fd.Dropped,
+	}
+}
+
+// PermissionRecords maps the permissions of the file onto _permissions rows.
+// Descriptions are not part of a row.
+func (f SchemaFile) PermissionRecords() []Record {
+	recs := []Record{}
+
+	for i, pd := range f.Permissions {
+		id := pd.Id
+		if len(id) == 0 {
+			id = "p" + strconv.Itoa(i+1)
+		}
+		recs = append(recs, Record{id, "1", pd.Resource, pd.Action, pd.Field, pd.Role})
+	}
+
+	return recs
+}
+
+// NewSchemaFile is the inverse of FieldSchemas and PermissionRecords.
+// Resources are written in the given order, and a description is read from
+// the column after role when a permission row has one.
+func NewSchemaFile(order []string, schemas map[string][]FieldSchema, permissions []Record) SchemaFile {
+	f := SchemaFile{
+		Resources: []ResourceDefinition{},
+	}
+
+	for _, resource := range order {
+		rd := ResourceDefinition{
+			Name:   resource,
+			Fields: []FieldDefinition{},
+		}
+
+		for _, fs := range schemas[resource] {
+			fd := FieldDefinition{
+				Name:     fs.Field,
+				Type:     fs.Type,
+				Min:      fs.Min,
+				Max:      fs.Max,
+				Regex:    fs.Regex,
+				Required: fs.Required,
+				Nullable: fs.Nullable,
+				Default:  fs.Default,
+				OnDelete: fs.OnDelete,
+				Dropped:  fs.Dropped,
+			}
+			if fs.Id != resource+"."+fs.Field {
+				fd.Id = fs.Id
+			}
+			rd.Fields = append(rd.Fields, fd)
+		}
+
+		f.Resources = append(f.Resources, rd)
+	}
+
+	if permissions != nil {
+		f.Permissions = []PermissionDefinition{}
+	}
+
+	for _, rec := range permissions {
+		col := func(i int) string {
+			if i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+
+		f.Permissions = append(f.Permissions, PermissionDefinition{
+			Id:          col(0),
+			Resource:    col(2),
+			Action:      col(3),
+			Field:       col(4),
+			Role:        col(5),
+			Description: col(6),
+		})
+	}
+
+	return f
+}
+
+var yamlLineRegex = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// LintSchemaFile is LintSchemas for schema files. Problems point at the line
+// of the field, or of the resource for the _id and _v it adds.
+func LintSchemaFile(data []byte) ([]SchemaProblem, error) {
+	problems := []SchemaProblem{}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return append(problems, yamlProblems(err)...), nil
+	}
+
+	if _, err := ParseSchemaFile(data); err != nil {
+		return append(problems, yamlProblems(err)...), nil
+	}
+
+	rows := []schemaRow{}
+
+	resources := lookupNode(&doc, "resources")
+	if resources == nil {
+		return append(problems, SchemaProblem{Severity: SeverityError, Message: "file has no resources"}), nil
+	}
+
+	names := map[string]bool{}
+
+	for _, rn := range resources.Content {
+		var rd ResourceDefinition
+		if err := rn.Decode(&rd); err != nil {
+			return nil, err
+		}
+
+		names[rd.Name] = true
+
+		fields := rd.fieldSchemas()
+		implicit := len(fields) - len(rd.Fields)
+
+		var fieldNodes []*yaml.Node
+		if fn := lookupNode(rn, "fields"); fn != nil {
+			fieldNodes = fn.Content
+		}
+
+		for i, fs := range fields {
+			line := rn.Line
+			if i >= implicit && i-implicit < len(fieldNodes) {
+				line = fieldNodes[i-implicit].Line
+			}
+			rows = append(rows, schemaRow{line: line, rec: ToSchemaRecord(fs)})
+		}
+	}
+
+	fallbacks := []FieldSchema{}
+	if !names["_users"] {
+		fallbacks = append(fallbacks, UserFields...)
+	}
+	if !names["_permissions"] {
+		fallbacks = append(fallbacks, PermissionFields...)
+	}
+
+	for _, fs := range fallbacks {
+		rows = append(rows, schemaRow{rec: ToSchemaRecord(fs)})
+	}
+
+	return lintSchemaRows(rows, problems), nil
+}
+
+// lookupNode finds the value of key in a mapping node, looking through the
+// document node that wraps a whole file.
+func lookupNode(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func yamlProblems(err error) []SchemaProblem {
+	msgs := []string{err.Error()}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+
+	problems := []SchemaProblem{}
+
+	for _, msg := range msgs {
+		p := SchemaProblem{Severity: SeverityError, Message: msg}
+
+		if m := yamlLineRegex.FindStringSubmatch(strings.TrimPrefix(msg, "yaml: ")); m != nil {
+			p.Line, _ = strconv.Atoi(m[1])
+			p.Message = m[2]
+		}
+
+		problems = append(problems, p)
+	}
+
+	return problems
+}
@@ -0,0 +1,113 @@
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// The collations of text besides a language tag, like en or de.
+const (
+	// CollationBinary compares text byte by byte, which is the default.
+	CollationBinary = "binary"
+	// CollationNoCase compares text with case folded.
+	CollationNoCase = "nocase"
+)
+
+// SortKey orders records by Field, descending when Desc.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Sort orders records by each of Keys in turn, then by _id, so that
+// records never tie. Null and empty values go last, or first when
+// NullsFirst, whichever the direction of the key. Text and lists compare
+// by Collation: binary, nocase or a language tag.
+type Sort struct {
+	Keys       []SortKey
+	NullsFirst bool
+	Collation  string
+}
+
+// ParseSort reads keys like -priority,description: by priority,
+// descending, then by description.
+func ParseSort(s string) ([]SortKey, error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return nil, nil
+	}
+
+	keys := []SortKey{}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+
+		key := SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if len(key.Field) == 0 {
+			return nil, fmt.Errorf("expected a field name, like title or -title, got %q", part)
+		}
+
+		if slices.ContainsFunc(keys, func(other SortKey) bool { return other.Field == key.Field }) {
+			return nil, fmt.Errorf("field %q sorted by twice", key.Field)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// ParseNulls reads where nulls go: first, or last, the default.
+func ParseNulls(s string) (bool, error) {
+	switch s {
+	case "", "last":
+		return false, nil
+	case "first":
+		return true, nil
+	default:
+		return false, fmt.Errorf("expected first or last, got %q", s)
+	}
+}
+
+// Validate checks that every key of s is a field of schemas that can be
+// sorted by, which all but geopoint, vector and file fields can, and that
+// its collation is known.
+func (s Sort) Validate(schemas []FieldSchema) error {
+	for _, key := range s.Keys {
+		idx := slices.IndexFunc(schemas, func(fs FieldSchema) bool { return fs.Field == key.Field && !fs.Dropped })
+		if idx < 0 {
+			return fmt.Errorf("unknown field %q to sort by", key.Field)
+		}
+
+		switch t := BaseType(schemas[idx].Type); t {
+		case "number", "text", "ref", "list":
+		default:
+			return fmt.Errorf("field %q is a %s, which cannot be sorted by", key.Field, t)
+		}
+	}
+
+	if _, err := ParseCollation(s.Collation); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ParseCollation reads a collation, which is empty or binary, nocase or a
+// language tag. The tag is returned for a language.
+func ParseCollation(c string) (language.Tag, error) {
+	switch c {
+	case "", CollationBinary, CollationNoCase:
+		return language.Und, nil
+	}
+
+	tag, err := language.Parse(c)
+	if err != nil {
+		return language.Und, errors.New("expected a collation of binary, nocase or a language, like en or de")
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,150 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SchemaFields describes the columns of _schemas itself.
+var SchemaFields = []FieldSchema{
+	{Resource: "_schemas", Field: "_id", Type: "text", Regex: "^.+$"},
+	{Resource: "_schemas", Field: "_v", Type: "number", Min: 1},
+	{Resource: "_schemas", Field: "resource", Type: "text", Regex: "^.+$"},
+	{Resource: "_schemas", Field: "field", Type: "text", Regex: "^.+$"},
+	{Resource: "_schemas", Field: "type", Type: "text", Regex: "^.+$"},
+	{Resource: "_schemas", Field: "min", Type: "text"},
+	{Resource: "_schemas", Field: "max", Type: "text"},
+	{Resource: "_schemas", Field: "regex", Type: "text"},
+	{Resource: "_schemas", Field: "required", Type: "text"},
+	{Resource: "_schemas", Field: "nullable", Type: "text"},
+	{Resource: "_schemas", Field: "default", Type: "text"},
+	{Resource: "_schemas", Field: "on_delete", Type: "text"},
+	{Resource: "_schemas", Field: "dropped", Type: "text"},
+}
+
+// ToFieldSchema maps a row of _schemas onto a FieldSchema. The columns are
+// _id, _v, resource, field, type, min, max, regex, required, nullable,
+// default, on_delete and dropped. Trailing columns may be omitted.
+//
+// The _id of a row is the identity of the field: renames and type changes
+// update the row in place, and its _v counts those changes. A resource's
+// columns are laid out in the order its rows were first written.
+func ToFieldSchema(rec Record) FieldSchema {
+	col := func(i int) string {
+		if i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+
+	schema := FieldSchema{
+		Id:       col(0),
+		Resource: col(2),
+		Field:    col(3),
+		Type:     col(4),
+		Regex:    col(7),
+		Default:  col(10),
+		OnDelete: col(11),
+	}
+
+	schema.Version, _ = strconv.Atoi(col(1))
+	schema.Min, _ = strconv.ParseFloat(col(5), 64)
+	schema.Max, _ = strconv.ParseFloat(col(6), 64)
+	schema.Required, _ = strconv.ParseBool(col(8))
+	schema.Nullable, _ = strconv.ParseBool(col(9))
+	schema.Dropped, _ = strconv.ParseBool(col(12))
+
+	return schema
+}
+
+// ToSchemaRecord is the inverse of ToFieldSchema. Zero and false attributes
+// are written as empty cells. The version column is left to the writer.
+func ToSchemaRecord(fs FieldSchema) Record {
+	num := func(f float64) string {
+		if f == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	flag := func(b bool) string {
+		if !b {
+			return ""
+		}
+		return "true"
+	}
+
+	return Record{
+		fs.Id,
+		"1",
+		fs.Resource,
+		fs.Field,
+		fs.Type,
+		num(fs.Min),
+		num(fs.Max),
+		fs.Regex,
+		flag(fs.Required),
+		flag(fs.Nullable),
+		fs.Default,
+		fs.OnDelete,
+		flag(fs.Dropped),
+	}
+}
+
+// LiveFields leaves out the dropped fields of a resource's layout.
+func LiveFields(layout []FieldSchema) []FieldSchema {
+	live := []FieldSchema{}
+
+	for _, fs := range layout {
+		if !fs.Dropped {
+			live = append(live, fs)
+		}
+	}
+
+	return live
+}
+
+// SchemaVersion is the version of a resource's schema as a whole. Every
+// change bumps the _v of one _schemas row or adds a row, so it only grows.
+func SchemaVersion(layout []FieldSchema) int {
+	v := 0
+
+	for _, fs := range layout {
+		v += max(fs.Version, 1)
+	}
+
+	return v
+}
+
+// CheckConversion reports whether a cell written under one definition of a
+// field still reads correctly once the field has another type. Null and
+// empty cells always convert.
+func CheckConversion(from FieldSchema, to FieldSchema, cell string) error {
+	if cell == NullValue || len(cell) == 0 {
+		return nil
+	}
+
+	fromType, toType := BaseType(from.Type), BaseType(to.Type)
+
+	if (fromType == "file") != (toType == "file") || (fromType == "vector") != (toType == "vector") {
+		return fmt.Errorf("%s values cannot become %s values", from.Type, to.Type)
+	}
+
+	v, err := FormatResourceField(to, cell)
+	if err != nil {
+		return err
+	}
+
+	switch toType {
+	case "number":
+		if _, err := strconv.ParseFloat(unescapeNull(cell), 64); err != nil {
+			return fmt.Errorf("%q is not a number", cell)
+		}
+	case "vector":
+		if dim, _ := VectorDim(to); len(v.([]float64)) != dim {
+			return fmt.Errorf("vector has %d dimensions, not %d", len(v.([]float64)), dim)
+		}
+	}
+
+	return nil
+}
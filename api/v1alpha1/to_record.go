@@ -5,11 +5,17 @@ import (
 	"strings"
 )
 
+// ToRecord lays a resource out in the columns of its schema. The columns of
+// dropped fields are left empty.
 func ToRecord(s []FieldSchema, res Resource) (Record, error) {
 	recordLen := len(s)
 	rec := make(Record, recordLen)
 
 	for i, fs := range s {
+		if fs.Dropped {
+			continue
+		}
+
 		v := res[fs.Field]
 
 		formattedValue, err := FormatRecordField(fs, v)
@@ -24,7 +30,11 @@ func ToRecord(s []FieldSchema, res Resource) (Record, error) {
 }
 
 func FormatRecordField(fs FieldSchema, v any) (string, error) {
-	switch fs.Type {
+	if v == nil && fs.Nullable {
+		return NullValue, nil
+	}
+
+	switch BaseType(fs.Type) {
 	case "number":
 		if v == nil {
 			v = 0.0
@@ -33,12 +43,12 @@ func FormatRecordField(fs FieldSchema, v any) (string, error) {
 			return fmt.Sprintf("%g", n), nil
 		}
 		return "", fmt.Errorf("internal error: expected float64 for field '%s', got %T", fs.Field, v)
-	case "text":
+	case "text", "ref":
 		if v == nil {
 			v = ""
 		}
 		if t, ok := v.(string); ok {
-			return t, nil
+			return escapeNull(t), nil
 		}
 		return "", fmt.Errorf("internal error: expected string for field '%s', got %T", fs.Field, v)
 	case "list":
@@ -46,10 +56,51 @@ func FormatRecordField(fs FieldSchema, v any) (string, error) {
 			v = []string{}
 		}
 		if l, ok := v.([]string); ok {
-			return strings.Join(l, ","), nil
+			return escapeNull(strings.Join(l, ",")), nil
 		}
 		return "", fmt.Errorf("internal error: expected []string for field '%s', got %T", fs.Field, v)
+	case "file":
+		return formatFileRecordField(fs, v)
+	case "geopoint":
+		if v == nil {
+			return "", nil
+		}
+		if p, ok := v.(GeoPoint); ok {
+			return FormatGeoPoint(p), nil
+		}
+		return "", fmt.Errorf("internal error: expected GeoPoint for field '%s', got %T", fs.Field, v)
+	case "vector":
+		if v == nil {
+			return "", nil
+		}
+		if vec, ok := v.([]float64); ok {
+			return EncodeVector(vec), nil
+		}
+		return "", fmt.Errorf("internal error: expected []float64 for field '%s', got %T", fs.Field, v)
 	default:
 		return "", fmt.Errorf("unknown schema type '%s' during record formatting", fs.Type)
 	}
 }
+
+// escapeNull keeps a literal value from being read back as null by adding a
+// backslash to anything of the form \N, \\N, \\\N and so on.
+func escapeNull(s string) string {
+	if isEscapedNull(s) {
+		return `\` + s
+	}
+	return s
+}
+
+func unescapeNull(s string) string {
+	if isEscapedNull(s) && s != NullValue {
+		return s[1:]
+	}
+	return s
+}
+
+func isEscapedNull(s string) bool {
+	if len(s) < 2 || !strings.HasSuffix(s, "N") {
+		return false
+	}
+	return strings.Trim(s[:len(s)-1], `\`) == ""
+}
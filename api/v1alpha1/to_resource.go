@@ -6,12 +6,32 @@ import (
 	"strings"
 )
 
+// ToResource maps a record onto the fields of a resource's layout. Dropped
+// fields are skipped. Records written before a field was added are short;
+// the missing fields read as their default, as null when nullable, or else
+// as their zero value. A now() default would read differently every time,
+// so it is not used for them.
 func ToResource(s []FieldSchema, rec Record) (Resource, error) {
+	return ProjectResource(s, rec, Projection{})
+}
+
+// ProjectResource maps a record onto the fields of a resource's layout
+// that p keeps, as ToResource does, and decodes none of the others.
+func ProjectResource(s []FieldSchema, rec Record, p Projection) (Resource, error) {
 	res := Resource{}
 
 	for i, fs := range s {
+		if fs.Dropped || !p.Keeps(fs.Field) {
+			continue
+		}
+
 		if i >= len(rec) {
-			return nil, fmt.Errorf("record length %d is less than schema length %d", len(rec), len(s))
+			v, err := missingResourceField(fs)
+			if err != nil {
+				return nil, err
+			}
+			res[fs.Field] = v
+			continue
 		}
 
 		strValue := rec[i]
@@ -27,12 +47,29 @@ func ToResource(s []FieldSchema, rec Record) (Resource, error) {
 	return res, nil
 }
 
+func missingResourceField(fs FieldSchema) (any, error) {
+	switch {
+	case len(fs.Default) > 0 && fs.Default != "now()":
+		return EvalDefault(fs)
+	case fs.Nullable:
+		return nil, nil
+	default:
+		return FormatResourceField(fs, "")
+	}
+}
+
 func FormatResourceField(fs FieldSchema, strValue string) (any, error) {
-	switch fs.Type {
+	if strValue == NullValue {
+		return nil, nil
+	}
+
+	strValue = unescapeNull(strValue)
+
+	switch BaseType(fs.Type) {
 	case "number":
 		n, _ := strconv.ParseFloat(strValue, 64)
 		return n, nil
-	case "text":
+	case "text", "ref":
 		return strValue, nil
 	case "list":
 		if len(strValue) > 0 {
@@ -40,6 +77,26 @@ func FormatResourceField(fs FieldSchema, strValue string) (any, error) {
 		} else {
 			return []string{}, nil
 		}
+	case "file":
+		return formatFileResourceField(fs, strValue)
+	case "geopoint":
+		if len(strValue) == 0 {
+			return nil, nil
+		}
+		return ParseGeoPoint(strValue)
+	case "vector":
+		if len(strValue) == 0 {
+			return nil, nil
+		}
+		vec, err := DecodeVector(strValue)
+		if err != nil {
+			return nil, fmt.Errorf("%w for field '%s'", err, fs.Field)
+		}
+		out := make([]float64, len(vec))
+		for i, f := range vec {
+			out[i] = float64(f)
+		}
+		return out, nil
 	default:
 		return nil, fmt.Errorf("unknown schema type %s during resource formatting", fs.Type)
 	}
@@ -0,0 +1,81 @@
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+var fieldNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateFieldSchema checks a single field definition in isolation and
+// reports every problem it finds. Whether a ref target exists depends on the
+// other resources and is left to the caller.
+func ValidateFieldSchema(fs FieldSchema) error {
+	errs := []error{}
+
+	if !fieldNameRegex.MatchString(fs.Resource) {
+		errs = append(errs, fmt.Errorf("invalid resource name %q", fs.Resource))
+	}
+
+	if !fieldNameRegex.MatchString(fs.Field) {
+		errs = append(errs, fmt.Errorf("invalid field name %q", fs.Field))
+	}
+
+	switch BaseType(fs.Type) {
+	case "number", "text", "list", "file", "geopoint":
+		if fs.Type != BaseType(fs.Type) {
+			errs = append(errs, fmt.Errorf("type %q takes no parameters", fs.Type))
+		}
+	case "ref":
+		if _, ok := RefResource(fs); !ok {
+			errs = append(errs, fmt.Errorf("type %q is missing its target resource", fs.Type))
+		}
+	case "vector":
+		if _, ok := VectorDim(fs); !ok {
+			errs = append(errs, fmt.Errorf("type %q needs a positive dimension, as in vector(3)", fs.Type))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown type %q", fs.Type))
+	}
+
+	if fs.Min != 0 && fs.Max != 0 && fs.Min > fs.Max {
+		errs = append(errs, fmt.Errorf("min %g is greater than max %g", fs.Min, fs.Max))
+	}
+
+	if len(fs.Regex) > 0 {
+		if _, err := regexp.Compile(fs.Regex); err != nil {
+			errs = append(errs, fmt.Errorf("invalid regex %q: %w", fs.Regex, err))
+		}
+	}
+
+	if len(fs.OnDelete) > 0 {
+		if _, ok := RefResource(fs); !ok {
+			errs = append(errs, errors.New("on_delete only applies to ref fields"))
+		} else if !slices.Contains([]string{OnDeleteRestrict, OnDeleteCascade, OnDeleteSetNull}, fs.OnDelete) {
+			errs = append(errs, fmt.Errorf("unknown on_delete policy %q", fs.OnDelete))
+		}
+	}
+
+	if fs.OnDelete == OnDeleteSetNull && !fs.Nullable {
+		errs = append(errs, errors.New("on_delete set-null requires a nullable field"))
+	}
+
+	if len(fs.Default) > 0 {
+		if _, err := EvalDefault(fs); err != nil {
+			errs = append(errs, fmt.Errorf("invalid default: %w", err))
+		} else if BaseType(fs.Type) == "number" && fs.Default != "now()" && fs.Default != NullValue {
+			if _, err := strconv.ParseFloat(fs.Default, 64); err != nil {
+				errs = append(errs, fmt.Errorf("invalid default: %q is not a number", fs.Default))
+			}
+		}
+	}
+
+	if (fs.Field == "_id" || fs.Field == "_v") && (fs.Nullable || len(fs.Default) > 0) {
+		errs = append(errs, fmt.Errorf("%s cannot be nullable or have a default", fs.Field))
+	}
+
+	return errors.Join(errs...)
+}
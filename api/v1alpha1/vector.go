@@ -0,0 +1,109 @@
+package v1alpha1
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Distance metrics accepted by nearest-neighbor queries.
+const (
+	MetricCosine = "cosine"
+	MetricL2     = "l2"
+)
+
+// VectorDim reports the dimension N of a vector(N) field.
+func VectorDim(fs FieldSchema) (int, bool) {
+	if BaseType(fs.Type) != "vector" || !strings.HasSuffix(fs.Type, ")") {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(fs.Type, "vector("), ")"))
+	if err != nil || n < 1 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// EncodeVector packs a vector as base64 little-endian float32s, which is how
+// it is kept in a record.
+func EncodeVector(v []float64) string {
+	bs := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(bs[4*i:], math.Float32bits(float32(f)))
+	}
+	return base64.RawStdEncoding.EncodeToString(bs)
+}
+
+func DecodeVector(s string) ([]float32, error) {
+	bs, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil || len(bs)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector encoding")
+	}
+
+	v := make([]float32, len(bs)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(bs[4*i:]))
+	}
+
+	return v, nil
+}
+
+func ValidMetric(metric string) bool {
+	return metric == MetricCosine || metric == MetricL2
+}
+
+// VectorDistance is 1 - cosine similarity for cosine and the Euclidean
+// distance for l2.
+func VectorDistance(metric string, a []float32, b []float32) float64 {
+	if metric == MetricL2 {
+		var sum float64
+		for i := range a {
+			d := float64(a[i]) - float64(b[i])
+			sum += d * d
+		}
+		return math.Sqrt(sum)
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(na)*math.Sqrt(nb))
+}
+
+func ToFloat32s(v []float64) []float32 {
+	out := make([]float32, len(v))
+	for i, f := range v {
+		out[i] = float32(f)
+	}
+	return out
+}
+
+func toVector(v any) ([]float64, bool) {
+	switch vec := v.(type) {
+	case []float64:
+		return vec, true
+	case []any:
+		out := make([]float64, len(vec))
+		for i, e := range vec {
+			f, ok := e.(float64)
+			if !ok {
+				return nil, false
+			}
+			out[i] = f
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+	"github.com/w-h-a/backend/internal/codegen"
+	"github.com/w-h-a/backend/pkg/backend"
+)
+
+// Codegen writes typed models and a client for the resources of a project
+// to --out, in Go or TypeScript. Files it wrote before are replaced.
+func Codegen(ctx *cli.Context, lang string) error {
+	out := ctx.String("out")
+
+	path := ctx.String("schemas")
+	if len(path) == 0 {
+		path = backend.SchemaPath("examples/todo")
+	}
+
+	file, err := readSchemaFile(path)
+	if err != nil {
+		return err
+	}
+
+	schemas := file.FieldSchemas()
+
+	var files map[string][]byte
+
+	switch lang {
+	case "go":
+		files, err = codegen.Go(schemas, ctx.String("package"))
+	case "ts":
+		files, err = codegen.TypeScript(schemas)
+	default:
+		return fmt.Errorf("unknown language %q", lang)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(out, name), src, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintln(ctx.App.Writer, filepath.Join(out, name))
+	}
+
+	return nil
+}
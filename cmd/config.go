@@ -0,0 +1,509 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"github.com/w-h-a/backend/internal/logs"
+	"github.com/w-h-a/backend/internal/traces"
+	"github.com/w-h-a/backend/pkg/backend"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is how the server is run. Every setting is looked up in this
+// order, and the first place that has it wins:
+//
+//  1. the command line flag, e.g. --http-addr
+//  2. the environment variable, e.g. BACKEND_HTTP_ADDR
+//  3. the config file given by --config or BACKEND_CONFIG, e.g. http_addr
+//  4. the default
+type Config struct {
+	Dir             string
+	HTTPAddr        string
+	GRPCAddr        string
+	TLSCert         string
+	TLSKey          string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	LogLevel        slog.Level
+	LogFormat       string
+	TraceExporter   string
+	TraceEndpoint   string
+	TraceFile       string
+	TraceSampling   float64
+	CORSOrigins     []string
+	CORSCredentials bool
+	MaxBodyBytes    int64
+	RequestTimeout  time.Duration
+	RateLimit       backend.RateLimit
+	LoginAttempts   int
+	LoginLockout    time.Duration
+	// Backends maps resources to "csv" or "memory". Resources left out are
+	// stored as CSV.
+	Backends map[string]string
+	// RouteTimeouts map route templates to the timeout of the route, in
+	// place of RequestTimeout.
+	RouteTimeouts map[string]time.Duration
+	// RateLimits map RESOURCE:ACTION, like notes:create, to the rate limit
+	// of the resource and action, in place of RateLimit.
+	RateLimits map[string]backend.RateLimit
+}
+
+// setting is a single-valued entry of Config. Its config file key and
+// environment variable follow from the flag name.
+type setting struct {
+	name  string
+	usage string
+	value string
+	set   func(c *Config, v string) error
+}
+
+var settings = []setting{
+	{"dir", "project directory with the schemas and records", "examples/todo", func(c *Config, v string) error {
+		c.Dir = v
+		return nil
+	}},
+	{"http-addr", "address to serve the HTTP API on, or empty for none", ":4000", func(c *Config, v string) error {
+		c.HTTPAddr = v
+		return nil
+	}},
+	{"grpc-addr", "address to serve gRPC on, or empty for none", "", func(c *Config, v string) error {
+		c.GRPCAddr = v
+		return nil
+	}},
+	{"tls-cert", "TLS certificate file, serving plain text when empty", "", func(c *Config, v string) error {
+		c.TLSCert = v
+		return nil
+	}},
+	{"tls-key", "TLS key file, serving plain text when empty", "", func(c *Config, v string) error {
+		c.TLSKey = v
+		return nil
+	}},
+	{"read-timeout", "how long reading an HTTP request may take, 0 for no limit", "30s", func(c *Config, v string) (err error) {
+		c.ReadTimeout, err = parseDuration(v)
+		return err
+	}},
+	{"write-timeout", "how long writing an HTTP response may take, 0 for no limit", "60s", func(c *Config, v string) (err error) {
+		c.WriteTimeout, err = parseDuration(v)
+		return err
+	}},
+	{"idle-timeout", "how long an idle HTTP connection is kept open, 0 for no limit", "2m", func(c *Config, v string) (err error) {
+		c.IdleTimeout, err = parseDuration(v)
+		return err
+	}},
+	{"shutdown-timeout", "how long to wait for requests in flight on shutdown", "10s", func(c *Config, v string) (err error) {
+		c.ShutdownTimeout, err = parseDuration(v)
+		return err
+	}},
+	{"log-level", "debug, info, warn or error", "info", func(c *Config, v string) error {
+		return c.LogLevel.UnmarshalText([]byte(v))
+	}},
+	{"log-format", "text or json", logs.FormatText, func(c *Config, v string) error {
+		if v != logs.FormatText && v != logs.FormatJSON {
+			return errors.New("expected text or json")
+		}
+		c.LogFormat = v
+		return nil
+	}},
+	{"trace-exporter", "where spans go: " + strings.Join(traces.Exporters, ", "), traces.ExporterNone, func(c *Config, v string) error {
+		if !slices.Contains(traces.Exporters, v) {
+			return fmt.Errorf("expected one of %s", strings.Join(traces.Exporters, ", "))
+		}
+		c.TraceExporter = v
+		return nil
+	}},
+	{"trace-endpoint", "URL of the OTLP collector, or empty for the OTEL_EXPORTER_OTLP_ENDPOINT default", "", func(c *Config, v string) error {
+		c.TraceEndpoint = v
+		return nil
+	}},
+	{"trace-file", "file the file exporter appends spans to", "traces.jsonl", func(c *Config, v string) error {
+		c.TraceFile = v
+		return nil
+	}},
+	{"trace-sample-ratio", "share of traces to record, from 0 to 1", "1", func(c *Config, v string) (err error) {
+		c.TraceSampling, err = strconv.ParseFloat(v, 64)
+		if err == nil && (c.TraceSampling < 0 || c.TraceSampling > 1) {
+			err = errors.New("expected a number from 0 to 1")
+		}
+		return err
+	}},
+	{"cors-origins", "comma-separated origins browsers may call the API from, * for any", "", func(c *Config, v string) error {
+		c.CORSOrigins = nil
+		for _, origin := range strings.Split(v, ",") {
+			if origin = strings.TrimSpace(origin); len(origin) > 0 {
+				c.CORSOrigins = append(c.CORSOrigins, origin)
+			}
+		}
+		return nil
+	}},
+	{"cors-credentials", "whether browsers may send credentials to another origin", "false", func(c *Config, v string) (err error) {
+		c.CORSCredentials, err = strconv.ParseBool(v)
+		return err
+	}},
+	{"max-body-size", "how large a request body may be, file uploads aside, like 1MiB, 0 for no limit", "1MiB", func(c *Config, v string) (err error) {
+		c.MaxBodyBytes, err = parseSize(v)
+		return err
+	}},
+	{"request-timeout", "how long handling a request may take, file transfers aside, 0 for no limit", "30s", func(c *Config, v string) (err error) {
+		c.RequestTimeout, err = parseDuration(v)
+		return err
+	}},
+	{"rate-limit", "how often a user, or an address, may call a route, like 100/s, 0 for no limit", "100/s", func(c *Config, v string) (err error) {
+		c.RateLimit, err = backend.ParseRateLimit(v)
+		return err
+	}},
+	{"login-attempts", "failed logins in a row that lock a username out, 0 for no lockout", "5", func(c *Config, v string) (err error) {
+		c.LoginAttempts, err = strconv.Atoi(v)
+		if err == nil && c.LoginAttempts < 0 {
+			err = errors.New("expected a count of 0 or more")
+		}
+		return err
+	}},
+	{"login-lockout", "how long the first lockout lasts, doubling with every failed login after", "30s", func(c *Config, v string) (err error) {
+		c.LoginLockout, err = parseDuration(v)
+		return err
+	}},
+}
+
+// mapSetting is an entry of Config that maps keys to values. Its flag takes
+// KEY=VALUE and is repeated, and its config file key and environment
+// variable are the plural of the flag name.
+type mapSetting struct {
+	name  string
+	usage string
+	// entry is the form of a flag value, and of a map in the config file.
+	entry string
+	of    string
+	set   func(c *Config, k string, v string) error
+}
+
+var mapSettings = []mapSetting{
+	{"backend", "RESOURCE=csv|memory, to choose how a resource is stored", "RESOURCE=KIND", "resources to backends", func(c *Config, k string, v string) error {
+		if v != "csv" && v != "memory" {
+			return errors.New("expected csv or memory")
+		}
+		c.Backends[k] = v
+		return nil
+	}},
+	{"route-timeout", "ROUTE=DURATION, like /api/{resource}/_knn=5s, to time out a route differently", "ROUTE=DURATION", "routes to timeouts", func(c *Config, k string, v string) (err error) {
+		c.RouteTimeouts[k], err = parseDuration(v)
+		return err
+	}},
+	{"resource-rate-limit", "RESOURCE:ACTION=LIMIT, like notes:create=10/m, either part * for any, to limit a resource differently", "RESOURCE:ACTION=LIMIT", "resources and actions to rate limits", func(c *Config, k string, v string) (err error) {
+		c.RateLimits[k], err = backend.ParseRateLimit(v)
+		return err
+	}},
+}
+
+// ConfigFlags are the flags of the commands that load a project. They are
+// made anew for every command, since urfave/cli keeps what it parsed in
+// them. Settings read their environment variables themselves, as
+// urfave/cli would report those as flags.
+func ConfigFlags() []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Usage:   "YAML or JSON file with settings, overridden by flags and environment variables",
+			EnvVars: []string{envName("config")},
+		},
+	}
+
+	for _, s := range settings {
+		flags = append(flags, &cli.StringFlag{
+			Name:        s.name,
+			Usage:       fmt.Sprintf("%s [$%s]", s.usage, envName(s.name)),
+			DefaultText: s.value,
+		})
+	}
+
+	for _, s := range mapSettings {
+		flags = append(flags, &cli.StringSliceFlag{
+			Name:  s.name,
+			Usage: fmt.Sprintf("%s [$%s]", s.usage, envName(s.name+"s")),
+		})
+	}
+
+	return flags
+}
+
+func envName(name string) string {
+	return "BACKEND_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func fileKey(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// source is where the value of a setting came from.
+type source struct {
+	key   string
+	value string
+	from  string
+	// values are the entries of a map setting.
+	values map[string]string
+}
+
+// LoadConfig resolves the settings of the command, see Config.
+func LoadConfig(ctx *cli.Context) (Config, error) {
+	config, _, err := loadConfig(ctx)
+	return config, err
+}
+
+// PrintConfig writes the effective configuration as a config file, with
+// where each value came from as a comment.
+func PrintConfig(ctx *cli.Context) error {
+	_, sources, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeConfig(ctx.App.Writer, sources)
+}
+
+func loadConfig(ctx *cli.Context) (Config, []source, error) {
+	file, path, err := readConfigFile(ctx.String("config"))
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	config := Config{}
+	sources := []source{}
+	errs := []error{}
+
+	for _, s := range settings {
+		src := source{key: fileKey(s.name)}
+		env, inEnv := os.LookupEnv(envName(s.name))
+
+		switch {
+		case ctx.IsSet(s.name):
+			src.value, src.from = ctx.String(s.name), "flag --"+s.name
+		case inEnv:
+			src.value, src.from = env, "env "+envName(s.name)
+		case file[src.key] != nil:
+			src.value, src.from = fmt.Sprint(file[src.key]), "file "+path
+		default:
+			src.value, src.from = s.value, "default"
+		}
+
+		if err := s.set(&config, src.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s %q (from %s): %w", src.key, src.value, src.from, err))
+		}
+
+		sources = append(sources, src)
+	}
+
+	config.Backends = map[string]string{}
+	config.RouteTimeouts = map[string]time.Duration{}
+	config.RateLimits = map[string]backend.RateLimit{}
+
+	for _, s := range mapSettings {
+		src := source{key: fileKey(s.name + "s"), from: "default", values: map[string]string{}}
+		env, inEnv := os.LookupEnv(envName(s.name + "s"))
+
+		switch {
+		case ctx.IsSet(s.name) || inEnv:
+			values := ctx.StringSlice(s.name)
+			src.from = "flag --" + s.name
+			if !ctx.IsSet(s.name) {
+				values, src.from = splitList(env), "env "+envName(s.name+"s")
+			}
+			for _, v := range values {
+				k, value, ok := strings.Cut(v, "=")
+				if !ok {
+					errs = append(errs, fmt.Errorf("%s %q (from %s): expected %s", s.name, v, src.from, s.entry))
+					continue
+				}
+				src.values[k] = value
+			}
+		case file[src.key] != nil:
+			src.from = "file " + path
+			m, ok := file[src.key].(map[string]any)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s (from %s): expected a map of %s", src.key, src.from, s.of))
+			}
+			for k, value := range m {
+				src.values[k] = fmt.Sprint(value)
+			}
+		}
+
+		for _, k := range slices.Sorted(maps.Keys(src.values)) {
+			if err := s.set(&config, k, src.values[k]); err != nil {
+				errs = append(errs, fmt.Errorf("%s %q of %s (from %s): %w", s.name, src.values[k], k, src.from, err))
+			}
+		}
+
+		sources = append(sources, src)
+	}
+
+	if (len(config.TLSCert) == 0) != (len(config.TLSKey) == 0) {
+		errs = append(errs, errors.New("tls_cert and tls_key must be set together"))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, nil, err
+	}
+
+	return config, sources, nil
+}
+
+// splitList reads the comma-separated entries of a map setting's
+// environment variable, as urfave/cli would a repeated flag.
+func splitList(v string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(v, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// readConfigFile reads the settings of a config file by key. There are none
+// when path is empty. Unknown keys are errors.
+func readConfigFile(path string) (map[string]any, string, error) {
+	if len(path) == 0 {
+		return map[string]any{}, "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	file := map[string]any{}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	known := []string{}
+	for _, s := range settings {
+		known = append(known, fileKey(s.name))
+	}
+	for _, s := range mapSettings {
+		known = append(known, fileKey(s.name+"s"))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(file)) {
+		if !slices.Contains(known, key) {
+			return nil, "", fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+	}
+
+	return file, path, nil
+}
+
+func writeConfig(w io.Writer, sources []source) error {
+	var b bytes.Buffer
+
+	b.WriteString("# precedence: flag > environment > config file > default\n")
+
+	for _, src := range sources {
+		if src.values == nil {
+			fmt.Fprintf(&b, "%s: %s # %s\n", src.key, strconv.Quote(src.value), src.from)
+			continue
+		}
+
+		if len(src.values) == 0 {
+			fmt.Fprintf(&b, "%s: {} # %s\n", src.key, src.from)
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s: # %s\n", src.key, src.from)
+		for _, k := range slices.Sorted(maps.Keys(src.values)) {
+			fmt.Fprintf(&b, "  %s: %s\n", strconv.Quote(k), strconv.Quote(src.values[k]))
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func parseDuration(v string) (time.Duration, error) {
+	if v == "0" {
+		return 0, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// parseSize reads a number of bytes, with an optional KiB, MiB or GiB
+// suffix.
+func parseSize(v string) (int64, error) {
+	unit := int64(1)
+
+	for suffix, n := range map[string]int64{"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30} {
+		if number, ok := strings.CutSuffix(v, suffix); ok {
+			v, unit = number, n
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("expected a number of bytes, like 512KiB")
+	}
+
+	return n * unit, nil
+}
+
+// newBackend loads the project of config, with each resource stored as
+// config.Backends says.
+func newBackend(ctx context.Context, config Config) (*backend.Backend, error) {
+	csvFactory := backend.CSVReadWriterFactory(config.Dir)
+
+	b, err := backend.New(
+		backend.Config{
+			Dir:             config.Dir,
+			Addr:            config.HTTPAddr,
+			GRPCAddr:        config.GRPCAddr,
+			TLSCertFile:     config.TLSCert,
+			TLSKeyFile:      config.TLSKey,
+			ReadTimeout:     config.ReadTimeout,
+			WriteTimeout:    config.WriteTimeout,
+			IdleTimeout:     config.IdleTimeout,
+			ShutdownTimeout: config.ShutdownTimeout,
+			CORSOrigins:     config.CORSOrigins,
+			CORSCredentials: config.CORSCredentials,
+			MaxBodyBytes:    config.MaxBodyBytes,
+			RequestTimeout:  config.RequestTimeout,
+			RouteTimeouts:   config.RouteTimeouts,
+			RateLimit:       config.RateLimit,
+			RateLimits:      config.RateLimits,
+			LoginAttempts:   config.LoginAttempts,
+			LoginLockout:    config.LoginLockout,
+		},
+		backend.WithReadWriterFactory(func(resource string, fields []backend.FieldSchema) (backend.ReadWriter, error) {
+			if config.Backends[resource] == "memory" {
+				return backend.NewMemoryReadWriter(fields), nil
+			}
+			return csvFactory(resource, fields)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := b.Store().Schemas(ctx)
+
+	for _, resource := range slices.Sorted(maps.Keys(config.Backends)) {
+		if _, ok := schemas[resource]; !ok {
+			err := fmt.Errorf("backend configured for unknown resource %q", resource)
+			// stopping is what closes the read/writers New opened
+			return nil, errors.Join(err, b.Start(ctx), b.Stop(ctx))
+		}
+	}
+
+	return b, nil
+}
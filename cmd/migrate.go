@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Migrate rewrites the records written under older schemas so that every
+// file matches the current layout of its resource.
+func Migrate(ctx *cli.Context) error {
+	config, err := LoadConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	b, err := newBackend(ctx.Context, config)
+	if err != nil {
+		return err
+	}
+
+	if err := b.Start(ctx.Context); err != nil {
+		return err
+	}
+
+	defer b.Stop(ctx.Context)
+
+	s := b.Store()
+
+	schemas := s.Schemas(ctx.Context)
+
+	migrated, err := s.Migrate(ctx.Context)
+	if err != nil {
+		return err
+	}
+
+	resources := []string{}
+	for resource := range schemas {
+		resources = append(resources, resource)
+	}
+	slices.Sort(resources)
+
+	for _, resource := range resources {
+		version, err := s.SchemaVersion(ctx.Context, resource)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.App.Writer, "%s: %d records migrated to schema version %d\n", resource, migrated[resource], version)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"context"
+	encodingcsv "encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/readwriter/csv"
+	"github.com/w-h-a/backend/pkg/backend"
+)
+
+// LintSchemas reports every problem in a _schemas or schema file, which
+// defaults to the one of the example project.
+func LintSchemas(ctx *cli.Context) error {
+	path := ctx.Args().First()
+	if len(path) == 0 {
+		path = backend.SchemaPath("examples/todo")
+	}
+
+	fatal, err := lintSchemas(path, ctx.App.Writer)
+	if err != nil {
+		return err
+	}
+
+	if fatal {
+		return cli.Exit(fmt.Sprintf("%s has errors", path), 1)
+	}
+
+	return nil
+}
+
+// ConvertSchemas writes the schemas and permissions of one format to
+// another. The format of either side follows from its extension: .csv
+// means _schemas.csv with _permissions.csv next to it, and .yaml, .yml or
+// .json a schema file. Existing files are never overwritten.
+func ConvertSchemas(ctx *cli.Context) error {
+	src, dst := ctx.Args().Get(0), ctx.Args().Get(1)
+	if len(src) == 0 || len(dst) == 0 {
+		return cli.Exit("usage: backend schema convert SRC DST", 1)
+	}
+
+	if len(schemaFormat(src)) == 0 {
+		return cli.Exit(fmt.Sprintf("%s: unknown schema format", src), 1)
+	}
+
+	if len(schemaFormat(dst)) == 0 {
+		return cli.Exit(fmt.Sprintf("%s: unknown schema format", dst), 1)
+	}
+
+	file, err := readSchemaFile(src)
+	if err != nil {
+		return err
+	}
+
+	return writeSchemaFile(dst, file)
+}
+
+// schemaFormat is "csv", "yaml" or "json" by the extension of path, or
+// empty when it is none of them.
+func schemaFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	default:
+		return ""
+	}
+}
+
+// lintSchemas writes every problem of a _schemas or schema file to w,
+// prefixed with its path, and reports whether any of them is fatal.
+func lintSchemas(path string, w io.Writer) (bool, error) {
+	problems, err := backend.LintSchemaPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range problems {
+		sep := " "
+		if p.Line > 0 {
+			sep = ""
+		}
+		fmt.Fprintf(w, "%s:%s%s\n", path, sep, p)
+	}
+
+	return v1alpha1.HasFatal(problems), nil
+}
+
+// readSchemaFile reads the schemas and permissions at path in any format.
+func readSchemaFile(path string) (v1alpha1.SchemaFile, error) {
+	if schemaFormat(path) != "csv" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return v1alpha1.SchemaFile{}, err
+		}
+		return v1alpha1.ParseSchemaFile(data)
+	}
+
+	// the csv ReadWriter creates missing files, which a source must not be
+	if _, err := os.Stat(path); err != nil {
+		return v1alpha1.SchemaFile{}, err
+	}
+
+	recs, err := listRecords(path, v1alpha1.SchemaFields)
+	if err != nil {
+		return v1alpha1.SchemaFile{}, err
+	}
+
+	order := []string{}
+	schemas := map[string][]v1alpha1.FieldSchema{}
+
+	for _, rec := range recs {
+		fs := v1alpha1.ToFieldSchema(rec)
+		if _, ok := schemas[fs.Resource]; !ok {
+			order = append(order, fs.Resource)
+		}
+		schemas[fs.Resource] = append(schemas[fs.Resource], fs)
+	}
+
+	var permissions []v1alpha1.Record
+
+	permissionsPath := filepath.Join(filepath.Dir(path), "_permissions.csv")
+	if _, err := os.Stat(permissionsPath); err == nil {
+		permissions, err = listRecords(permissionsPath, schemas["_permissions"])
+		if err != nil {
+			return v1alpha1.SchemaFile{}, err
+		}
+	}
+
+	return v1alpha1.NewSchemaFile(order, schemas, permissions), nil
+}
+
+func listRecords(path string, fields []v1alpha1.FieldSchema) ([]v1alpha1.Record, error) {
+	rw := csv.NewReadWriter(
+		readwriter.WithLocation(path),
+		readwriter.WithFieldSchemas(fields),
+	)
+	defer rw.Close(context.Background())
+
+	return rw.List(context.Background())
+}
+
+// writeSchemaFile writes file to path in the format of its extension.
+func writeSchemaFile(path string, file v1alpha1.SchemaFile) error {
+	format := schemaFormat(path)
+
+	if format != "csv" {
+		data, err := v1alpha1.MarshalSchemaFile(file, format)
+		if err != nil {
+			return err
+		}
+		return createFile(path, func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})
+	}
+
+	schemas := file.FieldSchemas()
+
+	order := []string{}
+	for _, rd := range file.Resources {
+		order = append(order, rd.Name)
+	}
+	for _, resource := range []string{"_permissions", "_users"} {
+		if !slices.Contains(order, resource) {
+			order = slices.Insert(order, 0, resource)
+		}
+	}
+
+	permissionsPath := filepath.Join(filepath.Dir(path), "_permissions.csv")
+
+	if len(file.Permissions) > 0 {
+		if _, err := os.Stat(permissionsPath); err == nil {
+			return fmt.Errorf("%s already exists", permissionsPath)
+		}
+	}
+
+	if err := createFile(path, func(w io.Writer) error {
+		cw := encodingcsv.NewWriter(w)
+		for _, resource := range order {
+			for _, fs := range schemas[resource] {
+				if err := cw.Write(v1alpha1.ToSchemaRecord(fs)); err != nil {
+					return err
+				}
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}); err != nil {
+		return err
+	}
+
+	if len(file.Permissions) == 0 {
+		return nil
+	}
+
+	return createFile(permissionsPath, func(w io.Writer) error {
+		cw := encodingcsv.NewWriter(w)
+		for i, rec := range file.PermissionRecords() {
+			if len(file.Permissions[i].Description) > 0 {
+				rec = append(rec, file.Permissions[i].Description)
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+}
+
+// createFile writes a new file at path and fails when there already is one.
+func createFile(path string, write func(io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
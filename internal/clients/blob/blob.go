@@ -0,0 +1,14 @@
+package blob
+
+import (
+	"context"
+	"io"
+)
+
+// Blob is a content-addressed object store. Put returns the key the content
+// was stored under, which is its SHA-256 hash in hex.
+type Blob interface {
+	Put(ctx context.Context, r io.Reader, opts ...PutOption) (string, error)
+	Get(ctx context.Context, key string, opts ...GetOption) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string, opts ...DeleteOption) error
+}
@@ -0,0 +1,7 @@
+package blob
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("not found")
+)
@@ -0,0 +1,106 @@
+package local
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/w-h-a/backend/internal/clients/blob"
+)
+
+type localBlob struct {
+	options blob.Options
+}
+
+func (b *localBlob) Put(ctx context.Context, r io.Reader, opts ...blob.PutOption) (string, error) {
+	tmp, err := os.CreateTemp(b.options.Location, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	key := hex.EncodeToString(h.Sum(nil))
+
+	dst := b.path(key)
+
+	if _, err := os.Stat(dst); err == nil {
+		return key, nil // same content is already stored
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+func (b *localBlob) Get(ctx context.Context, key string, opts ...blob.GetOption) (io.ReadCloser, error) {
+	if !validKey(key) {
+		return nil, blob.ErrNotFound
+	}
+
+	f, err := os.Open(b.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, blob.ErrNotFound
+	}
+
+	return f, err
+}
+
+func (b *localBlob) Delete(ctx context.Context, key string, opts ...blob.DeleteOption) error {
+	if !validKey(key) {
+		return blob.ErrNotFound
+	}
+
+	err := os.Remove(b.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return blob.ErrNotFound
+	}
+
+	return err
+}
+
+func (b *localBlob) path(key string) string {
+	return filepath.Join(b.options.Location, key[:2], key)
+}
+
+func validKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+func NewBlob(opts ...blob.Option) blob.Blob {
+	options := blob.NewOptions(opts...)
+
+	if err := os.MkdirAll(options.Location, 0755); err != nil {
+		panic(fmt.Sprintf("failed to create blob location %s: %v", options.Location, err))
+	}
+
+	return &localBlob{
+		options: options,
+	}
+}
@@ -0,0 +1,82 @@
+package blob
+
+import "context"
+
+type Option func(*Options)
+
+type Options struct {
+	Location string
+	Context  context.Context
+}
+
+func WithLocation(loc string) Option {
+	return func(o *Options) {
+		o.Location = loc
+	}
+}
+
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Context: context.Background(),
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
+
+type PutOption func(*PutOptions)
+
+type PutOptions struct {
+	Context context.Context
+}
+
+func NewPutOptions(opts ...PutOption) PutOptions {
+	options := PutOptions{
+		Context: context.Background(),
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
+
+type GetOption func(*GetOptions)
+
+type GetOptions struct {
+	Context context.Context
+}
+
+func NewGetOptions(opts ...GetOption) GetOptions {
+	options := GetOptions{
+		Context: context.Background(),
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
+
+type DeleteOption func(*DeleteOptions)
+
+type DeleteOptions struct {
+	Context context.Context
+}
+
+func NewDeleteOptions(opts ...DeleteOption) DeleteOptions {
+	options := DeleteOptions{
+		Context: context.Background(),
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
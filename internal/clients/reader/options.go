@@ -1,17 +1,63 @@
 package reader
 
-import "context"
+import (
+	"context"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
 
 type ListOption func(*ListOptions)
 
 type ListOptions struct {
-	SortBy  string
-	Context context.Context
+	Sort    v1alpha1.Sort
+	Geo     *GeoQuery
+	Nearest *VectorQuery
+	// Projection is the fields the caller keeps. A read/writer may leave
+	// the others out, but must still sort by those of Sort.
+	Projection v1alpha1.Projection
+	Context    context.Context
+}
+
+// GeoQuery restricts a list to records whose geopoint Field lies within
+// Radius kilometers of Center and/or inside Box. Results come back ordered
+// by distance from Center, or from the center of Box when no radius is set.
+type GeoQuery struct {
+	Field  string
+	Center *v1alpha1.GeoPoint
+	Radius float64
+	Box    *v1alpha1.BBox
+}
+
+// VectorQuery restricts a list to the K records whose vector Field is
+// closest to Vector under Metric, nearest first.
+type VectorQuery struct {
+	Field  string
+	Vector []float64
+	K      int
+	Metric string
+}
+
+func WithSort(s v1alpha1.Sort) ListOption {
+	return func(lo *ListOptions) {
+		lo.Sort = s
+	}
+}
+
+func WithGeo(q GeoQuery) ListOption {
+	return func(lo *ListOptions) {
+		lo.Geo = &q
+	}
+}
+
+func WithNearest(q VectorQuery) ListOption {
+	return func(lo *ListOptions) {
+		lo.Nearest = &q
+	}
 }
 
-func WithSortBy(sortBy string) ListOption {
+func WithProjection(p v1alpha1.Projection) ListOption {
 	return func(lo *ListOptions) {
-		lo.SortBy = sortBy
+		lo.Projection = p
 	}
 }
 
@@ -30,7 +76,16 @@ func NewListOptions(opts ...ListOption) ListOptions {
 type ReadOneOption func(*ReadOneOptions)
 
 type ReadOneOptions struct {
-	Context context.Context
+	// Projection is the fields the caller keeps. A read/writer may leave
+	// the others out.
+	Projection v1alpha1.Projection
+	Context    context.Context
+}
+
+func WithReadOneProjection(p v1alpha1.Projection) ReadOneOption {
+	return func(ro *ReadOneOptions) {
+		ro.Projection = p
+	}
 }
 
 func NewReadOneOptions(opts ...ReadOneOption) ReadOneOptions {
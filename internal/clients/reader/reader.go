@@ -7,6 +7,8 @@ import (
 )
 
 type Reader interface {
+	// List returns the records in the order they were created unless the
+	// options ask for another.
 	List(ctx context.Context, opts ...ListOption) ([]v1alpha1.Record, error)
 	ReadOne(ctx context.Context, id string, opts ...ReadOneOption) (v1alpha1.Record, error)
 }
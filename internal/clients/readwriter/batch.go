@@ -0,0 +1,102 @@
+package readwriter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/reader"
+	"github.com/w-h-a/backend/internal/clients/writer"
+)
+
+// WriteBatch applies ops to rw whole or not at all: in one batch when rw,
+// or a read/writer it wraps, is a BatchWriter, and otherwise one at a
+// time, taking back those applied when one fails. Only a BatchWriter
+// honors opts.
+func WriteBatch(ctx context.Context, rw ReadWriter, ops []writer.Op, opts ...writer.BatchOption) error {
+	if bw, ok := unwrap[writer.BatchWriter](rw); ok {
+		return bw.Batch(ctx, ops, opts...)
+	}
+
+	undo, err := Inverse(ctx, rw, ops)
+	if err != nil {
+		return err
+	}
+
+	for i, op := range ops {
+		if err := apply(ctx, rw, op); err != nil {
+			err = &writer.BatchError{Index: i, Err: err}
+
+			// best effort: take back what the others can
+			for j := i - 1; j >= 0; j-- {
+				if uerr := apply(ctx, rw, undo[j]); uerr != nil {
+					err = errors.Join(err, fmt.Errorf("failed to take back op %d: %w", j, uerr))
+				}
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Inverse reads what each of ops, which name the id of their record, would
+// overwrite in rw, after those before it, and returns for each the op
+// that takes it back. Applied last to first, as Undo orders them, and
+// with writer.WithRestore, the inverses of ops that were applied restore
+// what the records held, versions included.
+func Inverse(ctx context.Context, rw ReadWriter, ops []writer.Op) ([]writer.Op, error) {
+	// what each id holds as the ops leave it, nil for nothing
+	held := map[string]v1alpha1.Record{}
+	undo := make([]writer.Op, len(ops))
+
+	for i, op := range ops {
+		prev, ok := held[op.Id]
+		if !ok && op.Action != writer.OpCreate {
+			rec, err := rw.ReadOne(ctx, op.Id)
+			if err != nil && !errors.Is(err, reader.ErrNotFound) {
+				return nil, err
+			}
+			prev = rec
+		}
+
+		switch op.Action {
+		case writer.OpCreate:
+			undo[i] = writer.Op{Action: writer.OpDelete, Id: op.Id}
+			held[op.Id] = op.Record
+		case writer.OpUpdate:
+			undo[i] = writer.Op{Action: writer.OpUpdate, Id: op.Id, Record: prev}
+			held[op.Id] = op.Record
+		case writer.OpDelete:
+			undo[i] = writer.Op{Action: writer.OpCreate, Id: op.Id, Record: prev}
+			held[op.Id] = nil
+		}
+
+		// an op of no known action fails, so needs no inverse
+	}
+
+	return undo, nil
+}
+
+// Undo orders the inverses of ops, as Inverse returns them, to be applied.
+func Undo(inverse []writer.Op) []writer.Op {
+	undo := slices.Clone(inverse)
+	slices.Reverse(undo)
+	return undo
+}
+
+func apply(ctx context.Context, rw ReadWriter, op writer.Op) error {
+	switch op.Action {
+	case writer.OpCreate:
+		return rw.Create(ctx, op.Record)
+	case writer.OpUpdate:
+		return rw.Update(ctx, op.Record)
+	case writer.OpDelete:
+		return rw.Delete(ctx, op.Id)
+	default:
+		return fmt.Errorf("unknown action %q", op.Action)
+	}
+}
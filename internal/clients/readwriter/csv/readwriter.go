@@ -1,6 +1,7 @@
 package csv
 
 import (
+	"bytes"
 	"context"
 	"encoding/csv"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
@@ -15,6 +17,7 @@ import (
 	"github.com/w-h-a/backend/api/v1alpha1"
 	"github.com/w-h-a/backend/internal/clients/reader"
 	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/readwriter/index"
 	"github.com/w-h-a/backend/internal/clients/writer"
 )
 
@@ -24,12 +27,30 @@ type csvReadWriter struct {
 	w       *csv.Writer
 	index   map[string]int64
 	version map[string]int64
-	mtx     sync.RWMutex
+	created map[string]int64
+	geo     map[string]*index.Geo
+	vec     map[string]*index.Vector
+	// rows counts the rows in the file, old versions and tombstones
+	// included.
+	rows int
+	mtx  sync.RWMutex
 }
 
+// List returns the live records in the order they were created, like the
+// memory read/writer, unless a sort or a spatial or vector query asks for
+// another order. Updates leave a record where it is; one created again
+// after its delete goes last.
 func (rw *csvReadWriter) List(ctx context.Context, opts ...reader.ListOption) ([]v1alpha1.Record, error) {
 	options := reader.NewListOptions(opts...)
 
+	if options.Geo != nil {
+		return rw.geoList(ctx, *options.Geo, options)
+	}
+
+	if options.Nearest != nil {
+		return rw.vectorList(ctx, *options.Nearest)
+	}
+
 	rs := []v1alpha1.Record{}
 	var listErr error
 
@@ -50,45 +71,82 @@ func (rw *csvReadWriter) List(ctx context.Context, opts ...reader.ListOption) ([
 		return nil, listErr
 	}
 
-	if len(options.SortBy) == 0 {
-		return rs, nil
-	}
+	rw.mtx.RLock()
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rw.created[rs[i][0]] < rw.created[rs[j][0]]
+	})
+	rw.mtx.RUnlock()
+
+	return readwriter.SortRecords(rs, rw.options, options.Sort)
+}
+
+// Scan reads the file once, through, passing fn each live record as it
+// comes.
+func (rw *csvReadWriter) Scan(ctx context.Context, fn func(v1alpha1.Record) error) error {
+	var scanErr error
 
-	sortDef, ok := rw.options.Schema[options.SortBy]
+	rw.iter(ctx)(func(r v1alpha1.Record, err error) bool {
+		if err == nil {
+			err = fn(r)
+		}
+		scanErr = err
+		return err == nil
+	})
+
+	return scanErr
+}
+
+func (rw *csvReadWriter) geoList(ctx context.Context, q reader.GeoQuery, options reader.ListOptions) ([]v1alpha1.Record, error) {
+	idx, ok := rw.geo[q.Field]
 	if !ok {
-		return nil, fmt.Errorf("field '%s' is not a defined schema field for sorting", options.SortBy)
+		return nil, fmt.Errorf("field '%s' is not a geopoint field", q.Field)
 	}
 
-	sortIndex := sortDef.Index
-	sortType := sortDef.Type
+	var hits []index.GeoHit
 
-	sort.Slice(rs, func(i, j int) bool {
-		if sortIndex >= len(rs[i]) || sortIndex >= len(rs[j]) {
-			return false
-		}
+	switch {
+	case q.Center != nil:
+		hits = idx.Near(*q.Center, q.Radius, q.Box)
+	case q.Box != nil:
+		hits = idx.Within(*q.Box, q.Box.Center())
+	default:
+		return nil, errors.New("geo query needs a center or a box")
+	}
 
-		a := rs[i][sortIndex]
-		b := rs[j][sortIndex]
+	rs := []v1alpha1.Record{}
 
-		if a == "" && b != "" {
-			return false
+	for _, hit := range hits {
+		rec, err := rw.ReadOne(ctx, hit.Id)
+		if errors.Is(err, reader.ErrNotFound) {
+			continue
 		}
-		if a != "" && b == "" {
-			return true
+		if err != nil {
+			return nil, err
 		}
+		rs = append(rs, rec)
+	}
 
-		switch sortType {
-		case "number":
-			aFloat, _ := strconv.ParseFloat(a, 64)
-			bFloat, _ := strconv.ParseFloat(b, 64)
+	return readwriter.SortRecords(rs, rw.options, options.Sort)
+}
 
-			return aFloat < bFloat
-		case "text":
-			return a < b
-		default:
-			return false
+func (rw *csvReadWriter) vectorList(ctx context.Context, q reader.VectorQuery) ([]v1alpha1.Record, error) {
+	idx, ok := rw.vec[q.Field]
+	if !ok {
+		return nil, fmt.Errorf("field '%s' is not a vector field", q.Field)
+	}
+
+	rs := []v1alpha1.Record{}
+
+	for _, hit := range idx.Search(v1alpha1.ToFloat32s(q.Vector), q.K, q.Metric) {
+		rec, err := rw.ReadOne(ctx, hit.Id)
+		if errors.Is(err, reader.ErrNotFound) {
+			continue
 		}
-	})
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, rec)
+	}
 
 	return rs, nil
 }
@@ -129,7 +187,7 @@ func (rw *csvReadWriter) Create(ctx context.Context, r v1alpha1.Record, opts ...
 	rw.mtx.Lock()
 	defer rw.mtx.Unlock()
 
-	if len(r) != len(rw.options.Schema) || len(r[0]) == 0 {
+	if len(r) != rw.options.Width || len(r) < 2 || len(r[0]) == 0 {
 		return errors.New("invalid record")
 	}
 
@@ -142,7 +200,7 @@ func (rw *csvReadWriter) Update(ctx context.Context, r v1alpha1.Record, opts ...
 	rw.mtx.Lock()
 	defer rw.mtx.Unlock()
 
-	if len(r) != len(rw.options.Schema) {
+	if len(r) != rw.options.Width || len(r) < 2 {
 		return errors.New("invalid record")
 	}
 
@@ -159,7 +217,7 @@ func (rw *csvReadWriter) Delete(ctx context.Context, id string, opts ...writer.D
 		return writer.ErrNotFound
 	}
 
-	numCols := len(rw.options.Schema)
+	numCols := rw.options.Width
 
 	tombstone := make(v1alpha1.Record, numCols)
 	tombstone[0] = id
@@ -168,6 +226,105 @@ func (rw *csvReadWriter) Delete(ctx context.Context, id string, opts ...writer.D
 	return rw.append(ctx, tombstone)
 }
 
+// Batch writes the rows of every op with a single write to the file, and
+// cuts the file back to where it was when that write fails. No op is
+// applied unless all of them are valid, and the records of ops are copied,
+// not changed.
+func (rw *csvReadWriter) Batch(ctx context.Context, ops []writer.Op, opts ...writer.BatchOption) error {
+	options := writer.NewBatchOptions(opts...)
+
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+
+	versions := map[string]int64{}
+	versionOf := func(id string) int64 {
+		if v, ok := versions[id]; ok {
+			return v
+		}
+		return rw.version[id]
+	}
+
+	rows := make([]v1alpha1.Record, 0, len(ops))
+
+	for i, op := range ops {
+		var r v1alpha1.Record
+
+		switch op.Action {
+		case writer.OpCreate:
+			if len(op.Record) != rw.options.Width || len(op.Record) < 2 || len(op.Record[0]) == 0 {
+				return &writer.BatchError{Index: i, Err: errors.New("invalid record")}
+			}
+			r = slices.Clone(op.Record)
+			if !options.Restore {
+				r[1] = "1"
+			}
+		case writer.OpUpdate:
+			if len(op.Record) != rw.options.Width || len(op.Record) < 2 {
+				return &writer.BatchError{Index: i, Err: errors.New("invalid record")}
+			}
+			r = slices.Clone(op.Record)
+			if !options.Restore {
+				r[1] = strconv.FormatInt(versionOf(r[0])+1, 10)
+			}
+		case writer.OpDelete:
+			if versionOf(op.Id) < 1 {
+				return &writer.BatchError{Index: i, Err: writer.ErrNotFound}
+			}
+			r = make(v1alpha1.Record, rw.options.Width)
+			r[0] = op.Id
+			r[1] = "0"
+		default:
+			return &writer.BatchError{Index: i, Err: fmt.Errorf("unknown action %q", op.Action)}
+		}
+
+		v, err := strconv.ParseInt(r[1], 10, 64)
+		if err != nil || (v < 1 && op.Action != writer.OpDelete) {
+			return &writer.BatchError{Index: i, Err: errors.New("invalid version")}
+		}
+
+		versions[r[0]] = v
+		rows = append(rows, r)
+	}
+
+	// the offset of every row, to index it once the write succeeds
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	offsets := make([]int, 0, len(rows))
+
+	for _, r := range rows {
+		w.Flush()
+		offsets = append(offsets, buf.Len())
+		if err := w.Write(r); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	pos, err := rw.f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	if _, err := rw.f.Write(buf.Bytes()); err != nil {
+		if terr := rw.f.Truncate(pos); terr != nil {
+			slog.ErrorContext(ctx, "failed to roll back batch", "location", rw.options.Location, "error", terr)
+		}
+		return err
+	}
+
+	for i, r := range rows {
+		if err := rw.track(r, pos+int64(offsets[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (rw *csvReadWriter) Close(ctx context.Context) error {
 	rw.mtx.Lock()
 	defer rw.mtx.Unlock()
@@ -177,6 +334,88 @@ func (rw *csvReadWriter) Close(ctx context.Context) error {
 	return rw.f.Close()
 }
 
+// Stats counts the live records against the rows in the file, which keeps
+// every version written.
+func (rw *csvReadWriter) Stats(_ context.Context) (readwriter.Stats, error) {
+	rw.mtx.RLock()
+	defer rw.mtx.RUnlock()
+
+	info, err := rw.f.Stat()
+	if err != nil {
+		return readwriter.Stats{}, err
+	}
+
+	live := 0
+	for _, v := range rw.version {
+		if v > 0 {
+			live++
+		}
+	}
+
+	return readwriter.Stats{
+		Records:      live,
+		Rows:         rw.rows,
+		Bytes:        info.Size(),
+		IndexEntries: len(rw.index),
+	}, nil
+}
+
+// CheckHealth checks that the file is still open and writable, and that
+// the index points at rows that are in the file, reading back the last
+// one it points at.
+func (rw *csvReadWriter) CheckHealth(_ context.Context) error {
+	rw.mtx.RLock()
+	defer rw.mtx.RUnlock()
+
+	info, err := rw.f.Stat()
+	if err != nil {
+		return fmt.Errorf("file is not open: %w", err)
+	}
+
+	// writing nothing still fails on a file that cannot be written
+	if _, err := rw.f.Write(nil); err != nil {
+		return fmt.Errorf("file is not writable: %w", err)
+	}
+
+	if err := rw.w.Error(); err != nil {
+		return fmt.Errorf("file is not writable: %w", err)
+	}
+
+	if len(rw.index) != len(rw.version) {
+		return fmt.Errorf("index has %d ids but %d versions", len(rw.index), len(rw.version))
+	}
+
+	lastId, last := "", int64(-1)
+
+	for id, offset := range rw.index {
+		if offset >= info.Size() {
+			return fmt.Errorf("index points past the end of the file for %s", id)
+		}
+		if offset > last {
+			lastId, last = id, offset
+		}
+	}
+
+	if last < 0 {
+		return nil
+	}
+
+	r := csv.NewReader(io.NewSectionReader(rw.f, last, info.Size()-last))
+
+	r.FieldsPerRecord = -1
+
+	rec, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read the row of %s: %w", lastId, err)
+	}
+
+	if len(rec) < 2 || rec[0] != lastId || rec[1] != strconv.FormatInt(rw.version[lastId], 10) {
+		return fmt.Errorf("index does not point at the row of %s", lastId)
+	}
+
+	return nil
+}
+
 func (rw *csvReadWriter) iter(_ context.Context) func(yield func(v1alpha1.Record, error) bool) {
 	return func(yield func(v1alpha1.Record, error) bool) {
 		rw.mtx.RLock()
@@ -192,6 +431,7 @@ func (rw *csvReadWriter) iter(_ context.Context) func(yield func(v1alpha1.Record
 		r.FieldsPerRecord = -1
 
 		for {
+			pos := r.InputOffset()
 			rec, err := r.Read()
 			if errors.Is(err, io.EOF) {
 				break
@@ -203,8 +443,10 @@ func (rw *csvReadWriter) iter(_ context.Context) func(yield func(v1alpha1.Record
 			if len(rec) < 2 {
 				continue
 			}
+			// a record created again after its delete numbers its
+			// versions from 1 again, so only the indexed row is latest
 			id, version := rec[0], rec[1]
-			if version == "0" || version != strconv.FormatInt(rw.version[id], 10) {
+			if version == "0" || rw.index[id] != pos {
 				continue // deleted or outdated
 			}
 			if !yield(rec, nil) {
@@ -226,15 +468,76 @@ func (rw *csvReadWriter) append(_ context.Context, r v1alpha1.Record) error {
 
 	rw.w.Flush()
 
+	return rw.track(r, pos)
+}
+
+// place keeps where in the list the record of row r goes: where it was
+// first created, or again at the end once it was deleted.
+func (rw *csvReadWriter) place(r v1alpha1.Record, pos int64) {
+	if r[1] == "0" {
+		delete(rw.created, r[0])
+		return
+	}
+	if _, ok := rw.created[r[0]]; !ok {
+		rw.created[r[0]] = pos
+	}
+}
+
+// track points the indexes at the row r written at pos.
+func (rw *csvReadWriter) track(r v1alpha1.Record, pos int64) error {
+	var err error
+
+	rw.rows++
 	rw.index[r[0]] = pos
+	rw.place(r, pos)
 	rw.version[r[0]], err = strconv.ParseInt(r[1], 10, 64)
 	if err != nil {
 		return err
 	}
 
+	rw.indexRecord(r)
+
 	return nil
 }
 
+// indexRecord keeps the secondary indexes in step with the latest version of
+// a record.
+func (rw *csvReadWriter) indexRecord(r v1alpha1.Record) {
+	for field, idx := range rw.geo {
+		col := rw.options.Schema[field].Index
+
+		if r[1] == "0" || col >= len(r) {
+			idx.Remove(r[0])
+			continue
+		}
+
+		p, err := v1alpha1.ParseGeoPoint(r[col])
+		if err != nil {
+			idx.Remove(r[0])
+			continue
+		}
+
+		idx.Put(r[0], p)
+	}
+
+	for field, idx := range rw.vec {
+		col := rw.options.Schema[field].Index
+
+		if r[1] == "0" || col >= len(r) {
+			idx.Remove(r[0])
+			continue
+		}
+
+		v, err := v1alpha1.DecodeVector(r[col])
+		if err != nil || len(v) == 0 {
+			idx.Remove(r[0])
+			continue
+		}
+
+		idx.Put(r[0], v)
+	}
+}
+
 func NewReadWriter(opts ...readwriter.Option) readwriter.ReadWriter {
 	options := readwriter.NewOptions(opts...)
 
@@ -242,6 +545,18 @@ func NewReadWriter(opts ...readwriter.Option) readwriter.ReadWriter {
 		options: options,
 		index:   map[string]int64{},
 		version: map[string]int64{},
+		created: map[string]int64{},
+		geo:     map[string]*index.Geo{},
+		vec:     map[string]*index.Vector{},
+	}
+
+	for field, def := range options.Schema {
+		switch v1alpha1.BaseType(def.Type) {
+		case "geopoint":
+			rw.geo[field] = index.NewGeo()
+		case "vector":
+			rw.vec[field] = index.NewVector()
+		}
 	}
 
 	f, err := os.OpenFile(options.Location, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
@@ -254,6 +569,8 @@ func NewReadWriter(opts ...readwriter.Option) readwriter.ReadWriter {
 
 	r := csv.NewReader(f)
 
+	r.FieldsPerRecord = -1
+
 	for {
 		pos := r.InputOffset()
 		rec, err := r.Read()
@@ -264,8 +581,21 @@ func NewReadWriter(opts ...readwriter.Option) readwriter.ReadWriter {
 			panic(fmt.Sprintf("failed to read at location %s: %v", rw.options.Location, err))
 		}
 		if len(rec) > 1 {
+			rw.rows++
 			rw.index[rec[0]] = pos
+			rw.place(rec, pos)
 			rw.version[rec[0]], _ = strconv.ParseInt(rec[1], 10, 64)
+			rw.indexRecord(rec)
+		}
+	}
+
+	// appends must not run into a last line that lacks its newline
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			if _, err := f.Write([]byte("\n")); err != nil {
+				panic(fmt.Sprintf("failed to write at location %s: %v", rw.options.Location, err))
+			}
 		}
 	}
 
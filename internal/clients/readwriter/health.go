@@ -0,0 +1,37 @@
+package readwriter
+
+import "context"
+
+// HealthChecker is implemented by read/writers that can tell whether they
+// still work.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// CheckHealth checks rw, looking through the read/writers it is wrapped
+// in. Read/writers that cannot check themselves are taken to be healthy.
+func CheckHealth(ctx context.Context, rw ReadWriter) error {
+	hc, ok := unwrap[HealthChecker](rw)
+	if !ok {
+		return nil
+	}
+	return hc.CheckHealth(ctx)
+}
+
+// unwrap finds the first read/writer that is a T, from rw down through the
+// read/writers it wraps.
+func unwrap[T any](rw ReadWriter) (T, bool) {
+	for {
+		if t, ok := rw.(T); ok {
+			return t, true
+		}
+
+		w, ok := rw.(interface{ Unwrap() ReadWriter })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+
+		rw = w.Unwrap()
+	}
+}
@@ -0,0 +1,231 @@
+package index
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+const (
+	base32           = "0123456789bcdefghjkmnpqrstuvwxyz"
+	geoPrecision     = 4
+	maxCoveringCells = 4096
+)
+
+// GeoHit is a point returned from a spatial query, with its distance in
+// kilometers from the query's center.
+type GeoHit struct {
+	Id       string
+	Point    v1alpha1.GeoPoint
+	Distance float64
+}
+
+// Geo buckets points by geohash so box and radius queries only look at the
+// cells they overlap.
+type Geo struct {
+	cells  map[string]map[string]v1alpha1.GeoPoint
+	owners map[string]string
+	mtx    sync.RWMutex
+}
+
+func (g *Geo) Put(id string, p v1alpha1.GeoPoint) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.remove(id)
+
+	cell := geohash(p, geoPrecision)
+
+	if _, ok := g.cells[cell]; !ok {
+		g.cells[cell] = map[string]v1alpha1.GeoPoint{}
+	}
+
+	g.cells[cell][id] = p
+	g.owners[id] = cell
+}
+
+func (g *Geo) Remove(id string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.remove(id)
+}
+
+func (g *Geo) Len() int {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	return len(g.owners)
+}
+
+// Within returns the points inside box, nearest to center first.
+func (g *Geo) Within(box v1alpha1.BBox, center v1alpha1.GeoPoint) []GeoHit {
+	return g.query(box, center, math.Inf(1))
+}
+
+// Near returns the points within radiusKm of center, nearest first. A
+// non-nil box further restricts the results.
+func (g *Geo) Near(center v1alpha1.GeoPoint, radiusKm float64, box *v1alpha1.BBox) []GeoHit {
+	search := v1alpha1.RadiusBBox(center, radiusKm)
+	hits := g.query(search, center, radiusKm)
+
+	if box == nil {
+		return hits
+	}
+
+	filtered := []GeoHit{}
+	for _, hit := range hits {
+		if box.Contains(hit.Point) {
+			filtered = append(filtered, hit)
+		}
+	}
+
+	return filtered
+}
+
+func (g *Geo) query(box v1alpha1.BBox, center v1alpha1.GeoPoint, radiusKm float64) []GeoHit {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	hits := []GeoHit{}
+
+	collect := func(points map[string]v1alpha1.GeoPoint) {
+		for id, p := range points {
+			if !box.Contains(p) {
+				continue
+			}
+			d := v1alpha1.Distance(center, p)
+			if d > radiusKm {
+				continue
+			}
+			hits = append(hits, GeoHit{Id: id, Point: p, Distance: d})
+		}
+	}
+
+	if cells, ok := coveringCells(box); ok && len(cells) < len(g.cells) {
+		for _, cell := range cells {
+			collect(g.cells[cell])
+		}
+	} else {
+		for _, points := range g.cells {
+			collect(points)
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Distance != hits[j].Distance {
+			return hits[i].Distance < hits[j].Distance
+		}
+		return hits[i].Id < hits[j].Id
+	})
+
+	return hits
+}
+
+func (g *Geo) remove(id string) {
+	cell, ok := g.owners[id]
+	if !ok {
+		return
+	}
+
+	delete(g.cells[cell], id)
+	if len(g.cells[cell]) == 0 {
+		delete(g.cells, cell)
+	}
+
+	delete(g.owners, id)
+}
+
+// coveringCells lists the geohash cells overlapping box. It gives up when
+// the box would need more cells than are worth enumerating.
+func coveringCells(box v1alpha1.BBox) ([]string, bool) {
+	if box.MinLon > box.MaxLon {
+		west, ok := coveringCells(v1alpha1.BBox{MinLat: box.MinLat, MinLon: box.MinLon, MaxLat: box.MaxLat, MaxLon: 180})
+		if !ok {
+			return nil, false
+		}
+		east, ok := coveringCells(v1alpha1.BBox{MinLat: box.MinLat, MinLon: -180, MaxLat: box.MaxLat, MaxLon: box.MaxLon})
+		if !ok {
+			return nil, false
+		}
+		return append(west, east...), true
+	}
+
+	bits := geoPrecision * 5
+	lonStep := 360 / math.Pow(2, float64((bits+1)/2))
+	latStep := 180 / math.Pow(2, float64(bits/2))
+
+	lat0 := math.Floor((box.MinLat+90)/latStep)*latStep - 90
+	lon0 := math.Floor((box.MinLon+180)/lonStep)*lonStep - 180
+
+	rows := int(math.Floor((box.MaxLat-lat0)/latStep)) + 1
+	cols := int(math.Floor((box.MaxLon-lon0)/lonStep)) + 1
+
+	if rows*cols > maxCoveringCells {
+		return nil, false
+	}
+
+	cells := make([]string, 0, rows*cols)
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			p := v1alpha1.GeoPoint{
+				Lat: math.Min(90, lat0+(float64(r)+0.5)*latStep),
+				Lon: math.Min(180, lon0+(float64(c)+0.5)*lonStep),
+			}
+			cells = append(cells, geohash(p, geoPrecision))
+		}
+	}
+
+	return cells, true
+}
+
+func geohash(p v1alpha1.GeoPoint, precision int) string {
+	latLo, latHi := -90.0, 90.0
+	lonLo, lonHi := -180.0, 180.0
+
+	hash := make([]byte, 0, precision)
+	even := true
+	ch, bit := 0, 0
+
+	for len(hash) < precision {
+		if even {
+			mid := (lonLo + lonHi) / 2
+			if p.Lon >= mid {
+				ch |= 1 << (4 - bit)
+				lonLo = mid
+			} else {
+				lonHi = mid
+			}
+		} else {
+			mid := (latLo + latHi) / 2
+			if p.Lat >= mid {
+				ch |= 1 << (4 - bit)
+				latLo = mid
+			} else {
+				latHi = mid
+			}
+		}
+
+		even = !even
+
+		if bit < 4 {
+			bit++
+		} else {
+			hash = append(hash, base32[ch])
+			ch, bit = 0, 0
+		}
+	}
+
+	return string(hash)
+}
+
+func NewGeo() *Geo {
+	return &Geo{
+		cells:  map[string]map[string]v1alpha1.GeoPoint{},
+		owners: map[string]string{},
+		mtx:    sync.RWMutex{},
+	}
+}
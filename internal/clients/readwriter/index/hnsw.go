@@ -0,0 +1,281 @@
+package index
+
+import (
+	"container/heap"
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+const (
+	hnswM              = 16
+	hnswMaxLayer0      = 2 * hnswM
+	hnswEfConstruction = 200
+	hnswEfSearch       = 64
+)
+
+type hnswNode struct {
+	id        string
+	vec       []float32
+	neighbors [][]int
+	deleted   bool
+}
+
+// hnsw is a hierarchical navigable small world graph (Malkov & Yashunin).
+// Removed vectors stay in the graph as deleted nodes so it remains
+// navigable; the owner rebuilds it once they pile up.
+type hnsw struct {
+	metric   string
+	nodes    []*hnswNode
+	ids      map[string]int
+	entry    int
+	maxLevel int
+	deleted  int
+	levelMul float64
+	rng      *rand.Rand
+}
+
+func (g *hnsw) insert(id string, vec []float32) {
+	g.remove(id)
+
+	level := int(math.Floor(-math.Log(1-g.rng.Float64()) * g.levelMul))
+
+	n := &hnswNode{
+		id:        id,
+		vec:       vec,
+		neighbors: make([][]int, level+1),
+	}
+
+	idx := len(g.nodes)
+	g.nodes = append(g.nodes, n)
+	g.ids[id] = idx
+
+	if idx == 0 {
+		g.entry = idx
+		g.maxLevel = level
+		return
+	}
+
+	ep := []int{g.entry}
+
+	for l := g.maxLevel; l > level; l-- {
+		ep = g.nearest(g.searchLayer(vec, ep, 1, l), 1)
+	}
+
+	for l := min(level, g.maxLevel); l >= 0; l-- {
+		candidates := g.searchLayer(vec, ep, hnswEfConstruction, l)
+
+		n.neighbors[l] = g.nearest(candidates, hnswM)
+
+		for _, nb := range n.neighbors[l] {
+			g.connect(nb, idx, l)
+		}
+
+		ep = candidates.ids()
+	}
+
+	if level > g.maxLevel {
+		g.entry = idx
+		g.maxLevel = level
+	}
+}
+
+func (g *hnsw) remove(id string) {
+	idx, ok := g.ids[id]
+	if !ok {
+		return
+	}
+
+	g.nodes[idx].deleted = true
+	g.deleted++
+
+	delete(g.ids, id)
+}
+
+// stale reports whether deleted nodes make up most of the graph.
+func (g *hnsw) stale() bool {
+	return g.deleted > len(g.ids)
+}
+
+// search returns the k live nodes closest to q, or all of them when
+// fewer. Deleted nodes take up room among those a layer search keeps, so
+// it keeps as many more, and looks wider still while too few live ones
+// turn up.
+func (g *hnsw) search(q []float32, k int) []VectorHit {
+	if len(g.ids) == 0 {
+		return []VectorHit{}
+	}
+
+	ep := []int{g.entry}
+
+	for l := g.maxLevel; l > 0; l-- {
+		ep = g.nearest(g.searchLayer(q, ep, 1, l), 1)
+	}
+
+	want := min(k, len(g.ids))
+
+	for ef := max(hnswEfSearch, k) + g.deleted; ; ef *= 2 {
+		found := g.searchLayer(q, ep, ef, 0)
+
+		sort.Sort(byDistance(found))
+
+		hits := []VectorHit{}
+		for _, c := range found {
+			if g.nodes[c.idx].deleted {
+				continue
+			}
+			hits = append(hits, VectorHit{Id: g.nodes[c.idx].id, Distance: c.dist})
+			if len(hits) == k {
+				break
+			}
+		}
+
+		if len(hits) >= want || ef >= len(g.nodes) {
+			return hits
+		}
+	}
+}
+
+// searchLayer is a best-first search of one layer that keeps the ef closest
+// nodes seen.
+func (g *hnsw) searchLayer(q []float32, ep []int, ef int, level int) candidates {
+	visited := map[int]bool{}
+	frontier := &minQueue{}
+	results := &maxQueue{}
+
+	for _, idx := range ep {
+		visited[idx] = true
+		c := candidate{idx: idx, dist: g.distance(q, idx)}
+		heap.Push(frontier, c)
+		heap.Push(results, c)
+	}
+
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(candidate)
+
+		if results.Len() >= ef && c.dist > (*results)[0].dist {
+			break
+		}
+
+		if level >= len(g.nodes[c.idx].neighbors) {
+			continue
+		}
+
+		for _, nb := range g.nodes[c.idx].neighbors[level] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+
+			d := g.distance(q, nb)
+			if results.Len() < ef || d < (*results)[0].dist {
+				heap.Push(frontier, candidate{idx: nb, dist: d})
+				heap.Push(results, candidate{idx: nb, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	return candidates(*results)
+}
+
+// connect adds to as a neighbor of from, dropping from's farthest neighbor
+// when the layer's degree limit is exceeded.
+func (g *hnsw) connect(from int, to int, level int) {
+	n := g.nodes[from]
+
+	n.neighbors[level] = append(n.neighbors[level], to)
+
+	limit := hnswM
+	if level == 0 {
+		limit = hnswMaxLayer0
+	}
+
+	if len(n.neighbors[level]) <= limit {
+		return
+	}
+
+	cs := make(candidates, len(n.neighbors[level]))
+	for i, nb := range n.neighbors[level] {
+		cs[i] = candidate{idx: nb, dist: g.distance(n.vec, nb)}
+	}
+
+	n.neighbors[level] = g.nearest(cs, limit)
+}
+
+func (g *hnsw) nearest(cs candidates, k int) []int {
+	sorted := make(candidates, len(cs))
+	copy(sorted, cs)
+	sort.Sort(byDistance(sorted))
+
+	if len(sorted) > k {
+		sorted = sorted[:k]
+	}
+
+	return sorted.ids()
+}
+
+func (g *hnsw) distance(q []float32, idx int) float64 {
+	return v1alpha1.VectorDistance(g.metric, q, g.nodes[idx].vec)
+}
+
+func newHNSW(metric string) *hnsw {
+	return &hnsw{
+		metric:   metric,
+		nodes:    []*hnswNode{},
+		ids:      map[string]int{},
+		levelMul: 1 / math.Log(hnswM),
+		rng:      rand.New(rand.NewPCG(1, 2)),
+	}
+}
+
+type candidate struct {
+	idx  int
+	dist float64
+}
+
+type candidates []candidate
+
+func (cs candidates) ids() []int {
+	ids := make([]int, len(cs))
+	for i, c := range cs {
+		ids[i] = c.idx
+	}
+	return ids
+}
+
+type byDistance candidates
+
+func (b byDistance) Len() int           { return len(b) }
+func (b byDistance) Less(i, j int) bool { return b[i].dist < b[j].dist }
+func (b byDistance) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+type minQueue []candidate
+
+func (q minQueue) Len() int           { return len(q) }
+func (q minQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q minQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *minQueue) Push(x any)        { *q = append(*q, x.(candidate)) }
+func (q *minQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+type maxQueue []candidate
+
+func (q maxQueue) Len() int           { return len(q) }
+func (q maxQueue) Less(i, j int) bool { return q[i].dist > q[j].dist }
+func (q maxQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *maxQueue) Push(x any)        { *q = append(*q, x.(candidate)) }
+func (q *maxQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
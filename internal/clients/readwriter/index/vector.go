@@ -0,0 +1,133 @@
+package index
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+// exactSearchLimit is the number of vectors up to which a brute-force scan
+// is used instead of the approximate graph.
+const exactSearchLimit = 1024
+
+type VectorHit struct {
+	Id       string
+	Distance float64
+}
+
+// Vector answers nearest-neighbor queries over the vectors of one field. It
+// scans every vector for small sets and switches to an HNSW graph, one per
+// metric, once the set grows past exactSearchLimit.
+type Vector struct {
+	vecs   map[string][]float32
+	graphs map[string]*hnsw
+	mtx    sync.RWMutex
+}
+
+func (x *Vector) Put(id string, v []float32) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+
+	x.vecs[id] = v
+
+	for _, g := range x.graphs {
+		g.insert(id, v)
+	}
+}
+
+func (x *Vector) Remove(id string) {
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+
+	if _, ok := x.vecs[id]; !ok {
+		return
+	}
+
+	delete(x.vecs, id)
+
+	for _, g := range x.graphs {
+		g.remove(id)
+	}
+}
+
+func (x *Vector) Len() int {
+	x.mtx.RLock()
+	defer x.mtx.RUnlock()
+
+	return len(x.vecs)
+}
+
+// Search returns the k vectors closest to q, nearest first.
+func (x *Vector) Search(q []float32, k int, metric string) []VectorHit {
+	x.mtx.RLock()
+
+	if len(x.vecs) <= exactSearchLimit {
+		defer x.mtx.RUnlock()
+		return x.exact(q, k, metric)
+	}
+
+	if g, ok := x.graphs[metric]; ok && !g.stale() {
+		defer x.mtx.RUnlock()
+		return g.search(q, k)
+	}
+
+	x.mtx.RUnlock()
+
+	x.mtx.Lock()
+	defer x.mtx.Unlock()
+
+	g, ok := x.graphs[metric]
+	if !ok || g.stale() {
+		g = x.build(metric)
+	}
+
+	return g.search(q, k)
+}
+
+func (x *Vector) exact(q []float32, k int, metric string) []VectorHit {
+	hits := make([]VectorHit, 0, len(x.vecs))
+
+	for id, v := range x.vecs {
+		hits = append(hits, VectorHit{Id: id, Distance: v1alpha1.VectorDistance(metric, q, v)})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Distance != hits[j].Distance {
+			return hits[i].Distance < hits[j].Distance
+		}
+		return hits[i].Id < hits[j].Id
+	})
+
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+
+	return hits
+}
+
+func (x *Vector) build(metric string) *hnsw {
+	g := newHNSW(metric)
+
+	ids := make([]string, 0, len(x.vecs))
+	for id := range x.vecs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		g.insert(id, x.vecs[id])
+	}
+
+	x.graphs[metric] = g
+
+	return g
+}
+
+func NewVector() *Vector {
+	return &Vector{
+		vecs:   map[string][]float32{},
+		graphs: map[string]*hnsw{},
+		mtx:    sync.RWMutex{},
+	}
+}
@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/reader"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/writer"
+)
+
+// memoryReadWriter keeps records in memory only. It backs resources whose
+// records come from configuration rather than from a data file.
+type memoryReadWriter struct {
+	options readwriter.Options
+	records map[string]v1alpha1.Record
+	order   []string
+	mtx     sync.RWMutex
+}
+
+func (rw *memoryReadWriter) List(ctx context.Context, opts ...reader.ListOption) ([]v1alpha1.Record, error) {
+	options := reader.NewListOptions(opts...)
+
+	if options.Geo != nil || options.Nearest != nil {
+		return nil, errors.New("spatial and vector queries are not supported in memory")
+	}
+
+	rw.mtx.RLock()
+	rs := make([]v1alpha1.Record, 0, len(rw.order))
+	for _, id := range rw.order {
+		rs = append(rs, slices.Clone(rw.records[id]))
+	}
+	rw.mtx.RUnlock()
+
+	return readwriter.SortRecords(rs, rw.options, options.Sort)
+}
+
+func (rw *memoryReadWriter) ReadOne(ctx context.Context, id string, opts ...reader.ReadOneOption) (v1alpha1.Record, error) {
+	rw.mtx.RLock()
+	defer rw.mtx.RUnlock()
+
+	rec, ok := rw.records[id]
+	if !ok {
+		return nil, reader.ErrNotFound
+	}
+
+	return slices.Clone(rec), nil
+}
+
+func (rw *memoryReadWriter) Create(ctx context.Context, r v1alpha1.Record, opts ...writer.WriteOption) error {
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+
+	if len(r) != rw.options.Width || len(r) < 2 || len(r[0]) == 0 {
+		return errors.New("invalid record")
+	}
+
+	if _, ok := rw.records[r[0]]; !ok {
+		rw.order = append(rw.order, r[0])
+	}
+
+	r[1] = "1"
+
+	rw.records[r[0]] = slices.Clone(r)
+
+	return nil
+}
+
+func (rw *memoryReadWriter) Update(ctx context.Context, r v1alpha1.Record, opts ...writer.UpdateOption) error {
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+
+	if len(r) != rw.options.Width || len(r) < 2 {
+		return errors.New("invalid record")
+	}
+
+	old, ok := rw.records[r[0]]
+	if !ok {
+		return writer.ErrNotFound
+	}
+
+	v, _ := strconv.ParseInt(old[1], 10, 64)
+	r[1] = strconv.FormatInt(v+1, 10)
+
+	rw.records[r[0]] = slices.Clone(r)
+
+	return nil
+}
+
+func (rw *memoryReadWriter) Delete(ctx context.Context, id string, opts ...writer.DeleteOption) error {
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+
+	if _, ok := rw.records[id]; !ok {
+		return writer.ErrNotFound
+	}
+
+	delete(rw.records, id)
+	rw.order = slices.DeleteFunc(rw.order, func(other string) bool { return other == id })
+
+	return nil
+}
+
+// Batch applies every op or, when one of them is invalid, none. Every op
+// is checked before any is applied, and the records of ops are copied, not
+// changed.
+func (rw *memoryReadWriter) Batch(ctx context.Context, ops []writer.Op, opts ...writer.BatchOption) error {
+	options := writer.NewBatchOptions(opts...)
+
+	rw.mtx.Lock()
+	defer rw.mtx.Unlock()
+
+	// whether each id holds a record as the ops before leave it
+	live := map[string]bool{}
+	exists := func(id string) bool {
+		if ok, seen := live[id]; seen {
+			return ok
+		}
+		_, ok := rw.records[id]
+		return ok
+	}
+
+	for i, op := range ops {
+		switch op.Action {
+		case writer.OpCreate, writer.OpUpdate:
+			r := op.Record
+			if len(r) != rw.options.Width || len(r) < 2 || (op.Action == writer.OpCreate && len(r[0]) == 0) {
+				return &writer.BatchError{Index: i, Err: errors.New("invalid record")}
+			}
+			if op.Action == writer.OpUpdate && !exists(r[0]) {
+				return &writer.BatchError{Index: i, Err: writer.ErrNotFound}
+			}
+			if options.Restore {
+				if v, err := strconv.ParseInt(r[1], 10, 64); err != nil || v < 1 {
+					return &writer.BatchError{Index: i, Err: errors.New("invalid version")}
+				}
+			}
+			live[r[0]] = true
+		case writer.OpDelete:
+			if !exists(op.Id) {
+				return &writer.BatchError{Index: i, Err: writer.ErrNotFound}
+			}
+			live[op.Id] = false
+		default:
+			return &writer.BatchError{Index: i, Err: fmt.Errorf("unknown action %q", op.Action)}
+		}
+	}
+
+	for _, op := range ops {
+		switch op.Action {
+		case writer.OpCreate:
+			r := slices.Clone(op.Record)
+			if !options.Restore {
+				r[1] = "1"
+			}
+			if _, ok := rw.records[r[0]]; !ok {
+				rw.order = append(rw.order, r[0])
+			}
+			rw.records[r[0]] = r
+		case writer.OpUpdate:
+			r := slices.Clone(op.Record)
+			if !options.Restore {
+				v, _ := strconv.ParseInt(rw.records[r[0]][1], 10, 64)
+				r[1] = strconv.FormatInt(v+1, 10)
+			}
+			rw.records[r[0]] = r
+		case writer.OpDelete:
+			delete(rw.records, op.Id)
+			rw.order = slices.DeleteFunc(rw.order, func(other string) bool { return other == op.Id })
+		}
+	}
+
+	return nil
+}
+
+// Stats reports nothing on disk: only live records are kept.
+func (rw *memoryReadWriter) Stats(ctx context.Context) (readwriter.Stats, error) {
+	rw.mtx.RLock()
+	defer rw.mtx.RUnlock()
+
+	return readwriter.Stats{
+		Records:      len(rw.records),
+		Rows:         len(rw.records),
+		Bytes:        -1,
+		IndexEntries: len(rw.records),
+	}, nil
+}
+
+func (rw *memoryReadWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+func NewReadWriter(opts ...readwriter.Option) readwriter.ReadWriter {
+	options := readwriter.NewOptions(opts...)
+
+	return &memoryReadWriter{
+		options: options,
+		records: map[string]v1alpha1.Record{},
+		order:   []string{},
+	}
+}
@@ -1,6 +1,10 @@
 package readwriter
 
-import "context"
+import (
+	"context"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
 
 type Option func(*Options)
 
@@ -10,6 +14,9 @@ type Options struct {
 		Index int
 		Type  string
 	}
+	// Width is the number of columns in a record. It exceeds the size of
+	// Schema when dropped fields still hold a column.
+	Width   int
 	Context context.Context
 }
 
@@ -25,6 +32,35 @@ func WithSchema(schema map[string]struct {
 }) Option {
 	return func(o *Options) {
 		o.Schema = schema
+		o.Width = len(schema)
+	}
+}
+
+// WithFieldSchemas derives the schema from a resource's layout, taking column
+// indexes from its order. Dropped fields keep their column but are not part
+// of the schema.
+func WithFieldSchemas(schemas []v1alpha1.FieldSchema) Option {
+	schema := map[string]struct {
+		Index int
+		Type  string
+	}{}
+
+	for i, fs := range schemas {
+		if fs.Dropped {
+			continue
+		}
+		schema[fs.Field] = struct {
+			Index int
+			Type  string
+		}{
+			Index: i,
+			Type:  fs.Type,
+		}
+	}
+
+	return func(o *Options) {
+		o.Schema = schema
+		o.Width = len(schemas)
 	}
 }
 
@@ -0,0 +1,40 @@
+package readwriter
+
+import (
+	"context"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/reader"
+)
+
+// Scanner is implemented by read/writers that can pass over their records
+// one at a time, in no particular order, without holding them all.
+type Scanner interface {
+	Scan(ctx context.Context, fn func(v1alpha1.Record) error) error
+}
+
+// Scan calls fn with every record of rw that opts select, stopping at the
+// first error fn returns. It passes over rw, or a read/writer it wraps,
+// that is a Scanner, unless opts ask for a spatial or vector query, which
+// a list answers instead. fn must not call rw, which a scan may hold
+// locked.
+func Scan(ctx context.Context, rw ReadWriter, fn func(v1alpha1.Record) error, opts ...reader.ListOption) error {
+	options := reader.NewListOptions(opts...)
+
+	if sc, ok := unwrap[Scanner](rw); ok && options.Geo == nil && options.Nearest == nil {
+		return sc.Scan(ctx, fn)
+	}
+
+	recs, err := rw.List(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range recs {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
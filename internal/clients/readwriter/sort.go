@@ -0,0 +1,131 @@
+package readwriter
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// SortRecords orders records as s says, by fields of the schema in
+// options. The sort is stable and _id breaks every tie, so that records
+// come in the same order every time. Without keys the given order is
+// kept.
+func SortRecords(rs []v1alpha1.Record, options Options, s v1alpha1.Sort) ([]v1alpha1.Record, error) {
+	if len(s.Keys) == 0 {
+		return rs, nil
+	}
+
+	compareText, err := textComparer(s.Collation)
+	if err != nil {
+		return nil, err
+	}
+
+	type column struct {
+		index   int
+		compare func(a, b string) int
+		desc    bool
+	}
+
+	columns := make([]column, 0, len(s.Keys)+1)
+
+	for _, key := range s.Keys {
+		def, ok := options.Schema[key.Field]
+		if !ok {
+			return nil, fmt.Errorf("field '%s' is not a defined schema field for sorting", key.Field)
+		}
+
+		var compare func(a, b string) int
+
+		switch t := v1alpha1.BaseType(def.Type); t {
+		case "number":
+			compare = compareNumbers
+		case "text", "ref":
+			compare = compareText
+		case "list":
+			compare = func(a, b string) int {
+				return slices.CompareFunc(strings.Split(a, ","), strings.Split(b, ","), compareText)
+			}
+		default:
+			return nil, fmt.Errorf("field '%s' is a %s, which cannot be sorted by", key.Field, t)
+		}
+
+		columns = append(columns, column{index: def.Index, compare: compare, desc: key.Desc})
+	}
+
+	if def, ok := options.Schema["_id"]; ok {
+		columns = append(columns, column{index: def.Index, compare: strings.Compare})
+	}
+
+	slices.SortStableFunc(rs, func(a, b v1alpha1.Record) int {
+		for _, col := range columns {
+			x, y := valueAt(a, col.index), valueAt(b, col.index)
+
+			// nulls go where s says, whichever the direction
+			switch xNull, yNull := x == "", y == ""; {
+			case xNull && yNull:
+				continue
+			case xNull != yNull:
+				if xNull == s.NullsFirst {
+					return -1
+				}
+				return 1
+			}
+
+			c := col.compare(x, y)
+			if col.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+
+		return 0
+	})
+
+	return rs, nil
+}
+
+// valueAt is the value of column i of rec, empty when null or when rec was
+// written before the column was added.
+func valueAt(rec v1alpha1.Record, i int) string {
+	if i >= len(rec) || rec[i] == v1alpha1.NullValue {
+		return ""
+	}
+	return rec[i]
+}
+
+func compareNumbers(a, b string) int {
+	x, _ := strconv.ParseFloat(a, 64)
+	y, _ := strconv.ParseFloat(b, 64)
+	return cmp.Compare(x, y)
+}
+
+// textComparer compares text as collation says. A collator is not safe to
+// share, so each sort makes its own.
+func textComparer(collation string) (func(a, b string) int, error) {
+	tag, err := v1alpha1.ParseCollation(collation)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case collation == v1alpha1.CollationNoCase:
+		fold := cases.Fold()
+		return func(a, b string) int {
+			return strings.Compare(fold.String(a), fold.String(b))
+		}, nil
+	case tag != language.Und:
+		c := collate.New(tag)
+		return c.CompareString, nil
+	default:
+		return strings.Compare, nil
+	}
+}
@@ -0,0 +1,44 @@
+package readwriter
+
+import "context"
+
+// Stats describes what a read/writer holds.
+type Stats struct {
+	// Records is how many live records there are.
+	Records int
+	// Rows is how many rows are stored, live or not. A log keeps the old
+	// versions of a record and a tombstone for it once deleted.
+	Rows int
+	// Bytes is the size of what is stored on disk, or -1 when nothing is.
+	Bytes int64
+	// IndexEntries is how many ids the index of the read/writer maps.
+	IndexEntries int
+}
+
+// DeadRatio is the share of rows that no longer hold a live record, and so
+// what compacting would reclaim.
+func (s Stats) DeadRatio() float64 {
+	if s.Rows == 0 {
+		return 0
+	}
+	return float64(s.Rows-s.Records) / float64(s.Rows)
+}
+
+// StatsReader is implemented by read/writers that can describe what they
+// hold.
+type StatsReader interface {
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// ReadStats returns the stats of rw, looking through the read/writers it is
+// wrapped in. ok is false when rw cannot tell.
+func ReadStats(ctx context.Context, rw ReadWriter) (Stats, bool, error) {
+	sr, ok := unwrap[StatsReader](rw)
+	if !ok {
+		return Stats{}, false, nil
+	}
+
+	stats, err := sr.Stats(ctx)
+
+	return stats, true, err
+}
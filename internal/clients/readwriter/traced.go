@@ -0,0 +1,141 @@
+package readwriter
+
+import (
+	"context"
+	"errors"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/reader"
+	"github.com/w-h-a/backend/internal/clients/writer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/w-h-a/backend/internal/clients/readwriter")
+
+type tracedReadWriter struct {
+	rw       ReadWriter
+	resource string
+}
+
+// Traced wraps rw so that every call is a span, named after the method and
+// carrying the resource rw stores.
+func Traced(rw ReadWriter, resource string) ReadWriter {
+	if t, ok := rw.(*tracedReadWriter); ok {
+		return t
+	}
+	return &tracedReadWriter{rw: rw, resource: resource}
+}
+
+// Unwrap returns the read/writer underneath.
+func (t *tracedReadWriter) Unwrap() ReadWriter {
+	return t.rw
+}
+
+func (t *tracedReadWriter) List(ctx context.Context, opts ...reader.ListOption) ([]v1alpha1.Record, error) {
+	ctx, span := t.start(ctx, "readwriter.List")
+	defer span.End()
+
+	recs, err := t.rw.List(ctx, opts...)
+	span.SetAttributes(attribute.Int("record.count", len(recs)))
+	t.end(span, err)
+
+	return recs, err
+}
+
+func (t *tracedReadWriter) ReadOne(ctx context.Context, id string, opts ...reader.ReadOneOption) (v1alpha1.Record, error) {
+	ctx, span := t.start(ctx, "readwriter.ReadOne", attribute.String("record.id", id))
+	defer span.End()
+
+	rec, err := t.rw.ReadOne(ctx, id, opts...)
+	t.end(span, err)
+
+	return rec, err
+}
+
+func (t *tracedReadWriter) Create(ctx context.Context, r v1alpha1.Record, opts ...writer.WriteOption) error {
+	ctx, span := t.start(ctx, "readwriter.Create", recordID(r))
+	defer span.End()
+
+	err := t.rw.Create(ctx, r, opts...)
+	t.end(span, err)
+
+	return err
+}
+
+func (t *tracedReadWriter) Update(ctx context.Context, r v1alpha1.Record, opts ...writer.UpdateOption) error {
+	ctx, span := t.start(ctx, "readwriter.Update", recordID(r))
+	defer span.End()
+
+	err := t.rw.Update(ctx, r, opts...)
+	t.end(span, err)
+
+	return err
+}
+
+func (t *tracedReadWriter) Delete(ctx context.Context, id string, opts ...writer.DeleteOption) error {
+	ctx, span := t.start(ctx, "readwriter.Delete", attribute.String("record.id", id))
+	defer span.End()
+
+	err := t.rw.Delete(ctx, id, opts...)
+	t.end(span, err)
+
+	return err
+}
+
+func (t *tracedReadWriter) Batch(ctx context.Context, ops []writer.Op, opts ...writer.BatchOption) error {
+	ctx, span := t.start(ctx, "readwriter.Batch", attribute.Int("op.count", len(ops)))
+	defer span.End()
+
+	err := WriteBatch(ctx, t.rw, ops, opts...)
+	t.end(span, err)
+
+	return err
+}
+
+func (t *tracedReadWriter) Scan(ctx context.Context, fn func(v1alpha1.Record) error) error {
+	ctx, span := t.start(ctx, "readwriter.Scan")
+	defer span.End()
+
+	count := 0
+	err := Scan(ctx, t.rw, func(rec v1alpha1.Record) error {
+		count++
+		return fn(rec)
+	})
+	span.SetAttributes(attribute.Int("record.count", count))
+	t.end(span, err)
+
+	return err
+}
+
+// Close is not traced: it runs at shutdown, outside of any request.
+func (t *tracedReadWriter) Close(ctx context.Context) error {
+	return t.rw.Close(ctx)
+}
+
+func (t *tracedReadWriter) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(append(attrs, attribute.String("resource.name", t.resource))...))
+}
+
+// end records err on span. A record that does not exist is an answer, not
+// a failure.
+func (t *tracedReadWriter) end(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+
+	if !errors.Is(err, reader.ErrNotFound) && !errors.Is(err, writer.ErrNotFound) {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+func recordID(r v1alpha1.Record) attribute.KeyValue {
+	if len(r) == 0 {
+		return attribute.String("record.id", "")
+	}
+	return attribute.String("record.id", r[0])
+}
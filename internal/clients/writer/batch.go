@@ -0,0 +1,44 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+// The actions of an Op.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Op is one write of a batch. Create and Update write Record; Delete
+// removes the record with Id.
+type Op struct {
+	Action string
+	Id     string
+	Record v1alpha1.Record
+}
+
+// BatchWriter is implemented by writers that can apply many writes at
+// once. A batch is applied whole or not at all.
+type BatchWriter interface {
+	Batch(ctx context.Context, ops []Op, opts ...BatchOption) error
+}
+
+// BatchError is the error of a batch that was not applied because of the
+// op at Index.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("op %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
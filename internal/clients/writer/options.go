@@ -55,3 +55,32 @@ func NewDeleteOptions(opts ...DeleteOption) DeleteOptions {
 
 	return options
 }
+
+type BatchOption func(*BatchOptions)
+
+type BatchOptions struct {
+	Context context.Context
+	// Restore writes records at the versions they hold instead of
+	// numbering them anew.
+	Restore bool
+}
+
+// WithRestore makes a batch write records at the versions they hold, to
+// put back records that other writes overwrote as they were.
+func WithRestore() BatchOption {
+	return func(bo *BatchOptions) {
+		bo.Restore = true
+	}
+}
+
+func NewBatchOptions(opts ...BatchOption) BatchOptions {
+	options := BatchOptions{
+		Context: context.Background(),
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
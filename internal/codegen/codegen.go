@@ -0,0 +1,208 @@
+package codegen
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"maps"
+	"slices"
+	"strconv"
+	"text/template"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+var tmpl = template.Must(template.New("").ParseFS(templates, "templates/*.tmpl"))
+
+// model is a resource as the templates see it.
+type model struct {
+	Resource string
+	Name     string
+	Fields   []field
+	// GeoFields are the geopoint fields, which lists can be filtered on.
+	GeoFields []field
+}
+
+type field struct {
+	Field string
+	// Key is the property name of the field in TypeScript.
+	Key      string
+	Name     string
+	GoType   string
+	TSType   string
+	Comment  string
+	Optional bool
+}
+
+// Go generates a package with a struct per resource and a client for the
+// records API. The result maps file names to their content.
+func Go(schemas map[string][]v1alpha1.FieldSchema, pkg string) (map[string][]byte, error) {
+	data := map[string]any{
+		"Package": pkg,
+		"Models":  models(schemas),
+	}
+
+	files := map[string][]byte{}
+
+	for name, t := range map[string]string{"models.go": "go_models.tmpl", "client.go": "go_client.tmpl"} {
+		var b bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&b, t, data); err != nil {
+			return nil, err
+		}
+
+		src, err := format.Source(b.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("generated %s does not parse: %w", name, err)
+		}
+
+		files[name] = src
+	}
+
+	return files, nil
+}
+
+// TypeScript generates an interface per resource and a client for the
+// records API. The result maps file names to their content.
+func TypeScript(schemas map[string][]v1alpha1.FieldSchema) (map[string][]byte, error) {
+	ms := models(schemas)
+
+	data := map[string]any{
+		"Models": ms,
+		"HasGeo": slices.ContainsFunc(ms, func(m model) bool { return len(m.GeoFields) > 0 }),
+	}
+
+	files := map[string][]byte{}
+
+	for name, t := range map[string]string{"models.ts": "ts_models.tmpl", "client.ts": "ts_client.tmpl"} {
+		var b bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&b, t, data); err != nil {
+			return nil, err
+		}
+
+		files[name] = b.Bytes()
+	}
+
+	return files, nil
+}
+
+func models(schemas map[string][]v1alpha1.FieldSchema) []model {
+	ms := []model{}
+
+	for _, resource := range slices.Sorted(maps.Keys(schemas)) {
+		m := model{
+			Resource: resource,
+			Name:     v1alpha1.TypeName(resource),
+		}
+
+		names := map[string]bool{}
+
+		for _, fs := range schemas[resource] {
+			if fs.Dropped {
+				continue
+			}
+
+			f := toField(fs)
+
+			// fields whose names only differ in punctuation must not collide
+			for names[f.Name] {
+				f.Name += "_"
+			}
+			names[f.Name] = true
+
+			m.Fields = append(m.Fields, f)
+
+			if v1alpha1.BaseType(fs.Type) == "geopoint" {
+				m.GeoFields = append(m.GeoFields, f)
+			}
+		}
+
+		ms = append(ms, m)
+	}
+
+	return ms
+}
+
+// toField maps a field onto Go and TypeScript types. Fields the server
+// fills in when they are left out are optional: pointers in Go, so that
+// leaving them out is not the same as sending a zero value.
+func toField(fs v1alpha1.FieldSchema) field {
+	f := field{
+		Field:    fs.Field,
+		Key:      fs.Field,
+		Name:     v1alpha1.TypeName(fs.Field),
+		Optional: !fs.Required || len(fs.Default) > 0 || fs.Nullable,
+	}
+
+	switch fs.Field {
+	case "_id":
+		f.Name = "Id"
+		f.Optional = true
+	case "_v":
+		f.Name = "Version"
+		f.Optional = true
+	}
+
+	if !token.IsIdentifier(f.Key) {
+		f.Key = strconv.Quote(f.Key)
+	}
+
+	if len(f.Name) == 0 {
+		f.Name = "Field"
+	} else if f.Name[0] >= '0' && f.Name[0] <= '9' {
+		f.Name = "F" + f.Name
+	}
+
+	pointer := fs.Nullable || len(fs.Default) > 0
+
+	switch v1alpha1.BaseType(fs.Type) {
+	case "number":
+		f.GoType, f.TSType = "float64", "number"
+		if fs.Field == "_v" {
+			f.GoType = "int"
+		}
+	case "text":
+		f.GoType, f.TSType = "string", "string"
+		if len(fs.Regex) > 0 && fs.Field != "_id" {
+			f.Comment = "Matches " + strconv.Quote(fs.Regex) + "."
+		}
+	case "ref":
+		target, _ := v1alpha1.RefResource(fs)
+		f.GoType, f.TSType = "string", "string"
+		f.Comment = "Id of a record of " + target + "."
+	case "list":
+		f.GoType, f.TSType = "[]string", "string[]"
+		pointer = false
+	case "file":
+		f.GoType, f.TSType = "File", "File"
+		f.Comment = "Set by uploading content."
+		f.Optional = true
+		pointer = true
+	case "geopoint":
+		f.GoType, f.TSType = "GeoPoint", "GeoPoint"
+		pointer = true
+	case "vector":
+		f.GoType, f.TSType = "[]float64", "number[]"
+		if dim, ok := v1alpha1.VectorDim(fs); ok {
+			f.Comment = "Has " + strconv.Itoa(dim) + " dimensions."
+		}
+		pointer = false
+	default:
+		f.GoType, f.TSType = "any", "unknown"
+		pointer = false
+	}
+
+	if pointer {
+		f.GoType = "*" + f.GoType
+	}
+
+	if fs.Nullable {
+		f.TSType += " | null"
+	}
+
+	return f
+}
@@ -0,0 +1,31 @@
+package grpc
+
+import (
+	"strings"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// ProjectionFromMask is the projection a field mask asks for: the fields
+// its paths name, or every field when it has none. Records are flat, so a
+// path into a field is refused with InvalidArgument, as is a field that
+// schemas, the fields of the resource, do not have.
+func ProjectionFromMask(mask *fieldmaskpb.FieldMask, schemas []v1alpha1.FieldSchema) (v1alpha1.Projection, error) {
+	p := v1alpha1.Projection{}
+
+	for _, path := range mask.GetPaths() {
+		if len(path) == 0 || strings.Contains(path, ".") {
+			return v1alpha1.Projection{}, status.Errorf(codes.InvalidArgument, "invalid field mask path %q: expected a field of the resource", path)
+		}
+		p.Fields = append(p.Fields, path)
+	}
+
+	if err := p.Validate(schemas); err != nil {
+		return v1alpha1.Projection{}, status.Errorf(codes.InvalidArgument, "invalid field mask: %v", err)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,116 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/services/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// LivenessService is the service to check for liveness. The empty service,
+// as usual, stands for readiness, and each component can be checked by its
+// name, like "store" or "readwriter.notes".
+const LivenessService = "liveness"
+
+// watchInterval is how often Watch checks again.
+const watchInterval = 5 * time.Second
+
+type healthHandler struct {
+	healthpb.UnimplementedHealthServer
+	store *store.Store
+}
+
+func (h *healthHandler) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	st, ok := h.status(ctx, req.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+func (h *healthHandler) List(ctx context.Context, _ *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	ready := h.store.CheckHealth(ctx)
+
+	statuses := map[string]*healthpb.HealthCheckResponse{
+		"":              {Status: servingStatus(ready.Status)},
+		LivenessService: {Status: servingStatus(h.store.CheckLiveness(ctx).Status)},
+	}
+
+	for component, c := range ready.Components {
+		statuses[component] = &healthpb.HealthCheckResponse{Status: servingStatus(c.Status)}
+	}
+
+	return &healthpb.HealthListResponse{Statuses: statuses}, nil
+}
+
+// Watch sends the status of the service, then again whenever it changes. A
+// service that does not exist is watched as SERVICE_UNKNOWN, in case it
+// comes to.
+func (h *healthHandler) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	ctx := stream.Context()
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+
+	for {
+		st, ok := h.status(ctx, req.GetService())
+		if !ok {
+			st = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+
+		if st != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: st}); err != nil {
+				return err
+			}
+			last = st
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *healthHandler) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	if service == LivenessService {
+		return servingStatus(h.store.CheckLiveness(ctx).Status), true
+	}
+
+	ready := h.store.CheckHealth(ctx)
+
+	if len(service) == 0 {
+		return servingStatus(ready.Status), true
+	}
+
+	c, ok := ready.Components[service]
+	if !ok {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+
+	return servingStatus(c.Status), true
+}
+
+func servingStatus(health string) healthpb.HealthCheckResponse_ServingStatus {
+	if health == v1alpha1.HealthOK {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// NewHealthHandler serves the standard gRPC health service, registered
+// with healthpb.Health_ServiceDesc, from the checks of s.
+func NewHealthHandler(s *store.Store) healthpb.HealthServer {
+	return &healthHandler{
+		store: s,
+	}
+}
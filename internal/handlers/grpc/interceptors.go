@@ -0,0 +1,173 @@
+package grpc
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/w-h-a/backend/internal/handlers"
+	"github.com/w-h-a/backend/internal/logs"
+	"github.com/w-h-a/backend/internal/metrics"
+	"github.com/w-h-a/backend/internal/ratelimit"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RequestIDMetadata carries the id of a call, as the X-Request-Id header
+// does over HTTP.
+const RequestIDMetadata = "x-request-id"
+
+const maxRequestIDLength = 128
+
+// RequestIDUnaryInterceptor gives every call an id, the one the client
+// sent if any, sends it back in the header and logs it with everything
+// logged while serving the call.
+func RequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// RequestIDStreamInterceptor is RequestIDUnaryInterceptor for streams.
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDMetadata); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		id = rand.Text()
+	}
+
+	if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadata, id)); err != nil {
+		slog.WarnContext(ctx, "failed to send request id", "error", err)
+	}
+
+	ctx = context.WithValue(ctx, handlers.RequestIDKey{}, id)
+
+	return logs.NewScope(ctx, slog.String("request.id", id))
+}
+
+// RecoverUnaryInterceptor answers a call whose handler panics with an
+// Internal error and logs the panic, instead of crashing the server.
+func RecoverUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ctx, info.FullMethod, p)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// RecoverStreamInterceptor is RecoverUnaryInterceptor for streams.
+func RecoverStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(ss.Context(), info.FullMethod, p)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, method string, p any) error {
+	slog.ErrorContext(ctx, "panic serving call", "method", method, "panic", p, "stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "internal error")
+}
+
+// contextStream is a stream with a context of its own.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// RateLimitUnaryInterceptor turns away calls that come more often than
+// limit allows, with a bucket for every address and method. It answers
+// ResourceExhausted with RetryInfo saying how long to wait. The health
+// service is left alone, as it is probed on a schedule.
+func RateLimitUnaryInterceptor(limiter *ratelimit.Limiter, limit ratelimit.Limit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := allow(ctx, limiter, limit, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor is RateLimitUnaryInterceptor for streams,
+// which it limits as they start.
+func RateLimitStreamInterceptor(limiter *ratelimit.Limiter, limit ratelimit.Limit) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allow(ss.Context(), limiter, limit, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func allow(ctx context.Context, limiter *ratelimit.Limiter, limit ratelimit.Limit, method string) error {
+	if strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/") {
+		return nil
+	}
+
+	ok, wait := limiter.Allow("ip:"+peerAddr(ctx)+" "+method, limit)
+	if ok {
+		return nil
+	}
+
+	metrics.RateLimited("grpc", "rate")
+
+	return exhausted("rate limit exceeded", wait)
+}
+
+// peerAddr is the host the call came from, without its port.
+func peerAddr(ctx context.Context) string {
+	addr := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+	}
+
+	return addr
+}
+
+// exhausted answers ResourceExhausted with RetryInfo saying to wait.
+func exhausted(msg string, wait time.Duration) error {
+	st := status.New(codes.ResourceExhausted, msg)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = detailed
+	}
+
+	return st.Err()
+}
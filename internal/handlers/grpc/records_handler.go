@@ -0,0 +1,241 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/api/v1alpha1/recordspb"
+	"github.com/w-h-a/backend/internal/clients/reader"
+	"github.com/w-h-a/backend/internal/logs"
+	"github.com/w-h-a/backend/internal/metrics"
+	"github.com/w-h-a/backend/internal/services/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// AuthorizationMetadata carries basic credentials, as the Authorization
+// header does over HTTP.
+const AuthorizationMetadata = "authorization"
+
+type recordsHandler struct {
+	recordspb.UnimplementedRecordsServer
+	store   *store.Store
+	options RecordsOptions
+}
+
+func (h *recordsHandler) ListRecords(ctx context.Context, req *recordspb.ListRecordsRequest) (*recordspb.ListRecordsResponse, error) {
+	user, err := h.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.store.Authorize(ctx, req.GetResource(), "", "read", user); err != nil {
+		return nil, statusError(ctx, err, "failed to read resources")
+	}
+
+	schema, err := h.store.Schema(ctx, req.GetResource())
+	if err != nil {
+		return nil, statusError(ctx, err, "failed to read resources")
+	}
+
+	projection, err := ProjectionFromMask(req.GetReadMask(), schema)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := h.store.List(ctx, req.GetResource(), req.GetSort(), reader.WithProjection(projection))
+	if err != nil {
+		return nil, statusError(ctx, err, "failed to list resources")
+	}
+
+	rsp := &recordspb.ListRecordsResponse{Records: make([]*structpb.Struct, 0, len(resources))}
+
+	for _, res := range resources {
+		rec, err := toStruct(res)
+		if err != nil {
+			return nil, statusError(ctx, err, "failed to list resources")
+		}
+		rsp.Records = append(rsp.Records, rec)
+	}
+
+	return rsp, nil
+}
+
+func (h *recordsHandler) GetRecord(ctx context.Context, req *recordspb.GetRecordRequest) (*recordspb.GetRecordResponse, error) {
+	user, err := h.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.store.Authorize(ctx, req.GetResource(), req.GetId(), "read", user); err != nil {
+		return nil, statusError(ctx, err, "failed to read resource")
+	}
+
+	schema, err := h.store.Schema(ctx, req.GetResource())
+	if err != nil {
+		return nil, statusError(ctx, err, "failed to read resource")
+	}
+
+	projection, err := ProjectionFromMask(req.GetReadMask(), schema)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := h.store.ReadOne(ctx, req.GetResource(), req.GetId(), reader.WithReadOneProjection(projection))
+	if err != nil {
+		return nil, statusError(ctx, err, "failed to read resource")
+	}
+
+	rec, err := toStruct(res)
+	if err != nil {
+		return nil, statusError(ctx, err, "failed to read resource")
+	}
+
+	return &recordspb.GetRecordResponse{Record: rec}, nil
+}
+
+// authenticate is the user whose basic credentials came with the call, or
+// nil for none. Failed logins count towards a lockout, as over HTTP.
+func (h *recordsHandler) authenticate(ctx context.Context) (v1alpha1.Resource, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	auth := md.Get(AuthorizationMetadata)
+	if len(auth) == 0 {
+		return nil, nil
+	}
+
+	username, password, ok := parseBasicAuth(auth[0])
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "expected basic credentials")
+	}
+
+	// the password is not even checked while locked out, so that guesses
+	// tell nothing
+	keys := []string{"user:" + username, "ip:" + peerAddr(ctx)}
+
+	if wait := h.locked(keys); wait > 0 {
+		slog.WarnContext(ctx, "Authentication refused: locked out after failed logins", "user.id", username, "retry.after", wait)
+		metrics.RateLimited("grpc", "lockout")
+		return nil, exhausted("too many failed logins", wait)
+	}
+
+	user, err := h.store.Authenticate(ctx, username, password)
+	if err != nil {
+		if errors.Is(err, store.ErrAuthn) {
+			h.failed(ctx, keys)
+		}
+		return nil, statusError(ctx, err, "authentication failed")
+	}
+
+	logs.Add(ctx, slog.String("user.id", username))
+
+	if h.options.Lockout != nil {
+		h.options.Lockout.Reset(keys[0])
+	}
+
+	return user, nil
+}
+
+// locked is how much longer the longest lockout of keys lasts.
+func (h *recordsHandler) locked(keys []string) time.Duration {
+	wait := time.Duration(0)
+
+	if h.options.Lockout == nil {
+		return wait
+	}
+
+	for _, key := range keys {
+		wait = max(wait, h.options.Lockout.Locked(key))
+	}
+
+	return wait
+}
+
+func (h *recordsHandler) failed(ctx context.Context, keys []string) {
+	if h.options.Lockout == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if d := h.options.Lockout.Fail(key); d > 0 {
+			slog.WarnContext(ctx, "Authentication failed too often: locking out", "lockout.key", key, "lockout.duration", d)
+		}
+	}
+}
+
+// parseBasicAuth reads the username and password of "Basic <base64>".
+func parseBasicAuth(auth string) (string, string, bool) {
+	const prefix = "Basic "
+
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+
+	bs, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+
+	return strings.Cut(string(bs), ":")
+}
+
+// toStruct is res as it is answered over HTTP, in JSON.
+func toStruct(res v1alpha1.Resource) (*structpb.Struct, error) {
+	bs, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(bs, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// storeCodes are the codes of the errors of the store, as storeProblems
+// are their HTTP statuses.
+var storeCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{store.ErrNotFound, codes.NotFound},
+	{store.ErrAuthn, codes.Unauthenticated},
+	{store.ErrAuthz, codes.PermissionDenied},
+	{store.ErrInvalid, codes.InvalidArgument},
+	{store.ErrRef, codes.InvalidArgument},
+	{store.ErrConflict, codes.FailedPrecondition},
+	{store.ErrTooLarge, codes.ResourceExhausted},
+	{store.ErrAborted, codes.Aborted},
+}
+
+// statusError answers err with the code of the store error it is, or
+// Internal, which it logs.
+func statusError(ctx context.Context, err error, what string) error {
+	for _, sc := range storeCodes {
+		if errors.Is(err, sc.err) {
+			return status.Errorf(sc.code, "%s: %v", what, err)
+		}
+	}
+
+	slog.ErrorContext(ctx, what, "error", err)
+
+	return status.Errorf(codes.Internal, "%s: internal error", what)
+}
+
+func NewRecordsHandler(s *store.Store, opts ...RecordsOption) recordspb.RecordsServer {
+	return &recordsHandler{
+		store:   s,
+		options: NewRecordsOptions(opts...),
+	}
+}
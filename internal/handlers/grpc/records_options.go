@@ -0,0 +1,28 @@
+package grpc
+
+import "github.com/w-h-a/backend/internal/ratelimit"
+
+// RecordsOptions say how the records service guards against guessing.
+type RecordsOptions struct {
+	// Lockout locks out usernames, and the addresses they are tried from,
+	// after failed logins. Logins are never locked out when it is nil.
+	Lockout *ratelimit.Lockout
+}
+
+type RecordsOption func(*RecordsOptions)
+
+func WithLockout(lockout *ratelimit.Lockout) RecordsOption {
+	return func(o *RecordsOptions) {
+		o.Lockout = lockout
+	}
+}
+
+func NewRecordsOptions(opts ...RecordsOption) RecordsOptions {
+	options := RecordsOptions{}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/handlers"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+// schemasResource is the resource permissions name to grant access to the
+// admin API.
+const schemasResource = "_schemas"
+
+type adminHandler struct {
+	store *store.Store
+}
+
+func (h *adminHandler) ListSchemas(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, schemasResource, "", "read", user); err != nil {
+		writeError(w, r, err, "Failed to read schemas")
+		return
+	}
+
+	wrtJSON(w, http.StatusOK, h.store.Schemas(ctx))
+}
+
+func (h *adminHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, schemasResource, "", "read", user); err != nil {
+		writeError(w, r, err, "Failed to read schema")
+		return
+	}
+
+	schema, err := h.store.Schema(ctx, resourceName)
+	if err != nil {
+		writeError(w, r, err, "Failed to read schema")
+		return
+	}
+
+	wrtJSON(w, http.StatusOK, schema)
+}
+
+func (h *adminHandler) CreateResource(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, schemasResource, "", "create", user); err != nil {
+		writeError(w, r, err, "Failed to create resource")
+		return
+	}
+
+	var input struct {
+		Resource string                 `json:"resource"`
+		Fields   []v1alpha1.FieldSchema `json:"fields"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeBodyError(w, r, err)
+		return
+	}
+
+	schema, err := h.store.CreateResource(ctx, input.Resource, input.Fields)
+	if err != nil {
+		writeError(w, r, err, "Failed to create resource")
+		return
+	}
+
+	wrtJSON(w, http.StatusCreated, schema)
+}
+
+func (h *adminHandler) AddField(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, schemasResource, "", "create", user); err != nil {
+		writeError(w, r, err, "Failed to add field")
+		return
+	}
+
+	var input v1alpha1.FieldSchema
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeBodyError(w, r, err)
+		return
+	}
+
+	schema, err := h.store.AddField(ctx, resourceName, input)
+	if err != nil {
+		writeError(w, r, err, "Failed to add field")
+		return
+	}
+
+	wrtJSON(w, http.StatusCreated, schema)
+}
+
+func (h *adminHandler) AlterField(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+	field := vars["field"]
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, schemasResource, "", "update", user); err != nil {
+		writeError(w, r, err, "Failed to alter field")
+		return
+	}
+
+	var input v1alpha1.FieldSchema
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeBodyError(w, r, err)
+		return
+	}
+
+	schema, err := h.store.AlterField(ctx, resourceName, field, input)
+	if err != nil {
+		writeError(w, r, err, "Failed to alter field")
+		return
+	}
+
+	wrtJSON(w, http.StatusOK, schema)
+}
+
+func (h *adminHandler) RemoveField(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+	field := vars["field"]
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, schemasResource, "", "delete", user); err != nil {
+		writeError(w, r, err, "Failed to remove field")
+		return
+	}
+
+	if _, err := h.store.RemoveField(ctx, resourceName, field); err != nil {
+		writeError(w, r, err, "Failed to remove field")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func NewAdminHandler(store *store.Store) *adminHandler {
+	return &adminHandler{
+		store: store,
+	}
+}
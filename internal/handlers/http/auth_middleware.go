@@ -2,11 +2,15 @@ package http
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/w-h-a/backend/api/v1alpha1"
 	"github.com/w-h-a/backend/internal/handlers"
+	"github.com/w-h-a/backend/internal/logs"
+	"github.com/w-h-a/backend/internal/metrics"
 	httpserver "github.com/w-h-a/backend/internal/servers/http"
 	"github.com/w-h-a/backend/internal/services/store"
 )
@@ -14,6 +18,7 @@ import (
 type authMiddleware struct {
 	handler http.Handler
 	store   *store.Store
+	options AuthOptions
 }
 
 func (m *authMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -24,17 +29,33 @@ func (m *authMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	username, password, ok := r.BasicAuth()
 	if ok {
+		// the password is not even checked while locked out, so that
+		// guesses tell nothing
+		keys := []string{"user:" + username, "ip:" + clientIP(r)}
+
+		if wait := m.locked(keys); wait > 0 {
+			slog.WarnContext(ctx, "Authentication refused: locked out after failed logins", "user.id", username, "retry.after", wait)
+			metrics.RateLimited("http", "lockout")
+			tooManyRequests(w, r, wait, v1alpha1.CodeLockedOut, "Too many failed logins")
+			return
+		}
+
 		user, err := m.store.Authenticate(ctx, username, password)
 		if err == nil {
 			authenticatedUser = user
+			logs.Add(ctx, slog.String("user.id", username))
+			m.succeeded(keys[0])
 		} else {
 			authErr = err
+			if errors.Is(err, store.ErrAuthn) {
+				m.failed(ctx, keys)
+			}
 		}
 	}
 
 	if authErr != nil {
 		w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
-		http.Error(w, fmt.Sprintf("Unauthorized: %v", authErr), http.StatusUnauthorized)
+		writeError(w, r, authErr, "Authentication failed")
 		return
 	}
 
@@ -44,11 +65,51 @@ func (m *authMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.handler.ServeHTTP(w, rWithUser)
 }
 
-func NewAuthMiddleware(store *store.Store) httpserver.Middleware {
+// locked is how much longer the longest lockout of keys lasts.
+func (m *authMiddleware) locked(keys []string) time.Duration {
+	wait := time.Duration(0)
+
+	if m.options.Lockout == nil {
+		return wait
+	}
+
+	for _, key := range keys {
+		wait = max(wait, m.options.Lockout.Locked(key))
+	}
+
+	return wait
+}
+
+func (m *authMiddleware) failed(ctx context.Context, keys []string) {
+	if m.options.Lockout == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if d := m.options.Lockout.Fail(key); d > 0 {
+			slog.WarnContext(ctx, "Authentication failed too often: locking out", "lockout.key", key, "lockout.duration", d)
+		}
+	}
+}
+
+// succeeded forgets the failures of the username, but not of the address,
+// so that a login of their own does not let one keep guessing others.
+func (m *authMiddleware) succeeded(key string) {
+	if m.options.Lockout == nil {
+		return
+	}
+
+	m.options.Lockout.Reset(key)
+}
+
+func NewAuthMiddleware(store *store.Store, opts ...AuthOption) httpserver.Middleware {
+	options := NewAuthOptions(opts...)
+
 	return func(handler http.Handler) http.Handler {
 		return &authMiddleware{
 			handler: handler,
 			store:   store,
+			options: options,
 		}
 	}
 }
@@ -0,0 +1,28 @@
+package http
+
+import "github.com/w-h-a/backend/internal/ratelimit"
+
+// AuthOptions say how the auth middleware guards against guessing.
+type AuthOptions struct {
+	// Lockout locks out usernames, and the addresses they are tried from,
+	// after failed logins. Logins are never locked out when it is nil.
+	Lockout *ratelimit.Lockout
+}
+
+type AuthOption func(*AuthOptions)
+
+func WithLockout(lockout *ratelimit.Lockout) AuthOption {
+	return func(o *AuthOptions) {
+		o.Lockout = lockout
+	}
+}
+
+func NewAuthOptions(opts ...AuthOption) AuthOptions {
+	options := AuthOptions{}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	httpserver "github.com/w-h-a/backend/internal/servers/http"
+)
+
+type corsMiddleware struct {
+	handler http.Handler
+	options CORSOptions
+}
+
+func (m *corsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// what is allowed depends on who asks, so caches must tell callers apart
+	w.Header().Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	preflight := r.Method == http.MethodOptions && len(r.Header.Get("Access-Control-Request-Method")) > 0
+
+	if !m.allowed(origin) {
+		if preflight {
+			// without the headers, the browser refuses the request
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		m.handler.ServeHTTP(w, r)
+		return
+	}
+
+	h := w.Header()
+
+	if m.options.Credentials || !slices.Contains(m.options.Origins, "*") {
+		h.Set("Access-Control-Allow-Origin", origin)
+	} else {
+		h.Set("Access-Control-Allow-Origin", "*")
+	}
+
+	if m.options.Credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if preflight {
+		h.Set("Access-Control-Allow-Methods", strings.Join(m.options.Methods, ", "))
+		h.Set("Access-Control-Allow-Headers", strings.Join(m.options.Headers, ", "))
+		if m.options.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(m.options.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if len(m.options.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(m.options.ExposedHeaders, ", "))
+	}
+
+	m.handler.ServeHTTP(w, r)
+}
+
+func (m *corsMiddleware) allowed(origin string) bool {
+	for _, o := range m.options.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewCORSMiddleware lets browsers call the API from the origins of the
+// options, answering their preflight requests itself. It does nothing when
+// there are no origins. It goes in front of the auth middleware, since
+// preflight requests carry no credentials.
+func NewCORSMiddleware(opts ...CORSOption) httpserver.Middleware {
+	options := NewCORSOptions(opts...)
+
+	return func(handler http.Handler) http.Handler {
+		if len(options.Origins) == 0 {
+			return handler
+		}
+
+		return &corsMiddleware{
+			handler: handler,
+			options: options,
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+	"time"
+)
+
+// CORSOptions say which cross-origin requests browsers may make.
+type CORSOptions struct {
+	// Origins may call the API, "*" standing for any. No origin may when
+	// there are none.
+	Origins []string
+	Methods []string
+	// Headers are the request headers callers may send.
+	Headers []string
+	// ExposedHeaders are the response headers callers may read.
+	ExposedHeaders []string
+	// Credentials lets callers send cookies and basic auth along.
+	Credentials bool
+	// MaxAge is how long browsers may cache the answer to a preflight.
+	MaxAge time.Duration
+}
+
+type CORSOption func(*CORSOptions)
+
+func WithCORSOrigins(origins ...string) CORSOption {
+	return func(o *CORSOptions) {
+		o.Origins = origins
+	}
+}
+
+func WithCORSMethods(methods ...string) CORSOption {
+	return func(o *CORSOptions) {
+		o.Methods = methods
+	}
+}
+
+func WithCORSHeaders(headers ...string) CORSOption {
+	return func(o *CORSOptions) {
+		o.Headers = headers
+	}
+}
+
+func WithCORSExposedHeaders(headers ...string) CORSOption {
+	return func(o *CORSOptions) {
+		o.ExposedHeaders = headers
+	}
+}
+
+func WithCORSCredentials(credentials bool) CORSOption {
+	return func(o *CORSOptions) {
+		o.Credentials = credentials
+	}
+}
+
+func WithCORSMaxAge(maxAge time.Duration) CORSOption {
+	return func(o *CORSOptions) {
+		o.MaxAge = maxAge
+	}
+}
+
+func NewCORSOptions(opts ...CORSOption) CORSOptions {
+	options := CORSOptions{
+		Methods:        []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		Headers:        []string{"Authorization", "Content-Type", RequestIDHeader, "Traceparent", "Tracestate"},
+		ExposedHeaders: []string{RequestIDHeader, "Retry-After"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
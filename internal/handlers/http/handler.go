@@ -2,19 +2,21 @@ package http
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/reader"
 	"github.com/w-h-a/backend/internal/handlers"
 	"github.com/w-h-a/backend/internal/services/store"
 )
 
 type handler struct {
-	schemas map[string][]v1alpha1.FieldSchema
-	store   *store.Store
+	store *store.Store
 }
 
 func (h *handler) ListRecords(w http.ResponseWriter, r *http.Request) {
@@ -22,31 +24,42 @@ func (h *handler) ListRecords(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	resourceName := vars["resource"]
-	sortBy := r.URL.Query().Get("sort_by")
+	expand := splitParam(r.URL.Query().Get("expand"))
 
 	user, _ := handlers.GetUserFromCtx(ctx)
 	if err := h.store.Authorize(ctx, resourceName, "", "read", user); err != nil {
-		if errors.Is(err, store.ErrAuthn) {
-			http.Error(w, fmt.Sprintf("Unauthenticated: %v", err), http.StatusUnauthorized)
-			return
-		} else if errors.Is(err, store.ErrAuthz) {
-			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusForbidden)
-			return
-		} else if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
-			return
-		}
-		http.Error(w, fmt.Sprintf("Failed to read resources: %v", err), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to read resources")
 		return
 	}
 
-	resources, err := h.store.List(ctx, resourceName, sortBy)
+	projection, err := v1alpha1.ParseProjection(r.URL.Query().Get("fields"))
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
-			return
-		}
-		http.Error(w, fmt.Sprintf("Failed to list resources: %v", err), http.StatusInternalServerError)
+		badRequest(w, r, fmt.Sprintf("Invalid fields: %v", err))
+		return
+	}
+
+	sort, err := parseSort(r.URL.Query())
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("Invalid sort: %v", err))
+		return
+	}
+
+	opts := []reader.ListOption{reader.WithSort(sort), reader.WithProjection(projection)}
+
+	resourceSchema, _ := h.store.Schema(ctx, resourceName)
+
+	geo, err := parseGeoQuery(resourceSchema, r.URL.Query())
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("Invalid geo query: %v", err))
+		return
+	}
+	if geo != nil {
+		opts = append(opts, reader.WithGeo(*geo))
+	}
+
+	resources, err := h.store.List(ctx, resourceName, "", opts...)
+	if err != nil {
+		writeError(w, r, err, "Failed to list resources")
 		return
 	}
 
@@ -54,6 +67,13 @@ func (h *handler) ListRecords(w http.ResponseWriter, r *http.Request) {
 		resources = []v1alpha1.Resource{}
 	}
 
+	if len(expand) > 0 {
+		if err := h.store.Expand(ctx, resourceName, resources, expand, user); err != nil {
+			writeError(w, r, err, "Failed to expand resources")
+			return
+		}
+	}
+
 	wrtJSON(w, http.StatusOK, resources)
 }
 
@@ -63,31 +83,31 @@ func (h *handler) GetRecord(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	resourceName := vars["resource"]
 	recordId := vars["id"]
+	expand := splitParam(r.URL.Query().Get("expand"))
 
 	user, _ := handlers.GetUserFromCtx(ctx)
 	if err := h.store.Authorize(ctx, resourceName, recordId, "read", user); err != nil {
-		if errors.Is(err, store.ErrAuthn) {
-			http.Error(w, fmt.Sprintf("Unauthenticated: %v", err), http.StatusUnauthorized)
-			return
-		} else if errors.Is(err, store.ErrAuthz) {
-			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusForbidden)
-			return
-		} else if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
-			return
-		}
-		http.Error(w, fmt.Sprintf("Failed to read resource: %v", err), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to read resource")
+		return
+	}
+
+	projection, err := v1alpha1.ParseProjection(r.URL.Query().Get("fields"))
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("Invalid fields: %v", err))
 		return
 	}
 
-	resource, err := h.store.ReadOne(ctx, resourceName, recordId)
+	resource, err := h.store.ReadOne(ctx, resourceName, recordId, reader.WithReadOneProjection(projection))
 	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
+		writeError(w, r, err, "Failed to read resource")
+		return
+	}
+
+	if len(expand) > 0 {
+		if err := h.store.Expand(ctx, resourceName, []v1alpha1.Resource{resource}, expand, user); err != nil {
+			writeError(w, r, err, "Failed to expand resource")
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to read resource: %v", err), http.StatusInternalServerError)
-		return
 	}
 
 	wrtJSON(w, http.StatusOK, resource)
@@ -101,23 +121,13 @@ func (h *handler) CreateRecord(w http.ResponseWriter, r *http.Request) {
 
 	user, _ := handlers.GetUserFromCtx(ctx)
 	if err := h.store.Authorize(ctx, resourceName, "", "create", user); err != nil {
-		if errors.Is(err, store.ErrAuthn) {
-			http.Error(w, fmt.Sprintf("Unauthenticated: %v", err), http.StatusUnauthorized)
-			return
-		} else if errors.Is(err, store.ErrAuthz) {
-			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusForbidden)
-			return
-		} else if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
-			return
-		}
-		http.Error(w, fmt.Sprintf("Failed to create resource: %v", err), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to create resource")
 		return
 	}
 
 	var rawInput v1alpha1.Resource
 	if err := json.NewDecoder(r.Body).Decode(&rawInput); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON payload: %v", err), http.StatusBadRequest)
+		writeBodyError(w, r, err)
 		return
 	}
 
@@ -125,31 +135,50 @@ func (h *handler) CreateRecord(w http.ResponseWriter, r *http.Request) {
 		rawInput = v1alpha1.Resource{}
 	}
 
-	resourceSchema, ok := h.schemas[resourceName]
-	if !ok {
-		http.Error(w, fmt.Sprintf("No schema found for resource %s", resourceName), http.StatusBadRequest)
+	newId, err := h.store.Create(ctx, resourceName, rawInput)
+	if err != nil {
+		writeError(w, r, err, "Failed to create resource")
 		return
 	}
 
-	newRes, err := v1alpha1.ParseResource(resourceSchema, rawInput)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+	wrtJSON(w, http.StatusCreated, map[string]string{"_id": newId})
+}
+
+func (h *handler) UpdateRecord(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+	recordId := vars["id"]
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, resourceName, recordId, "update", user); err != nil {
+		writeError(w, r, err, "Failed to update resource")
 		return
 	}
 
-	delete(newRes, "_id")
-	delete(newRes, "_v")
+	var rawInput v1alpha1.Resource
+	if err := json.NewDecoder(r.Body).Decode(&rawInput); err != nil {
+		writeBodyError(w, r, err)
+		return
+	}
 
-	newId, err := h.store.Create(ctx, resourceName, newRes)
+	if rawInput == nil {
+		rawInput = v1alpha1.Resource{}
+	}
+
+	rawInput["_id"] = recordId
+
+	updatedRes, err := h.store.Update(ctx, resourceName, rawInput)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create resource: %v", err), http.StatusInternalServerError)
+		writeError(w, r, err, "Failed to update resource")
 		return
 	}
 
-	wrtJSON(w, http.StatusCreated, map[string]string{"_id": newId})
+	wrtJSON(w, 200, updatedRes)
 }
 
-func (h *handler) UpdateRecord(w http.ResponseWriter, r *http.Request) {
+func (h *handler) DeleteRecord(w http.ResponseWriter, r *http.Request) {
 	ctx := reqToCtx(r)
 
 	vars := mux.Vars(r)
@@ -157,96 +186,264 @@ func (h *handler) UpdateRecord(w http.ResponseWriter, r *http.Request) {
 	recordId := vars["id"]
 
 	user, _ := handlers.GetUserFromCtx(ctx)
-	if err := h.store.Authorize(ctx, resourceName, recordId, "update", user); err != nil {
-		if errors.Is(err, store.ErrAuthn) {
-			http.Error(w, fmt.Sprintf("Unauthenticated: %v", err), http.StatusUnauthorized)
-			return
-		} else if errors.Is(err, store.ErrAuthz) {
-			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusForbidden)
-			return
-		} else if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
+	if err := h.store.Authorize(ctx, resourceName, recordId, "delete", user); err != nil {
+		writeError(w, r, err, "Failed to delete resource")
+		return
+	}
+
+	if err := h.store.Delete(ctx, resourceName, recordId, user); err != nil {
+		writeError(w, r, err, "Failed to delete resource")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bulkStatus is the status of an op of a bulk request that succeeded, the
+// same as the status of the request that does it alone.
+var bulkStatus = map[string]int{
+	v1alpha1.BulkCreate: http.StatusCreated,
+	v1alpha1.BulkUpdate: http.StatusOK,
+	v1alpha1.BulkDelete: http.StatusNoContent,
+}
+
+// BulkRecords applies an array of create, update and delete ops, each
+// authorized on its own, and answers the result of each in the same place:
+// 200 when every op succeeded and 207 otherwise. With ?atomic=true an op
+// that fails fails them all.
+func (h *handler) BulkRecords(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+
+	atomic := false
+	if v := r.URL.Query().Get("atomic"); len(v) > 0 {
+		var err error
+		if atomic, err = strconv.ParseBool(v); err != nil {
+			badRequest(w, r, fmt.Sprintf("Invalid atomic: %q is not true or false", v))
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to update resource: %v", err), http.StatusInternalServerError)
+	}
+
+	var ops []v1alpha1.BulkOp
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		writeBodyError(w, r, err)
 		return
 	}
 
-	var rawInput v1alpha1.Resource
-	if err := json.NewDecoder(r.Body).Decode(&rawInput); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON payload: %v", err), http.StatusBadRequest)
+	user, _ := handlers.GetUserFromCtx(ctx)
+
+	results, err := h.store.Bulk(ctx, resourceName, ops, atomic, user)
+	if err != nil {
+		writeError(w, r, err, "Failed to write resources")
 		return
 	}
 
-	if rawInput == nil {
-		rawInput = v1alpha1.Resource{}
+	status := http.StatusOK
+	rsp := make([]v1alpha1.BulkResult, 0, len(results))
+
+	for i, res := range results {
+		result := v1alpha1.BulkResult{
+			Op:     ops[i].Op,
+			Status: bulkStatus[ops[i].Op],
+			Id:     res.Id,
+			Record: res.Record,
+		}
+
+		if res.Err != nil {
+			what := "Invalid op"
+			if _, ok := bulkStatus[ops[i].Op]; ok {
+				what = fmt.Sprintf("Failed to %s resource", ops[i].Op)
+			}
+			p := problemFor(res.Err, what)
+			p.Instance = fmt.Sprintf("%s#%d", r.URL.Path, i)
+			result.Status = p.Status
+			result.Error = &p
+			status = http.StatusMultiStatus
+		}
+
+		rsp = append(rsp, result)
+	}
+
+	wrtJSON(w, status, rsp)
+}
+
+func (h *handler) AggregateRecords(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+
+	agg, err := parseAggregate(r.URL.Query())
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("Invalid aggregate: %v", err))
+		return
 	}
 
-	resourceSchema, ok := h.schemas[resourceName]
-	if !ok {
-		http.Error(w, fmt.Sprintf("No schema found for resource %s", resourceName), http.StatusBadRequest)
+	opts := []reader.ListOption{}
+
+	resourceSchema, _ := h.store.Schema(ctx, resourceName)
+
+	geo, err := parseGeoQuery(resourceSchema, r.URL.Query())
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("Invalid geo query: %v", err))
 		return
 	}
+	if geo != nil {
+		opts = append(opts, reader.WithGeo(*geo))
+	}
+
+	user, _ := handlers.GetUserFromCtx(ctx)
 
-	updatedRes, err := v1alpha1.ParseResource(resourceSchema, rawInput)
+	rows, err := h.store.Aggregate(ctx, resourceName, agg, user, opts...)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+		writeError(w, r, err, "Failed to aggregate resources")
 		return
 	}
 
-	delete(updatedRes, "_v")
-	updatedRes["_id"] = recordId
+	wrtJSON(w, http.StatusOK, rows)
+}
 
-	if err := h.store.Update(ctx, resourceName, updatedRes); err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
-			return
+func (h *handler) NearestRecords(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, resourceName, "", "read", user); err != nil {
+		writeError(w, r, err, "Failed to read resources")
+		return
+	}
+
+	var input struct {
+		Field  string    `json:"field"`
+		Vector []float64 `json:"vector"`
+		K      int       `json:"k"`
+		Metric string    `json:"metric"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeBodyError(w, r, err)
+		return
+	}
+
+	if len(input.Field) == 0 {
+		resourceSchema, _ := h.store.Schema(ctx, resourceName)
+
+		for _, fs := range resourceSchema {
+			if v1alpha1.BaseType(fs.Type) == "vector" {
+				input.Field = fs.Field
+				break
+			}
 		}
-		http.Error(w, fmt.Sprintf("Failed to update resource: %v", err), http.StatusInternalServerError)
+	}
+
+	if input.K == 0 {
+		input.K = 10
+	}
+
+	if len(input.Metric) == 0 {
+		input.Metric = v1alpha1.MetricCosine
+	}
+
+	neighbors, err := h.store.Nearest(ctx, resourceName, reader.VectorQuery{
+		Field:  input.Field,
+		Vector: input.Vector,
+		K:      input.K,
+		Metric: input.Metric,
+	})
+	if err != nil {
+		writeError(w, r, err, "Failed to search resources")
 		return
 	}
 
-	wrtJSON(w, 200, updatedRes)
+	wrtJSON(w, http.StatusOK, neighbors)
 }
 
-func (h *handler) DeleteRecord(w http.ResponseWriter, r *http.Request) {
+func (h *handler) UploadFile(w http.ResponseWriter, r *http.Request) {
 	ctx := reqToCtx(r)
 
 	vars := mux.Vars(r)
 	resourceName := vars["resource"]
 	recordId := vars["id"]
+	field := vars["field"]
 
 	user, _ := handlers.GetUserFromCtx(ctx)
-	if err := h.store.Authorize(ctx, resourceName, recordId, "delete", user); err != nil {
-		if errors.Is(err, store.ErrAuthn) {
-			http.Error(w, fmt.Sprintf("Unauthenticated: %v", err), http.StatusUnauthorized)
+	if err := h.store.Authorize(ctx, resourceName, recordId, "update", user); err != nil {
+		writeError(w, r, err, "Failed to upload file")
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		badRequest(w, r, fmt.Sprintf("Invalid multipart payload: %v", err))
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			badRequest(w, r, "Invalid multipart payload: missing \"file\" part")
 			return
-		} else if errors.Is(err, store.ErrAuthz) {
-			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusForbidden)
+		}
+		if err != nil {
+			badRequest(w, r, fmt.Sprintf("Invalid multipart payload: %v", err))
 			return
-		} else if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		f, err := h.store.PutFile(ctx, resourceName, recordId, field, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			writeError(w, r, err, "Failed to upload file")
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to delete resource: %v", err), http.StatusInternalServerError)
+
+		wrtJSON(w, http.StatusOK, f)
 		return
 	}
+}
 
-	if err := h.store.Delete(ctx, resourceName, recordId); err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			http.Error(w, fmt.Sprintf("Resource: %v", err), http.StatusNotFound)
-			return
-		}
-		http.Error(w, fmt.Sprintf("Failed to delete resource: %v", err), http.StatusInternalServerError)
+func (h *handler) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["resource"]
+	recordId := vars["id"]
+	field := vars["field"]
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+	if err := h.store.Authorize(ctx, resourceName, recordId, "read", user); err != nil {
+		writeError(w, r, err, "Failed to download file")
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	f, rc, err := h.store.GetFile(ctx, resourceName, recordId, field)
+	if err != nil {
+		writeError(w, r, err, "Failed to download file")
+		return
+	}
+	defer rc.Close()
+
+	// the type was sniffed from what a user sent, so browsers must not
+	// sniff one of their own
+	w.Header().Set("Content-Type", f.MimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Length", strconv.FormatInt(f.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+	w.Header().Set("ETag", `"`+f.Hash+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	io.Copy(w, rc)
 }
 
-func NewHandler(schemas map[string][]v1alpha1.FieldSchema, store *store.Store) *handler {
+func NewHandler(store *store.Store) *handler {
 	return &handler{
-		schemas: schemas,
-		store:   store,
+		store: store,
 	}
 }
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+type healthHandler struct {
+	store *store.Store
+}
+
+// Live answers liveness probes. It fails once the store is stopped, when
+// the process is of no more use and should be restarted.
+func (h *healthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	wrtHealth(w, h.store.CheckLiveness(ctx))
+}
+
+// Ready answers readiness probes. It fails while a read/writer does not
+// work, so that requests go elsewhere until it does.
+func (h *healthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	wrtHealth(w, h.store.CheckHealth(ctx))
+}
+
+func wrtHealth(w http.ResponseWriter, health v1alpha1.Health) {
+	status := http.StatusOK
+	if health.Status != v1alpha1.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	wrtJSON(w, status, health)
+}
+
+func NewHealthHandler(store *store.Store) *healthHandler {
+	return &healthHandler{
+		store: store,
+	}
+}
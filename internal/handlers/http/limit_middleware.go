@@ -0,0 +1,71 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+// filesRoute streams files in and out, so neither the body limit nor the
+// default timeout applies to it.
+const filesRoute = "/api/{resource}/{id}/files/{field}"
+
+// limitRequest bounds the body of a request and how long its route may take
+// to answer, as options say for the route it matched.
+func limitRequest(options RouterOptions) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tmpl := ""
+			if route := mux.CurrentRoute(r); route != nil {
+				tmpl, _ = route.GetPathTemplate()
+			}
+
+			if options.MaxBodyBytes > 0 && tmpl != filesRoute {
+				r.Body = http.MaxBytesReader(w, r.Body, options.MaxBodyBytes)
+			}
+
+			timeout := options.Timeout
+			if tmpl == filesRoute {
+				timeout = 0
+			}
+			if d, ok := options.RouteTimeouts[tmpl]; ok {
+				timeout = d
+			}
+
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			http.TimeoutHandler(next, timeout, timeoutBody(r)).ServeHTTP(&timeoutWriter{ResponseWriter: w}, r)
+		})
+	}
+}
+
+// timeoutBody is the problem http.TimeoutHandler answers with once r timed
+// out.
+func timeoutBody(r *http.Request) string {
+	p := v1alpha1.NewProblem(http.StatusServiceUnavailable, v1alpha1.CodeTimeout, "Request timed out")
+	p.Instance = r.URL.Path
+
+	bs, _ := json.Marshal(p)
+
+	return string(bs)
+}
+
+// timeoutWriter marks the body of a timeout as a problem, as
+// http.TimeoutHandler sets no content type. Responses of the handler, which
+// always have one, pass through as they are.
+type timeoutWriter struct {
+	http.ResponseWriter
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code == http.StatusServiceUnavailable && len(w.Header().Get("Content-Type")) == 0 {
+		w.Header().Set("Content-Type", v1alpha1.ProblemContentType)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/w-h-a/backend/internal/handlers"
+	"github.com/w-h-a/backend/internal/logs"
+	httpserver "github.com/w-h-a/backend/internal/servers/http"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type logMiddleware struct {
+	handler http.Handler
+}
+
+func (m *logMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	attrs := []slog.Attr{}
+
+	if id, ok := handlers.GetRequestIDFromCtx(r.Context()); ok {
+		attrs = append(attrs, slog.String("request.id", id))
+	}
+
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace.id", sc.TraceID().String()), slog.String("span.id", sc.SpanID().String()))
+	}
+
+	ctx := logs.NewScope(r.Context(), attrs...)
+
+	rec := &statusRecorder{ResponseWriter: w}
+
+	m.handler.ServeHTTP(rec, r.WithContext(ctx))
+
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	level := slog.LevelInfo
+	if rec.status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+
+	slog.LogAttrs(ctx, level, "request",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", rec.status),
+		slog.Int64("bytes", rec.bytes),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("remote_addr", r.RemoteAddr),
+	)
+}
+
+// statusRecorder remembers what was written for the access log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the writer underneath.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// NewLogMiddleware logs a line for every request, with the id the request
+// id middleware gave it and, once authenticated, its user. It goes in front
+// of the auth middleware so that rejected requests are logged too.
+func NewLogMiddleware() httpserver.Middleware {
+	return func(handler http.Handler) http.Handler {
+		return &logMiddleware{
+			handler: handler,
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/w-h-a/backend/internal/handlers"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+type metaHandler struct {
+	store *store.Store
+}
+
+func (h *metaHandler) ListResources(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+
+	resources, err := h.store.Resources(ctx, user)
+	if err != nil {
+		writeError(w, r, err, "Failed to describe resources")
+		return
+	}
+
+	wrtJSON(w, http.StatusOK, resources)
+}
+
+func (h *metaHandler) GetResource(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	vars := mux.Vars(r)
+	resourceName := vars["name"]
+
+	user, _ := handlers.GetUserFromCtx(ctx)
+
+	resource, err := h.store.Resource(ctx, resourceName, user)
+	if err != nil {
+		writeError(w, r, err, "Failed to describe resource")
+		return
+	}
+
+	wrtJSON(w, http.StatusOK, resource)
+}
+
+func NewMetaHandler(store *store.Store) *metaHandler {
+	return &metaHandler{
+		store: store,
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/w-h-a/backend/internal/metrics"
+	httpserver "github.com/w-h-a/backend/internal/servers/http"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+type routeLabelsKey struct{}
+
+// routeLabels is filled in by the router once it matched a route. Requests
+// that match none keep the zero value.
+type routeLabels struct {
+	route    string
+	resource string
+}
+
+type metricsMiddleware struct {
+	handler http.Handler
+}
+
+func (m *metricsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	labels := &routeLabels{}
+
+	rec := &statusRecorder{ResponseWriter: w}
+
+	m.handler.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeLabelsKey{}, labels)))
+
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	metrics.ObserveHTTPRequest(r.Method, labels.route, labels.resource, rec.status, time.Since(start))
+}
+
+// NewMetricsMiddleware counts requests and how long they took, by the route
+// they matched. It goes in front of the auth middleware so that rejected
+// requests are counted too.
+func NewMetricsMiddleware() httpserver.Middleware {
+	return func(handler http.Handler) http.Handler {
+		return &metricsMiddleware{
+			handler: handler,
+		}
+	}
+}
+
+// labelRoute tells the metrics middleware the route a request matched and
+// the resource it is about, when s has it.
+func labelRoute(s *store.Store) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			labels, ok := r.Context().Value(routeLabelsKey{}).(*routeLabels)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if route := mux.CurrentRoute(r); route != nil {
+				if tmpl, err := route.GetPathTemplate(); err == nil {
+					labels.route = tmpl
+				}
+			}
+
+			labels.resource = knownResource(s, r)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+// swaggerUI is a page that loads Swagger UI and points it at /openapi.json.
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>backend API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+type openAPIHandler struct {
+	store *store.Store
+}
+
+// Document builds the OpenAPI document on every request, so it follows
+// every change made to schemas and permissions.
+func (h *openAPIHandler) Document(w http.ResponseWriter, r *http.Request) {
+	ctx := reqToCtx(r)
+
+	permissions, err := h.store.List(ctx, "_permissions", "")
+	if err != nil {
+		writeError(w, r, err, "Failed to read permissions")
+		return
+	}
+
+	wrtJSON(w, http.StatusOK, v1alpha1.OpenAPI(h.store.Schemas(ctx), permissions))
+}
+
+func (h *openAPIHandler) SwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(swaggerUI))
+}
+
+func NewOpenAPIHandler(store *store.Store) *openAPIHandler {
+	return &openAPIHandler{
+		store: store,
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+// storeProblems are the status and code of each error of the store, the
+// same whichever handler gets it.
+var storeProblems = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{store.ErrAuthn, http.StatusUnauthorized, v1alpha1.CodeUnauthenticated},
+	{store.ErrAuthz, http.StatusForbidden, v1alpha1.CodeForbidden},
+	{store.ErrNotFound, http.StatusNotFound, v1alpha1.CodeNotFound},
+	{store.ErrRef, http.StatusBadRequest, v1alpha1.CodeInvalidReference},
+	{store.ErrConflict, http.StatusConflict, v1alpha1.CodeConflict},
+	{store.ErrInvalid, http.StatusBadRequest, v1alpha1.CodeInvalidRequest},
+	{store.ErrTooLarge, http.StatusRequestEntityTooLarge, v1alpha1.CodeTooLarge},
+	{store.ErrAborted, http.StatusFailedDependency, v1alpha1.CodeAborted},
+}
+
+// problemFor is the problem answered for err: a validation failure, with
+// every field that failed, a body over the limit, an error of the store, or
+// else an internal error. what says what failed.
+func problemFor(err error, what string) v1alpha1.Problem {
+	detail := fmt.Sprintf("%s: %v", what, err)
+
+	var invalid *v1alpha1.ValidationError
+	if errors.As(err, &invalid) {
+		p := v1alpha1.NewProblem(http.StatusBadRequest, v1alpha1.CodeValidationFailed, detail)
+		p.Errors = invalid.Errors
+		return p
+	}
+
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return v1alpha1.NewProblem(http.StatusRequestEntityTooLarge, v1alpha1.CodeTooLarge, detail)
+	}
+
+	for _, sp := range storeProblems {
+		if errors.Is(err, sp.err) {
+			return v1alpha1.NewProblem(sp.status, sp.code, detail)
+		}
+	}
+
+	return v1alpha1.NewProblem(http.StatusInternalServerError, v1alpha1.CodeInternal, detail)
+}
+
+// writeError answers err as a problem.
+func writeError(w http.ResponseWriter, r *http.Request, err error, what string) {
+	writeProblem(w, r, problemFor(err, what))
+}
+
+// writeBodyError answers a body that failed to decode: 413 when it is over
+// the limit, 400 otherwise.
+func writeBodyError(w http.ResponseWriter, r *http.Request, err error) {
+	p := problemFor(err, "Invalid JSON payload")
+	if p.Status == http.StatusInternalServerError {
+		p = v1alpha1.NewProblem(http.StatusBadRequest, v1alpha1.CodeInvalidJSON, p.Detail)
+	}
+
+	writeProblem(w, r, p)
+}
+
+// badRequest answers a request that is wrong in a way no schema describes,
+// like a malformed query.
+func badRequest(w http.ResponseWriter, r *http.Request, detail string) {
+	writeProblem(w, r, v1alpha1.NewProblem(http.StatusBadRequest, v1alpha1.CodeInvalidRequest, detail))
+}
+
+// tooManyRequests answers 429, telling the client to wait before it tries
+// again.
+func tooManyRequests(w http.ResponseWriter, r *http.Request, wait time.Duration, code string, detail string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(max(wait, time.Second).Seconds()))))
+	writeProblem(w, r, v1alpha1.NewProblem(http.StatusTooManyRequests, code, detail))
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, p v1alpha1.Problem) {
+	if r != nil {
+		p.Instance = r.URL.Path
+	}
+
+	bs, _ := json.Marshal(p)
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", v1alpha1.ProblemContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(bs)
+}
+
+// problemHandler answers every request with the problem of status and code,
+// for requests no route takes.
+func problemHandler(status int, code string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, v1alpha1.NewProblem(status, code, fmt.Sprintf("%s %s", r.Method, r.URL.Path)))
+	})
+}
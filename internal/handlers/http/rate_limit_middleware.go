@@ -0,0 +1,99 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/handlers"
+	"github.com/w-h-a/backend/internal/metrics"
+	"github.com/w-h-a/backend/internal/ratelimit"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+// unlimitedRoutes are probed and scraped on a schedule, by clients that
+// should not be turned away for it.
+var unlimitedRoutes = []string{"/healthz", "/readyz", "/metrics"}
+
+// limitRate turns away requests that come more often than options allow,
+// with a bucket for every user, or address when there is no user, route and
+// resource. It runs behind the auth middleware, which knows the user.
+func limitRate(s *store.Store, options RouterOptions) mux.MiddlewareFunc {
+	if options.RateLimit.IsZero() && len(options.RateLimits) == 0 {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	limiter := ratelimit.NewLimiter()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tmpl := ""
+			if route := mux.CurrentRoute(r); route != nil {
+				tmpl, _ = route.GetPathTemplate()
+			}
+
+			if slices.Contains(unlimitedRoutes, tmpl) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			resource := knownResource(s, r)
+
+			limit := rateLimitFor(options, resource, routeAction(r.Method, tmpl))
+
+			who := "ip:" + clientIP(r)
+			if u, ok := handlers.GetUserFromCtx(r.Context()); ok {
+				if id, ok := u["_id"].(string); ok && len(id) > 0 {
+					who = "user:" + id
+				}
+			}
+
+			if ok, wait := limiter.Allow(fmt.Sprintf("%s %s %s %s", who, r.Method, tmpl, resource), limit); !ok {
+				metrics.RateLimited("http", "rate")
+				tooManyRequests(w, r, wait, v1alpha1.CodeRateLimited, "Rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitFor is the most specific limit options set for action on
+// resource: RESOURCE:ACTION, then RESOURCE:*, then *:ACTION, then *:*, then
+// the default.
+func rateLimitFor(options RouterOptions, resource string, action string) ratelimit.Limit {
+	for _, key := range []string{resource + ":" + action, resource + ":*", "*:" + action, "*:*"} {
+		if limit, ok := options.RateLimits[key]; ok {
+			return limit
+		}
+	}
+
+	return options.RateLimit
+}
+
+// routeAction is the action a request takes, as its method says, except
+// for searches sent by POST, and bulk writes, which take several.
+func routeAction(method string, tmpl string) string {
+	switch tmpl {
+	case "/api/{resource}/_knn":
+		return "read"
+	case "/api/{resource}/_bulk":
+		return "bulk"
+	}
+
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut, http.MethodPatch:
+		return "update"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "read"
+	}
+}
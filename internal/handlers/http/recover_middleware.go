@@ -0,0 +1,50 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	httpserver "github.com/w-h-a/backend/internal/servers/http"
+)
+
+type recoverMiddleware struct {
+	handler http.Handler
+}
+
+func (m *recoverMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w}
+
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+
+		// the way to abort a response on purpose
+		if p == http.ErrAbortHandler {
+			panic(p)
+		}
+
+		slog.ErrorContext(r.Context(), "panic serving request", "panic", p, "stack", string(debug.Stack()))
+
+		// too late to change the status of a response already started
+		if rec.status == 0 {
+			writeProblem(rec, r, v1alpha1.NewProblem(http.StatusInternalServerError, v1alpha1.CodeInternal, "The request could not be served"))
+		}
+	}()
+
+	m.handler.ServeHTTP(rec, r)
+}
+
+// NewRecoverMiddleware answers a request whose handler panics with a 500
+// and logs the panic, instead of dropping the connection. It goes behind
+// the log middleware so that the log line of the panic names the request.
+func NewRecoverMiddleware() httpserver.Middleware {
+	return func(handler http.Handler) http.Handler {
+		return &recoverMiddleware{
+			handler: handler,
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+
+	"github.com/w-h-a/backend/internal/handlers"
+	httpserver "github.com/w-h-a/backend/internal/servers/http"
+)
+
+// RequestIDHeader carries the id of a request. An id sent by the client is
+// kept, so that its logs and ours can be matched up, as long as it looks
+// like one.
+const RequestIDHeader = "X-Request-Id"
+
+const maxRequestIDLength = 128
+
+// validRequestID reports whether a client's id is short and made only of
+// letters, digits, dots, underscores and dashes, so that it is safe to
+// echo in headers and write into logs.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range []byte(id) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+type requestIDMiddleware struct {
+	handler http.Handler
+}
+
+func (m *requestIDMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = rand.Text()
+	}
+
+	w.Header().Set(RequestIDHeader, id)
+
+	// handlers that read the headers see the id that is used
+	r.Header.Set(RequestIDHeader, id)
+
+	ctx := context.WithValue(r.Context(), handlers.RequestIDKey{}, id)
+
+	m.handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// NewRequestIDMiddleware gives every request an id, the valid one the
+// client sent if any, and sends it back in the response. It goes in front
+// of the log middleware, which logs it.
+func NewRequestIDMiddleware() httpserver.Middleware {
+	return func(handler http.Handler) http.Handler {
+		return &requestIDMiddleware{
+			handler: handler,
+		}
+	}
+}
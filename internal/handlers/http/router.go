@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/metrics"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+// NewRouter routes the HTTP API to handlers backed by s. Authentication is
+// left to the middleware in front of it.
+func NewRouter(s *store.Store, opts ...RouterOption) *mux.Router {
+	options := NewRouterOptions(opts...)
+
+	router := mux.NewRouter()
+	router.NotFoundHandler = problemHandler(http.StatusNotFound, v1alpha1.CodeNotFound)
+	router.MethodNotAllowedHandler = problemHandler(http.StatusMethodNotAllowed, v1alpha1.CodeMethodNotAllowed)
+	router.Use(nameSpan, labelRoute(s), limitRate(s, options), limitRequest(options))
+
+	handler := NewHandler(s)
+	adminHandler := NewAdminHandler(s)
+	metaHandler := NewMetaHandler(s)
+	openAPIHandler := NewOpenAPIHandler(s)
+	healthHandler := NewHealthHandler(s)
+
+	router.HandleFunc("/healthz", healthHandler.Live).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", healthHandler.Ready).Methods(http.MethodGet)
+	router.Handle("/metrics", metrics.Handler(metrics.NewResourceCollector(s))).Methods(http.MethodGet)
+	router.HandleFunc("/openapi.json", openAPIHandler.Document).Methods(http.MethodGet)
+	router.HandleFunc("/docs", openAPIHandler.SwaggerUI).Methods(http.MethodGet)
+
+	// ahead of /api/{resource}/{id}, which would match too
+	router.HandleFunc("/api/_meta/resources", metaHandler.ListResources).Methods(http.MethodGet)
+	router.HandleFunc("/api/_meta/resources/{name}", metaHandler.GetResource).Methods(http.MethodGet)
+	router.HandleFunc("/api/{resource}/_aggregate", handler.AggregateRecords).Methods(http.MethodGet)
+
+	router.HandleFunc("/api/{resource}", handler.ListRecords).Methods(http.MethodGet)
+	router.HandleFunc("/api/{resource}/{id}", handler.GetRecord).Methods(http.MethodGet)
+	router.HandleFunc("/api/{resource}", handler.CreateRecord).Methods(http.MethodPost)
+	router.HandleFunc("/api/{resource}/_knn", handler.NearestRecords).Methods(http.MethodPost)
+	router.HandleFunc("/api/{resource}/_bulk", handler.BulkRecords).Methods(http.MethodPost)
+	router.HandleFunc("/api/{resource}/{id}", handler.UpdateRecord).Methods(http.MethodPut)
+	router.HandleFunc("/api/{resource}/{id}", handler.DeleteRecord).Methods(http.MethodDelete)
+	router.HandleFunc(filesRoute, handler.UploadFile).Methods(http.MethodPost)
+	router.HandleFunc(filesRoute, handler.DownloadFile).Methods(http.MethodGet)
+
+	router.HandleFunc("/admin/schemas", adminHandler.ListSchemas).Methods(http.MethodGet)
+	router.HandleFunc("/admin/schemas", adminHandler.CreateResource).Methods(http.MethodPost)
+	router.HandleFunc("/admin/schemas/{resource}", adminHandler.GetSchema).Methods(http.MethodGet)
+	router.HandleFunc("/admin/schemas/{resource}/fields", adminHandler.AddField).Methods(http.MethodPost)
+	router.HandleFunc("/admin/schemas/{resource}/fields/{field}", adminHandler.AlterField).Methods(http.MethodPut)
+	router.HandleFunc("/admin/schemas/{resource}/fields/{field}", adminHandler.RemoveField).Methods(http.MethodDelete)
+
+	return router
+}
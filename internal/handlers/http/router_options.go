@@ -0,0 +1,71 @@
+package http
+
+import (
+	"time"
+
+	"github.com/w-h-a/backend/internal/ratelimit"
+)
+
+// RouterOptions bound the requests the router serves.
+type RouterOptions struct {
+	// MaxBodyBytes bounds request bodies, except file uploads, which the
+	// field they go to bounds. Zero means no limit.
+	MaxBodyBytes int64
+	// Timeout bounds the handling of a request, except for file uploads
+	// and downloads, which take as long as the file. Zero means no limit.
+	Timeout time.Duration
+	// RouteTimeouts set the timeout of routes by their template, like
+	// /api/{resource}/_knn, for every method, files included.
+	RouteTimeouts map[string]time.Duration
+	// RateLimit bounds how often a user, or an address that does not
+	// authenticate, may call a route about a resource. RateLimits set it by
+	// RESOURCE:ACTION, like notes:create, either part * for any. The zero
+	// Limit means no limit.
+	RateLimit  ratelimit.Limit
+	RateLimits map[string]ratelimit.Limit
+}
+
+type RouterOption func(*RouterOptions)
+
+func WithMaxBodyBytes(n int64) RouterOption {
+	return func(o *RouterOptions) {
+		o.MaxBodyBytes = n
+	}
+}
+
+func WithTimeout(d time.Duration) RouterOption {
+	return func(o *RouterOptions) {
+		o.Timeout = d
+	}
+}
+
+func WithRouteTimeouts(timeouts map[string]time.Duration) RouterOption {
+	return func(o *RouterOptions) {
+		o.RouteTimeouts = timeouts
+	}
+}
+
+func WithRateLimit(limit ratelimit.Limit) RouterOption {
+	return func(o *RouterOptions) {
+		o.RateLimit = limit
+	}
+}
+
+func WithRateLimits(limits map[string]ratelimit.Limit) RouterOption {
+	return func(o *RouterOptions) {
+		o.RateLimits = limits
+	}
+}
+
+func NewRouterOptions(opts ...RouterOption) RouterOptions {
+	options := RouterOptions{
+		RouteTimeouts: map[string]time.Duration{},
+		RateLimits:    map[string]ratelimit.Limit{},
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	httpserver "github.com/w-h-a/backend/internal/servers/http"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTraceMiddleware starts a span for every request, continuing the trace
+// of the caller when it sends a W3C traceparent header. It goes in front of
+// the other middleware so that what they log belongs to the trace.
+func NewTraceMiddleware() httpserver.Middleware {
+	return func(handler http.Handler) http.Handler {
+		return otelhttp.NewHandler(handler, "http.server",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method
+			}),
+		)
+	}
+}
+
+// nameSpan names the span of a request after the route it matched, once
+// the router knows it.
+func nameSpan(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				span := trace.SpanFromContext(r.Context())
+				span.SetName(r.Method + " " + tmpl)
+				span.SetAttributes(attribute.String("http.route", tmpl))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
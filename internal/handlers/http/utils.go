@@ -3,8 +3,19 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/reader"
+	"github.com/w-h-a/backend/internal/services/store"
 )
 
 func reqToCtx(r *http.Request) context.Context {
@@ -23,3 +34,145 @@ func wrtJSON(w http.ResponseWriter, statusCode int, data any) {
 	w.WriteHeader(statusCode)
 	w.Write(bs)
 }
+
+// knownResource is the resource a request is about, as the route names it,
+// or empty when s has no such resource, so that made up names cannot grow
+// what is kept by resource.
+func knownResource(s *store.Store, r *http.Request) string {
+	vars := mux.Vars(r)
+
+	resource, ok := vars["resource"]
+	if !ok {
+		resource = vars["name"]
+	}
+
+	if _, err := s.Schema(r.Context(), resource); err != nil {
+		return ""
+	}
+
+	return resource
+}
+
+// clientIP is the address a request came from, without its port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func splitParam(v string) []string {
+	parts := []string{}
+
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			parts = append(parts, p)
+		}
+	}
+
+	return parts
+}
+
+// parseSort reads ?sort=-a,b, with ?nulls=first|last and ?collation=, or
+// the older ?sort_by=a, which sorts by a single field, ascending.
+func parseSort(q url.Values) (v1alpha1.Sort, error) {
+	if q.Has("sort") && q.Has("sort_by") {
+		return v1alpha1.Sort{}, errors.New("expected sort or sort_by, not both")
+	}
+
+	keys, err := v1alpha1.ParseSort(q.Get("sort"))
+	if err != nil {
+		return v1alpha1.Sort{}, err
+	}
+
+	if sortBy := q.Get("sort_by"); len(sortBy) > 0 {
+		keys = []v1alpha1.SortKey{{Field: sortBy}}
+	}
+
+	nullsFirst, err := v1alpha1.ParseNulls(q.Get("nulls"))
+	if err != nil {
+		return v1alpha1.Sort{}, fmt.Errorf("nulls: %w", err)
+	}
+
+	return v1alpha1.Sort{Keys: keys, NullsFirst: nullsFirst, Collation: q.Get("collation")}, nil
+}
+
+// parseAggregate reads ?group_by=a,b&count&sum=c&avg=c&min=d&max=d, each
+// list of fields given once, comma separated, or repeated.
+func parseAggregate(q url.Values) (v1alpha1.Aggregate, error) {
+	fields := func(key string) []string {
+		fs := []string{}
+		for _, v := range q[key] {
+			fs = append(fs, splitParam(v)...)
+		}
+		return fs
+	}
+
+	count := false
+	if q.Has("count") {
+		if v := q.Get("count"); len(v) > 0 {
+			var err error
+			if count, err = strconv.ParseBool(v); err != nil {
+				return v1alpha1.Aggregate{}, fmt.Errorf("count: expected true or false, got %q", v)
+			}
+		} else {
+			count = true
+		}
+	}
+
+	return v1alpha1.Aggregate{
+		GroupBy: fields("group_by"),
+		Count:   count,
+		Sum:     fields("sum"),
+		Avg:     fields("avg"),
+		Min:     fields("min"),
+		Max:     fields("max"),
+	}, nil
+}
+
+// parseGeoQuery reads ?near=lat,lon&radius=km and ?bbox=minLon,minLat,maxLon,maxLat.
+// The geopoint field is named by ?geo_field= or defaults to the first one in
+// the schema.
+func parseGeoQuery(schemas []v1alpha1.FieldSchema, q url.Values) (*reader.GeoQuery, error) {
+	near, bbox := q.Get("near"), q.Get("bbox")
+	if len(near) == 0 && len(bbox) == 0 {
+		return nil, nil
+	}
+
+	field := q.Get("geo_field")
+
+	idx := slices.IndexFunc(schemas, func(fs v1alpha1.FieldSchema) bool {
+		return v1alpha1.BaseType(fs.Type) == "geopoint" && (len(field) == 0 || fs.Field == field)
+	})
+	if idx < 0 {
+		return nil, errors.New("resource has no such geopoint field")
+	}
+
+	geo := &reader.GeoQuery{
+		Field: schemas[idx].Field,
+	}
+
+	if len(near) > 0 {
+		center, err := v1alpha1.ParseGeoPoint(near)
+		if err != nil {
+			return nil, err
+		}
+		radius, err := strconv.ParseFloat(q.Get("radius"), 64)
+		if err != nil || radius <= 0 {
+			return nil, fmt.Errorf("radius must be a positive number of kilometers")
+		}
+		geo.Center = &center
+		geo.Radius = radius
+	}
+
+	if len(bbox) > 0 {
+		box, err := v1alpha1.ParseBBox(bbox)
+		if err != nil {
+			return nil, err
+		}
+		geo.Box = &box
+	}
+
+	return geo, nil
+}
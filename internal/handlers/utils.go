@@ -12,3 +12,10 @@ func GetUserFromCtx(ctx context.Context) (v1alpha1.Resource, bool) {
 	user, ok := ctx.Value(UserKey{}).(v1alpha1.Resource)
 	return user, ok
 }
+
+type RequestIDKey struct{}
+
+func GetRequestIDFromCtx(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(RequestIDKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,132 @@
+// Package logs sets up slog for the server. Records logged with a context
+// carry the attributes of its scope, so everything logged while serving a
+// request names the request and its user without passing a logger around:
+//
+//	ctx = logs.NewScope(ctx, slog.String("request.id", id))
+//	...
+//	slog.ErrorContext(ctx, "failed to write record", "error", err)
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New makes a logger that writes records of level or above to w, as JSON or
+// text.
+func New(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler
+
+	switch format {
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+
+	return slog.New(NewHandler(h)), nil
+}
+
+// NewHandler wraps h so that records logged with a context get the
+// attributes of its scope.
+func NewHandler(h slog.Handler) slog.Handler {
+	return &contextHandler{handler: h}
+}
+
+type contextHandler struct {
+	handler slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := Attrs(ctx)
+	if len(attrs) == 0 {
+		return h.handler.Handle(ctx, r)
+	}
+
+	// what the record says itself wins over the scope
+	keys := map[string]bool{}
+	r.Attrs(func(a slog.Attr) bool {
+		keys[a.Key] = true
+		return true
+	})
+
+	r = r.Clone()
+
+	for _, a := range attrs {
+		if !keys[a.Key] {
+			r.AddAttrs(a)
+		}
+	}
+
+	return h.handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{handler: h.handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{handler: h.handler.WithGroup(name)}
+}
+
+type scopeKey struct{}
+
+// scope is shared by everything that derives from the context it was
+// started with, so attributes added deep down, like the user once they are
+// authenticated, also show in what the caller logs at the end.
+type scope struct {
+	mtx   sync.RWMutex
+	attrs []slog.Attr
+}
+
+// NewScope starts a scope, usually for a request, with the attributes of the
+// enclosing scope and the given ones.
+func NewScope(ctx context.Context, attrs ...slog.Attr) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{
+		attrs: append(Attrs(ctx), attrs...),
+	})
+}
+
+// Add adds attributes to the scope of ctx. It does nothing when ctx has no
+// scope.
+func Add(ctx context.Context, attrs ...slog.Attr) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// Attrs returns the attributes of the scope of ctx.
+func Attrs(ctx context.Context) []slog.Attr {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return nil
+	}
+
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return slices.Clone(s.attrs)
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor counts unary calls and how long they took.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeGRPCCall(info.FullMethod, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor counts streaming calls and how long they took,
+// until the stream closed.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeGRPCCall(info.FullMethod, err, time.Since(start))
+		return err
+	}
+}
+
+func observeGRPCCall(fullMethod string, err error, d time.Duration) {
+	// fullMethod is /package.Service/Method
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	code := status.Code(err).String()
+
+	grpcRequests.WithLabelValues(service, method, code).Inc()
+	grpcRequestDuration.WithLabelValues(service, method, code).Observe(d.Seconds())
+}
@@ -0,0 +1,146 @@
+// Package metrics exports Prometheus metrics about the server: the requests
+// it serves over HTTP and gRPC, failed authentications and authorizations,
+// requests turned away by rate limits, how long store operations take and, read at every scrape, what each
+// resource holds.
+//
+// Labels only take values from a closed set, like route templates and known
+// resources, so that requests for made up paths cannot grow the number of
+// series without bound.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "backend"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests served, by method, route, resource and status code.",
+	}, []string{"method", "route", "resource", "code"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "How long HTTP requests took to serve, by method, route, resource and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "resource", "code"})
+
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "gRPC calls served, by service, method and status code.",
+	}, []string{"service", "method", "code"})
+
+	grpcRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "How long gRPC calls took to serve, by service, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method", "code"})
+
+	authnFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "authn_failures_total",
+		Help:      "Failed authentications, by reason.",
+	}, []string{"reason"})
+
+	authzFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "authz_failures_total",
+		Help:      "Refused authorizations, by resource, action and reason.",
+	}, []string{"resource", "action", "reason"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ratelimit",
+		Name:      "rejected_total",
+		Help:      "Requests turned away for coming too often, by protocol and reason.",
+	}, []string{"protocol", "reason"})
+
+	storeOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "store",
+		Name:      "operation_duration_seconds",
+		Help:      "How long store operations took, by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+)
+
+// Handler serves the metrics of the server, along with those of the Go
+// runtime, the process and the given collectors, in the Prometheus text
+// format. A collector that fails leaves its metrics out rather than failing
+// the scrape.
+func Handler(cs ...prometheus.Collector) http.Handler {
+	reg := prometheus.NewRegistry()
+
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		grpcRequests,
+		grpcRequestDuration,
+		authnFailures,
+		authzFailures,
+		rateLimited,
+		storeOperationDuration,
+	)
+
+	reg.MustRegister(cs...)
+
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// ObserveHTTPRequest counts a request and how long it took. route is the
+// template of the route it matched and resource the one it is about, or
+// empty when there is none.
+func ObserveHTTPRequest(method string, route string, resource string, code int, d time.Duration) {
+	labels := prometheus.Labels{
+		"method":   method,
+		"route":    route,
+		"resource": resource,
+		"code":     strconv.Itoa(code),
+	}
+
+	httpRequests.With(labels).Inc()
+	httpRequestDuration.With(labels).Observe(d.Seconds())
+}
+
+// AuthnFailed counts a failed authentication.
+func AuthnFailed(reason string) {
+	authnFailures.WithLabelValues(reason).Inc()
+}
+
+// AuthzFailed counts a refused authorization.
+func AuthzFailed(resource string, action string, reason string) {
+	authzFailures.WithLabelValues(resource, action, reason).Inc()
+}
+
+// RateLimited counts a request turned away, over "http" or "grpc", for
+// going over its "rate" or while its user was locked out after failed
+// logins, the "lockout" reason.
+func RateLimited(protocol string, reason string) {
+	rateLimited.WithLabelValues(protocol, reason).Inc()
+}
+
+// ObserveStoreOperation records how long a store operation took.
+func ObserveStoreOperation(operation string, d time.Duration) {
+	storeOperationDuration.WithLabelValues(operation).Observe(d.Seconds())
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+)
+
+// StatsSource tells what the read/writer of each resource holds. Stats
+// returns what it could read along with the errors for the rest.
+type StatsSource interface {
+	Stats(ctx context.Context) (map[string]readwriter.Stats, error)
+}
+
+var (
+	resourceRecordsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "resource", "records"),
+		"Live records of a resource.",
+		[]string{"resource"}, nil,
+	)
+	resourceRowsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "resource", "rows"),
+		"Rows stored for a resource, old versions and deleted records included.",
+		[]string{"resource"}, nil,
+	)
+	resourceFileBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "resource", "file_size_bytes"),
+		"Size of the file a resource is stored in.",
+		[]string{"resource"}, nil,
+	)
+	resourceDeadRowRatioDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "resource", "dead_row_ratio"),
+		"Share of the rows in the file of a resource that no longer hold a live record.",
+		[]string{"resource"}, nil,
+	)
+	resourceIndexEntriesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "resource", "index_entries"),
+		"Ids in the index of a resource.",
+		[]string{"resource"}, nil,
+	)
+	resourceStatsErrorDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "resource", "stats_error"),
+		"Failed to read the stats of the resources.",
+		nil, nil,
+	)
+)
+
+type resourceCollector struct {
+	source StatsSource
+}
+
+// NewResourceCollector collects the stats of every resource of source when
+// scraped. File size and dead row ratio are left out for resources that
+// are not stored in a file.
+func NewResourceCollector(source StatsSource) prometheus.Collector {
+	return &resourceCollector{source: source}
+}
+
+func (c *resourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- resourceRecordsDesc
+	ch <- resourceRowsDesc
+	ch <- resourceFileBytesDesc
+	ch <- resourceDeadRowRatioDesc
+	ch <- resourceIndexEntriesDesc
+	ch <- resourceStatsErrorDesc
+}
+
+func (c *resourceCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.source.Stats(context.Background())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(resourceStatsErrorDesc, err)
+	}
+
+	for resource, s := range stats {
+		ch <- prometheus.MustNewConstMetric(resourceRecordsDesc, prometheus.GaugeValue, float64(s.Records), resource)
+		ch <- prometheus.MustNewConstMetric(resourceRowsDesc, prometheus.GaugeValue, float64(s.Rows), resource)
+		ch <- prometheus.MustNewConstMetric(resourceIndexEntriesDesc, prometheus.GaugeValue, float64(s.IndexEntries), resource)
+
+		if s.Bytes < 0 {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(resourceFileBytesDesc, prometheus.GaugeValue, float64(s.Bytes), resource)
+		ch <- prometheus.MustNewConstMetric(resourceDeadRowRatioDesc, prometheus.GaugeValue, s.DeadRatio(), resource)
+	}
+}
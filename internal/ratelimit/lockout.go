@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// failures are those of a key since its last success.
+type failures struct {
+	count  int
+	until  time.Time
+	latest time.Time
+}
+
+// Lockout locks a key out once it failed Threshold times in a row, first
+// for the Lockout of its options, and twice as long for every failure after
+// that. It is safe for concurrent use.
+type Lockout struct {
+	options   Options
+	mtx       sync.Mutex
+	failures  map[string]*failures
+	lastSweep time.Time
+}
+
+// Locked reports how much longer key is locked out, or zero if it is not.
+func (l *Lockout) Locked(key string) time.Duration {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	f, ok := l.failures[key]
+	if !ok {
+		return 0
+	}
+
+	return max(0, f.until.Sub(l.options.Clock()))
+}
+
+// Fail counts a failure of key and reports how long key is now locked out
+// for, which is zero while it is under the threshold.
+func (l *Lockout) Fail(key string) time.Duration {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.options.Clock()
+
+	l.sweep(now)
+
+	f, ok := l.failures[key]
+	if !ok {
+		f = &failures{}
+		l.failures[key] = f
+	}
+
+	f.count++
+	f.latest = now
+
+	if f.count < l.options.Threshold {
+		return 0
+	}
+
+	d := l.options.Lockout
+	for i := l.options.Threshold; i < f.count && d < l.options.MaxLockout; i++ {
+		d *= 2
+	}
+	d = min(d, l.options.MaxLockout)
+
+	f.until = now.Add(d)
+
+	return d
+}
+
+// Reset forgets the failures of key, as it succeeded.
+func (l *Lockout) Reset(key string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.failures, key)
+}
+
+// sweep forgets keys that have not failed for as long as the longest
+// lockout, so that they start over.
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, f := range l.failures {
+		if now.Sub(f.latest) >= l.options.MaxLockout && !now.Before(f.until) {
+			delete(l.failures, key)
+		}
+	}
+}
+
+func NewLockout(opts ...Option) *Lockout {
+	options := NewOptions(opts...)
+
+	return &Lockout{
+		options:   options,
+		failures:  map[string]*failures{},
+		lastSweep: options.Clock(),
+	}
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import "time"
+
+type Option func(*Options)
+
+type Options struct {
+	// Threshold is how many failures in a row a Lockout lets through before
+	// it locks a key out.
+	Threshold int
+	// Lockout is how long a key is first locked out for. Every failure
+	// past the threshold doubles it, up to MaxLockout.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+	// Clock tells the time. It is time.Now unless tests say otherwise.
+	Clock func() time.Time
+}
+
+func WithThreshold(n int) Option {
+	return func(o *Options) {
+		o.Threshold = n
+	}
+}
+
+func WithLockout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Lockout = d
+	}
+}
+
+func WithMaxLockout(d time.Duration) Option {
+	return func(o *Options) {
+		o.MaxLockout = d
+	}
+}
+
+func WithClock(clock func() time.Time) Option {
+	return func(o *Options) {
+		o.Clock = clock
+	}
+}
+
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Threshold:  5,
+		Lockout:    30 * time.Second,
+		MaxLockout: time.Hour,
+		Clock:      time.Now,
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,162 @@
+// Package ratelimit throttles clients. A Limiter keeps a token bucket per
+// key, so that each user, address or route can be held to a Limit of its
+// own, and a Lockout shuts keys out for longer and longer as they keep
+// failing, like a password guessed over and over:
+//
+//	if ok, wait := limiter.Allow("ip:"+addr, ratelimit.Limit{Count: 10, Per: time.Second}); !ok {
+//		// try again after wait
+//	}
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often a Limiter forgets the buckets it no longer
+// needs.
+const sweepInterval = time.Minute
+
+// Limit lets Count requests through per Per, all at once or spread out. The
+// zero Limit lets everything through.
+type Limit struct {
+	Count int
+	Per   time.Duration
+}
+
+func (l Limit) IsZero() bool {
+	return l.Count <= 0 || l.Per <= 0
+}
+
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// String writes l the way ParseLimit reads it.
+func (l Limit) String() string {
+	if l.IsZero() {
+		return "0"
+	}
+
+	for _, unit := range []string{"h", "m", "s"} {
+		if l.Per == units[unit] {
+			return fmt.Sprintf("%d/%s", l.Count, unit)
+		}
+	}
+
+	return fmt.Sprintf("%d/%s", l.Count, l.Per)
+}
+
+// ParseLimit reads a limit like 10/s, 100/m or 1000/h, or 0 for none. The
+// unit may be any duration too, like 5/10s.
+func ParseLimit(v string) (Limit, error) {
+	if v == "0" {
+		return Limit{}, nil
+	}
+
+	count, per, ok := strings.Cut(v, "/")
+	if !ok {
+		return Limit{}, errors.New("expected COUNT/UNIT, like 100/m")
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return Limit{}, errors.New("expected a positive count, like 100/m")
+	}
+
+	per = strings.TrimSpace(per)
+
+	d, ok := units[per]
+	if !ok {
+		if d, err = time.ParseDuration(per); err != nil || d <= 0 {
+			return Limit{}, errors.New("expected a unit of s, m, h or a duration, like 100/m")
+		}
+	}
+
+	return Limit{Count: n, Per: d}, nil
+}
+
+// bucket holds the tokens left to a key as of updated. It is full again
+// once a whole period of its limit went by.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	per     time.Duration
+}
+
+// Limiter keeps a token bucket per key. It is safe for concurrent use.
+type Limiter struct {
+	options   Options
+	mtx       sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// Allow takes a token from the bucket of key, which holds limit.Count
+// tokens and gets one back every limit.Per/limit.Count. When the bucket is
+// empty, Allow reports how long until it has a token again.
+func (l *Limiter) Allow(key string, limit Limit) (bool, time.Duration) {
+	if limit.IsZero() {
+		return true, 0
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.options.Clock()
+
+	l.sweep(now)
+
+	capacity := float64(limit.Count)
+	interval := limit.Per / time.Duration(limit.Count)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.updated))/float64(interval))
+	b.updated = now
+	b.per = limit.Per
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) * float64(interval))
+}
+
+// sweep forgets buckets that are full again, as they are no different from
+// the new ones Allow would make.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= b.per {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func NewLimiter(opts ...Option) *Limiter {
+	options := NewOptions(opts...)
+
+	return &Limiter{
+		options:   options,
+		buckets:   map[string]*bucket{},
+		lastSweep: options.Clock(),
+	}
+}
@@ -32,3 +32,23 @@ func getStreamInterceptorsFromCtx(ctx context.Context) ([]grpc.StreamServerInter
 	streamies, ok := ctx.Value(streamInterceptorKey{}).([]grpc.StreamServerInterceptor)
 	return streamies, ok
 }
+
+type tlsKey struct{}
+
+type tlsFiles struct {
+	cert string
+	key  string
+}
+
+// WithTLS serves gRPC over TLS with the certificate and key in the given
+// files.
+func WithTLS(certFile string, keyFile string) servers.Option {
+	return func(o *servers.Options) {
+		o.Context = context.WithValue(o.Context, tlsKey{}, tlsFiles{cert: certFile, key: keyFile})
+	}
+}
+
+func getTLSFromCtx(ctx context.Context) (tlsFiles, bool) {
+	files, ok := ctx.Value(tlsKey{}).(tlsFiles)
+	return files, ok
+}
@@ -4,17 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"sync"
-	"time"
 
+	"github.com/w-h-a/backend/internal/metrics"
 	"github.com/w-h-a/backend/internal/servers"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 type grpcServer struct {
-	options   servers.Options
-	server    *grpc.Server
+	options servers.Options
+	server  *grpc.Server
+	// err is why the server cannot start, set when options fail to apply.
+	err       error
 	errCh     chan error
 	exit      chan struct{}
 	isRunning bool
@@ -57,12 +62,12 @@ func (s *grpcServer) Run(stop chan struct{}) error {
 
 	select {
 	case err := <-s.errCh:
-		stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
 		defer stopCancel()
 		_ = s.stop(stopCtx)
 		return err
 	case <-stop:
-		stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
 		defer stopCancel()
 		return s.stop(stopCtx)
 	}
@@ -76,6 +81,10 @@ func (s *grpcServer) Start() error {
 		return errors.New("server already started")
 	}
 
+	if s.err != nil {
+		return s.err
+	}
+
 	listener, err := net.Listen("tcp", s.options.Address)
 	if err != nil {
 		return err
@@ -95,11 +104,13 @@ func (s *grpcServer) Start() error {
 
 	s.isRunning = true
 
+	slog.Info("grpc server listening", "address", s.options.Address)
+
 	return nil
 }
 
 func (s *grpcServer) Stop() error {
-	stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
 	defer stopCancel()
 	return s.stop(stopCtx)
 }
@@ -147,12 +158,43 @@ func (s *grpcServer) stop(ctx context.Context) error {
 func NewServer(opts ...servers.Option) servers.Server {
 	options := servers.NewOptions(opts...)
 
-	// add interceptor 'middleware' and otel instrumentation here
-	srv := grpc.NewServer()
+	var serverOpts []grpc.ServerOption
+	var err error
+
+	if files, ok := getTLSFromCtx(options.Context); ok {
+		creds, credsErr := credentials.NewServerTLSFromFile(files.cert, files.key)
+		if credsErr != nil {
+			err = fmt.Errorf("failed to load TLS certificate: %w", credsErr)
+		} else {
+			serverOpts = append(serverOpts, grpc.Creds(creds))
+		}
+	}
+
+	// spans for every call, continuing the trace of the caller
+	serverOpts = append(serverOpts, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+
+	// counts for every call, ahead of the interceptors that may refuse it
+	unaries := []grpc.UnaryServerInterceptor{metrics.UnaryServerInterceptor()}
+	if more, ok := getUnaryInterceptorsFromCtx(options.Context); ok {
+		unaries = append(unaries, more...)
+	}
+
+	streamies := []grpc.StreamServerInterceptor{metrics.StreamServerInterceptor()}
+	if more, ok := getStreamInterceptorsFromCtx(options.Context); ok {
+		streamies = append(streamies, more...)
+	}
+
+	serverOpts = append(serverOpts,
+		grpc.ChainUnaryInterceptor(unaries...),
+		grpc.ChainStreamInterceptor(streamies...),
+	)
+
+	srv := grpc.NewServer(serverOpts...)
 
 	s := &grpcServer{
 		options: options,
 		server:  srv,
+		err:     err,
 		mtx:     sync.RWMutex{},
 	}
 
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"time"
 
 	"github.com/w-h-a/backend/internal/servers"
 )
@@ -18,3 +19,43 @@ func getMiddlewareFromCtx(ctx context.Context) ([]Middleware, bool) {
 	ms, ok := ctx.Value(middlewareKey{}).([]Middleware)
 	return ms, ok
 }
+
+type tlsKey struct{}
+
+type tlsFiles struct {
+	cert string
+	key  string
+}
+
+// WithTLS serves HTTPS with the certificate and key in the given files.
+func WithTLS(certFile string, keyFile string) servers.Option {
+	return func(o *servers.Options) {
+		o.Context = context.WithValue(o.Context, tlsKey{}, tlsFiles{cert: certFile, key: keyFile})
+	}
+}
+
+func getTLSFromCtx(ctx context.Context) (tlsFiles, bool) {
+	files, ok := ctx.Value(tlsKey{}).(tlsFiles)
+	return files, ok
+}
+
+type timeoutsKey struct{}
+
+type timeouts struct {
+	read  time.Duration
+	write time.Duration
+	idle  time.Duration
+}
+
+// WithTimeouts bounds how long reading a request, writing a response and
+// keeping an idle connection open may take. Zero means no limit.
+func WithTimeouts(read time.Duration, write time.Duration, idle time.Duration) servers.Option {
+	return func(o *servers.Options) {
+		o.Context = context.WithValue(o.Context, timeoutsKey{}, timeouts{read: read, write: write, idle: idle})
+	}
+}
+
+func getTimeoutsFromCtx(ctx context.Context) (timeouts, bool) {
+	t, ok := ctx.Value(timeoutsKey{}).(timeouts)
+	return t, ok
+}
@@ -2,12 +2,13 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"sync"
-	"time"
 
 	"github.com/w-h-a/backend/internal/servers"
 )
@@ -65,12 +66,12 @@ func (s *httpServer) Run(stop chan struct{}) error {
 
 	select {
 	case err := <-s.errCh:
-		stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
 		defer stopCancel()
 		_ = s.stop(stopCtx)
 		return err
 	case <-stop:
-		stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
 		defer stopCancel()
 		return s.stop(stopCtx)
 	}
@@ -88,6 +89,15 @@ func (s *httpServer) Start() error {
 		return errors.New("handler not set")
 	}
 
+	files, useTLS := getTLSFromCtx(s.options.Context)
+	if useTLS {
+		cert, err := tls.LoadX509KeyPair(files.cert, files.key)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		s.server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
 	listener, err := net.Listen("tcp", s.options.Address)
 	if err != nil {
 		return err
@@ -99,7 +109,13 @@ func (s *httpServer) Start() error {
 	s.errCh = make(chan error, 1)
 
 	go func() {
-		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if useTLS {
+			err = s.server.ServeTLS(listener, "", "")
+		} else {
+			err = s.server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			s.errCh <- fmt.Errorf("http server ListenAndServe error: %w", err)
 		}
 		close(s.exit)
@@ -107,11 +123,13 @@ func (s *httpServer) Start() error {
 
 	s.isRunning = true
 
+	slog.Info("http server listening", "address", s.options.Address)
+
 	return nil
 }
 
 func (s *httpServer) Stop() error {
-	stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
 	defer stopCancel()
 	return s.stop(stopCtx)
 }
@@ -155,9 +173,17 @@ func (s *httpServer) stop(ctx context.Context) error {
 func NewServer(opts ...servers.Option) servers.Server {
 	options := servers.NewOptions(opts...)
 
+	srv := &http.Server{}
+
+	if t, ok := getTimeoutsFromCtx(options.Context); ok {
+		srv.ReadTimeout = t.read
+		srv.WriteTimeout = t.write
+		srv.IdleTimeout = t.idle
+	}
+
 	s := &httpServer{
 		options: options,
-		server:  &http.Server{},
+		server:  srv,
 		mtx:     sync.RWMutex{},
 	}
 
@@ -1,12 +1,18 @@
 package servers
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Option func(*Options)
 
 type Options struct {
 	Address string
-	Context context.Context
+	// ShutdownTimeout is how long Run and Stop wait for requests in flight
+	// before the server is closed anyway.
+	ShutdownTimeout time.Duration
+	Context         context.Context
 }
 
 func WithAddress(addr string) Option {
@@ -15,10 +21,17 @@ func WithAddress(addr string) Option {
 	}
 }
 
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.ShutdownTimeout = d
+	}
+}
+
 func NewOptions(opts ...Option) Options {
 	options := Options{
-		Address: ":0",
-		Context: context.Background(),
+		Address:         ":0",
+		ShutdownTimeout: 10 * time.Second,
+		Context:         context.Background(),
 	}
 
 	for _, fn := range opts {
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/reader"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// aggGroup adds up the records of a group as they pass.
+type aggGroup struct {
+	values []any
+	count  int
+	sums   map[string]float64
+	avgs   map[string][2]float64 // sum and count
+	mins   map[string]any
+	maxs   map[string]any
+}
+
+// Aggregate computes agg over the records of resource that opts select and
+// u may read, in a single pass over its read/writer that holds one group
+// at a time per distinct value, not the records. Rows come ordered by the
+// values they group by, nulls last.
+func (s *Store) Aggregate(ctx context.Context, resource string, agg v1alpha1.Aggregate, u v1alpha1.Resource, opts ...reader.ListOption) (rows []v1alpha1.AggregateRow, err error) {
+	ctx, span := s.startSpan(ctx, "store.Aggregate", resource, attribute.StringSlice("group_by", agg.GroupBy))
+	defer func() {
+		span.SetAttributes(attribute.Int("group.count", len(rows)))
+		endSpan(span, err)
+	}()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	layout, ok := s.layouts[resource]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	rw, ok := s.rws[resource]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if err := agg.Validate(s.schemas[resource]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	perms, err := s.list(ctx, "_permissions", "")
+	if err != nil {
+		slog.ErrorContext(ctx, "Authorization failed: could not load permissions", "error", err)
+		return nil, ErrAuthz
+	}
+
+	keep, owners, err := s.rowFilter(perms, resource, "read", u)
+	s.countAuthz(resource, "read", err)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode only what is added up or checked
+	p := v1alpha1.Projection{Fields: append(append([]string{"_id"}, agg.Fields()...), owners...)}
+
+	groups := map[string]*aggGroup{}
+
+	err = readwriter.Scan(ctx, rw, func(rec v1alpha1.Record) error {
+		res, err := v1alpha1.ProjectResource(layout, rec, p)
+		if err != nil {
+			return err
+		}
+
+		if keep != nil && !keep(res) {
+			return nil
+		}
+
+		values := make([]any, 0, len(agg.GroupBy))
+		for _, field := range agg.GroupBy {
+			v := res[field]
+			if v == "" {
+				v = nil // empty text is null, as when sorting
+			}
+			values = append(values, v)
+		}
+
+		bs, _ := json.Marshal(values)
+
+		g, ok := groups[string(bs)]
+		if !ok {
+			if len(groups) >= maxGroups {
+				return fmt.Errorf("%w: more than %d groups", ErrTooLarge, maxGroups)
+			}
+			g = &aggGroup{
+				values: values,
+				sums:   map[string]float64{},
+				avgs:   map[string][2]float64{},
+				mins:   map[string]any{},
+				maxs:   map[string]any{},
+			}
+			groups[string(bs)] = g
+		}
+
+		g.add(agg, res)
+
+		return nil
+	}, opts...)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to aggregate records", "resource.name", resource, "error", err)
+		return nil, err
+	}
+
+	// without groups, nothing still counts
+	if len(agg.GroupBy) == 0 && len(groups) == 0 {
+		groups[""] = &aggGroup{}
+	}
+
+	sorted := slices.SortedFunc(func(yield func(*aggGroup) bool) {
+		for _, g := range groups {
+			if !yield(g) {
+				return
+			}
+		}
+	}, func(a, b *aggGroup) int {
+		return slices.CompareFunc(a.values, b.values, compareValues)
+	})
+
+	rows = make([]v1alpha1.AggregateRow, 0, len(sorted))
+	for _, g := range sorted {
+		rows = append(rows, g.row(agg))
+	}
+
+	return rows, nil
+}
+
+func (g *aggGroup) add(agg v1alpha1.Aggregate, res v1alpha1.Resource) {
+	g.count++
+
+	for _, field := range agg.Sum {
+		if n, ok := res[field].(float64); ok {
+			g.sums[field] += n
+		}
+	}
+
+	for _, field := range agg.Avg {
+		if n, ok := res[field].(float64); ok {
+			avg := g.avgs[field]
+			g.avgs[field] = [2]float64{avg[0] + n, avg[1] + 1}
+		}
+	}
+
+	for _, field := range agg.Min {
+		if v := res[field]; v != nil && v != "" {
+			if least, ok := g.mins[field]; !ok || compareValues(v, least) < 0 {
+				g.mins[field] = v
+			}
+		}
+	}
+
+	for _, field := range agg.Max {
+		if v := res[field]; v != nil && v != "" {
+			if greatest, ok := g.maxs[field]; !ok || compareValues(v, greatest) > 0 {
+				g.maxs[field] = v
+			}
+		}
+	}
+}
+
+func (g *aggGroup) row(agg v1alpha1.Aggregate) v1alpha1.AggregateRow {
+	row := v1alpha1.AggregateRow{}
+
+	if len(agg.GroupBy) > 0 {
+		row.Group = map[string]any{}
+		for i, field := range agg.GroupBy {
+			row.Group[field] = g.values[i]
+		}
+	}
+
+	if agg.Count {
+		count := g.count
+		row.Count = &count
+	}
+
+	if len(agg.Sum) > 0 {
+		row.Sum = map[string]float64{}
+		for _, field := range agg.Sum {
+			row.Sum[field] = g.sums[field]
+		}
+	}
+
+	if len(agg.Avg) > 0 {
+		row.Avg = map[string]any{}
+		for _, field := range agg.Avg {
+			row.Avg[field] = nil
+			if avg := g.avgs[field]; avg[1] > 0 {
+				row.Avg[field] = avg[0] / avg[1]
+			}
+		}
+	}
+
+	if len(agg.Min) > 0 {
+		row.Min = map[string]any{}
+		for _, field := range agg.Min {
+			row.Min[field] = g.mins[field]
+		}
+	}
+
+	if len(agg.Max) > 0 {
+		row.Max = map[string]any{}
+		for _, field := range agg.Max {
+			row.Max[field] = g.maxs[field]
+		}
+	}
+
+	return row
+}
+
+// compareValues orders the values of a field: numbers by size, text byte
+// by byte, and nulls after either.
+func compareValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	if x, ok := a.(float64); ok {
+		y, _ := b.(float64)
+		return cmp.Compare(x, y)
+	}
+
+	x, _ := a.(string)
+	y, _ := b.(string)
+
+	return cmp.Compare(x, y)
+}
+
+// rowFilter decides, by perms, which records of resource u may take action
+// on, as authorize would for each of them. The filter is nil when u may
+// for every record, or else keeps those whose owner fields, also returned,
+// name u. It fails as authorize does when no record could pass.
+func (s *Store) rowFilter(perms []v1alpha1.Resource, resource string, action string, u v1alpha1.Resource) (func(v1alpha1.Resource) bool, []string, error) {
+	username, roles := identify(u)
+
+	g, err := match(perms, resource, action, username, roles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if g.public || len(g.role) > 0 {
+		return nil, nil, nil
+	}
+
+	owners := g.owners
+
+	return func(res v1alpha1.Resource) bool {
+		return slices.ContainsFunc(owners, func(field string) bool { return names(res, field, username) })
+	}, owners, nil
+}
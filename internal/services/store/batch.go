@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/writer"
+)
+
+// batch is the writes of a request to one resource.
+type batch struct {
+	resource string
+	ops      []writer.Op
+}
+
+// writeAll writes batches in turn, each whole or not at all. When one
+// fails, those before it are taken back by writing back what they
+// overwrote, at the versions it had, and its index comes back with the
+// error. This is best effort, not a transaction: readers may see the
+// batches before they are taken back, and one that fails to be stays
+// written, its error joined to the one returned.
+func (s *Store) writeAll(ctx context.Context, batches []batch) (int, error) {
+	// read what the batches overwrite before any of them does
+	inverses := make([][]writer.Op, len(batches))
+
+	for i, b := range batches {
+		inverse, err := readwriter.Inverse(ctx, s.rws[b.resource], b.ops)
+		if err != nil {
+			return i, err
+		}
+		inverses[i] = inverse
+	}
+
+	for i, b := range batches {
+		err := readwriter.WriteBatch(ctx, s.rws[b.resource], b.ops)
+		if err == nil {
+			continue
+		}
+
+		slog.ErrorContext(ctx, "failed to write batch", "resource.name", b.resource, "op.count", len(b.ops), "error", err)
+
+		for j := i - 1; j >= 0; j-- {
+			if uerr := readwriter.WriteBatch(ctx, s.rws[batches[j].resource], readwriter.Undo(inverses[j]), writer.WithRestore()); uerr != nil {
+				slog.ErrorContext(ctx, "failed to take back batch", "resource.name", batches[j].resource, "op.count", len(batches[j].ops), "error", uerr)
+				err = errors.Join(err, uerr)
+			}
+		}
+
+		return i, err
+	}
+
+	return -1, nil
+}
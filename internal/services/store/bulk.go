@@ -0,0 +1,354 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/writer"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// BulkResult is what became of one op of a bulk write: the id of its
+// record, with the record an update left, or the error that failed it.
+type BulkResult struct {
+	Id     string
+	Record v1alpha1.Resource
+	Err    error
+}
+
+// bulk stages the writes of a bulk request. Each write keeps the record
+// its key held before, so that the writes of an op that fails can be
+// taken back.
+type bulk struct {
+	*view
+	writes []bulkWrite
+}
+
+type bulkWrite struct {
+	op        int
+	key       recordKey
+	write     writer.Op
+	prev      v1alpha1.Resource
+	wasStaged bool
+	// blobs are the hashes of the files a delete drops
+	blobs []string
+}
+
+// Bulk authorizes, validates and stages the ops in turn, each seeing the
+// records as the ops before it left them, then writes what was staged with
+// one batch per resource. An op that fails writes nothing. When atomic, an
+// op that fails fails them all: nothing is written and the others fail
+// with ErrAborted. What was staged is written by writeAll, which takes
+// back the batches it wrote when one fails, so the ops that wrote to that
+// one fail with its error and the others with ErrAborted. The error is for the request as a whole.
+func (s *Store) Bulk(ctx context.Context, resource string, ops []v1alpha1.BulkOp, atomic bool, u v1alpha1.Resource) (results []BulkResult, err error) {
+	ctx, span := s.startSpan(ctx, "store.Bulk", resource, attribute.Int("op.count", len(ops)), attribute.Bool("atomic", atomic))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	if _, ok := s.rws[resource]; !ok {
+		return nil, ErrNotFound
+	}
+
+	if len(ops) > maxBulk {
+		return nil, fmt.Errorf("%w: at most %d ops at once", ErrTooLarge, maxBulk)
+	}
+
+	// read once for every op
+	perms, err := s.list(ctx, "_permissions", "")
+	if err != nil {
+		slog.ErrorContext(ctx, "Authorization failed: could not load permissions", "error", err)
+		return nil, ErrAuthz
+	}
+
+	b := &bulk{view: s.view()}
+	results = make([]BulkResult, len(ops))
+	failed := false
+
+	for i, op := range ops {
+		mark := len(b.writes)
+
+		res, err := s.stageOp(ctx, b, i, perms, resource, op, u)
+		if err != nil {
+			b.rollback(mark)
+			res = BulkResult{Err: err}
+			failed = true
+		}
+
+		results[i] = res
+	}
+
+	if atomic && failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = BulkResult{Err: fmt.Errorf("%w: another op of the batch failed", ErrAborted)}
+			}
+		}
+		return results, nil
+	}
+
+	batches := b.batches()
+
+	if failedAt, err := s.writeAll(ctx, batches); err != nil {
+		// nothing was written, so no op that staged went through
+		for _, w := range b.writes {
+			if results[w.op].Err != nil {
+				continue
+			}
+			if w.key.resource == batches[failedAt].resource {
+				results[w.op] = BulkResult{Err: err}
+			}
+		}
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = BulkResult{Err: fmt.Errorf("%w: another op of the batch failed", ErrAborted)}
+			}
+		}
+		return results, nil
+	}
+
+	s.blobsMtx.Lock()
+	s.releaseBlobs(ctx, b.blobs(results))
+	s.blobsMtx.Unlock()
+
+	return results, nil
+}
+
+func (s *Store) stageOp(ctx context.Context, b *bulk, i int, perms []v1alpha1.Resource, resource string, op v1alpha1.BulkOp, u v1alpha1.Resource) (BulkResult, error) {
+	action := op.Op
+	if action != v1alpha1.BulkCreate && action != v1alpha1.BulkUpdate && action != v1alpha1.BulkDelete {
+		return BulkResult{}, fmt.Errorf("%w: unknown op %q, expected create, update or delete", ErrInvalid, op.Op)
+	}
+
+	id := ""
+	if action != v1alpha1.BulkCreate {
+		if len(op.Id) == 0 {
+			return BulkResult{}, fmt.Errorf("%w: %s needs the _id of a record", ErrInvalid, action)
+		}
+		id = op.Id
+	}
+
+	err := s.authorizeWith(ctx, perms, resource, id, action, u)
+	s.countAuthz(resource, action, err)
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	if action == v1alpha1.BulkDelete {
+		return BulkResult{Id: id}, b.stageDelete(ctx, i, perms, recordKey{resource, id}, u)
+	}
+
+	raw := op.Record
+	if raw == nil {
+		raw = v1alpha1.Resource{}
+	}
+
+	res, err := v1alpha1.ParseResource(s.schemas[resource], raw)
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	delete(res, "_v")
+
+	if action == v1alpha1.BulkUpdate {
+		res["_id"] = id
+
+		if err := b.checkRefs(ctx, s.schemas[resource], res); err != nil {
+			return BulkResult{}, err
+		}
+
+		return BulkResult{Id: id, Record: res}, b.stageUpdate(ctx, i, recordKey{resource, id}, res)
+	}
+
+	if err := b.checkRefs(ctx, s.schemas[resource], res); err != nil {
+		return BulkResult{}, err
+	}
+
+	id = GenerateId()
+
+	res["_id"] = id
+	res["_v"] = 1.0
+
+	rec, err := v1alpha1.ToRecord(s.layouts[resource], res)
+	if err != nil {
+		return BulkResult{}, err
+	}
+
+	b.stage(i, recordKey{resource, id}, res, writer.Op{Action: writer.OpCreate, Id: id, Record: rec})
+
+	return BulkResult{Id: id}, nil
+}
+
+// stageUpdate stages res over the record at key, keeping the fields res
+// leaves out, as Store.Update does.
+func (b *bulk) stageUpdate(ctx context.Context, i int, key recordKey, res v1alpha1.Resource) error {
+	old, err := b.readOne(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	for _, fs := range b.s.schemas[key.resource] {
+		if _, ok := res[fs.Field]; !ok {
+			res[fs.Field] = old[fs.Field]
+		}
+	}
+
+	v, ok := old["_v"].(float64)
+	if !ok {
+		return fmt.Errorf("'_v' field is missing or not a number in old resource with id %s", key.id)
+	}
+
+	res["_v"] = v + 1
+
+	rec, err := v1alpha1.ToRecord(b.s.layouts[key.resource], res)
+	if err != nil {
+		return err
+	}
+
+	b.stage(i, key, res, writer.Op{Action: writer.OpUpdate, Id: key.id, Record: rec})
+
+	return nil
+}
+
+// stageDelete stages the delete of the record at key and what its delete
+// policies require, once perms, loaded when nil and needed, allow u every
+// write beyond the delete of key itself, which the caller authorizes.
+func (b *bulk) stageDelete(ctx context.Context, i int, perms []v1alpha1.Resource, key recordKey, u v1alpha1.Resource) error {
+	plan := &deletePlan{
+		deletes: []recordKey{},
+		nulls:   map[recordKey][]string{},
+		seen:    map[recordKey]bool{},
+	}
+
+	if err := b.planDelete(ctx, key, plan); err != nil {
+		return err
+	}
+
+	for _, from := range plan.restricted {
+		if !plan.seen[from] {
+			return fmt.Errorf("%w: %s %s still references %s %s", ErrConflict, from.resource, from.id, key.resource, key.id)
+		}
+	}
+
+	if perms == nil && (len(plan.deletes) > 1 || len(plan.nulls) > 0) {
+		var err error
+		if perms, err = b.s.list(ctx, "_permissions", ""); err != nil {
+			slog.ErrorContext(ctx, "Authorization failed: could not load permissions", "error", err)
+			return ErrAuthz
+		}
+	}
+
+	for _, k := range plan.deletes[1:] {
+		err := b.s.authorizeWith(ctx, perms, k.resource, k.id, "delete", u)
+		b.s.countAuthz(k.resource, "delete", err)
+		if err != nil {
+			return fmt.Errorf("%w: the delete cascades to %s %s", err, k.resource, k.id)
+		}
+	}
+
+	for from := range plan.nulls {
+		if plan.seen[from] {
+			continue
+		}
+		err := b.s.authorizeWith(ctx, perms, from.resource, from.id, "update", u)
+		b.s.countAuthz(from.resource, "update", err)
+		if err != nil {
+			return fmt.Errorf("%w: the delete sets %s %s null", err, from.resource, from.id)
+		}
+	}
+
+	for from, fields := range plan.nulls {
+		if plan.seen[from] {
+			continue // about to be deleted anyway
+		}
+		res, err := b.readOne(ctx, from)
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			res[field] = nil
+		}
+		if err := b.stageUpdate(ctx, i, from, res); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range plan.deletes {
+		old, err := b.readOne(ctx, k)
+		if err != nil {
+			return err
+		}
+
+		hashes := []string{}
+		for _, fs := range b.s.schemas[k.resource] {
+			if f, ok := old[fs.Field].(v1alpha1.File); ok {
+				hashes = append(hashes, f.Hash)
+			}
+		}
+
+		b.stage(i, k, nil, writer.Op{Action: writer.OpDelete, Id: k.id})
+		b.writes[len(b.writes)-1].blobs = hashes
+	}
+
+	return nil
+}
+
+// stage stages write, after which key holds res, or nothing when res is
+// nil.
+func (b *bulk) stage(i int, key recordKey, res v1alpha1.Resource, write writer.Op) {
+	prev, wasStaged := b.pending[key]
+
+	b.writes = append(b.writes, bulkWrite{op: i, key: key, write: write, prev: prev, wasStaged: wasStaged})
+	b.pending[key] = res
+}
+
+// batches groups the staged writes by resource, in the order each
+// resource was first written.
+func (b *bulk) batches() []batch {
+	batches := []batch{}
+	index := map[string]int{}
+
+	for _, w := range b.writes {
+		i, ok := index[w.key.resource]
+		if !ok {
+			i = len(batches)
+			index[w.key.resource] = i
+			batches = append(batches, batch{resource: w.key.resource})
+		}
+		batches[i].ops = append(batches[i].ops, w.write)
+	}
+
+	return batches
+}
+
+// blobs are the hashes of the files the staged deletes drop, of the ops
+// that succeeded when results are given.
+func (b *bulk) blobs(results []BulkResult) []string {
+	hashes := []string{}
+
+	for _, w := range b.writes {
+		if results == nil || results[w.op].Err == nil {
+			hashes = append(hashes, w.blobs...)
+		}
+	}
+
+	return hashes
+}
+
+// rollback takes back the writes staged since mark.
+func (b *bulk) rollback(mark int) {
+	for j := len(b.writes) - 1; j >= mark; j-- {
+		w := b.writes[j]
+		if w.wasStaged {
+			b.pending[w.key] = w.prev
+		} else {
+			delete(b.pending, w.key)
+		}
+	}
+
+	b.writes = b.writes[:mark]
+}
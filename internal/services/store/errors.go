@@ -6,4 +6,9 @@ var (
 	ErrNotFound = errors.New("not found")
 	ErrAuthn    = errors.New("unauthenticated")
 	ErrAuthz    = errors.New("unauthorized")
+	ErrRef      = errors.New("invalid reference")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid request")
+	ErrTooLarge = errors.New("too large")
+	ErrAborted  = errors.New("aborted")
 )
@@ -0,0 +1,263 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/blob"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PutFile stores content for a file field and points the record at it,
+// releasing the file it held before. The field's Max bounds the size in
+// bytes and its Regex the sniffed MIME type. The content streams in
+// without holding a lock, so the record and the field are checked again
+// before the record is written.
+func (s *Store) PutFile(ctx context.Context, resource string, id string, field string, name string, r io.Reader) (_ v1alpha1.File, err error) {
+	ctx, span := s.startSpan(ctx, "store.PutFile", resource, attribute.String("record.id", id), attribute.String("field", field))
+	defer func() { endSpan(span, err) }()
+
+	fs, err := s.checkFile(ctx, resource, id, field)
+	if err != nil {
+		return v1alpha1.File{}, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return v1alpha1.File{}, err
+	}
+	head = head[:n]
+
+	mimeType := http.DetectContentType(head)
+
+	if len(fs.Regex) > 0 {
+		matched, err := regexp.MatchString(fs.Regex, mimeType)
+		if err != nil {
+			return v1alpha1.File{}, fmt.Errorf("invalid regex for field \"%s\": %w", fs.Field, err)
+		}
+		if !matched {
+			return v1alpha1.File{}, fmt.Errorf("%w: type %s is not allowed for field \"%s\"", ErrInvalid, mimeType, fs.Field)
+		}
+	}
+
+	s.blobsMtx.Lock()
+	s.uploads++
+	s.blobsMtx.Unlock()
+
+	counter := &limitedReader{r: io.MultiReader(bytes.NewReader(head), r), max: int64(fs.Max)}
+
+	hash, err := s.options.Blob.Put(ctx, counter)
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	s.blobsMtx.Lock()
+	defer s.blobsMtx.Unlock()
+	defer s.doneUpload(ctx)
+
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			return v1alpha1.File{}, fmt.Errorf("%w: field \"%s\" accepts at most %d bytes", ErrTooLarge, fs.Field, int64(fs.Max))
+		}
+		return v1alpha1.File{}, err
+	}
+
+	f := v1alpha1.File{
+		Name:     name,
+		Size:     counter.n,
+		MimeType: mimeType,
+		Hash:     hash,
+	}
+
+	// the record or the field may have gone while the content streamed in
+	if _, err := s.fileField(resource, field); err != nil {
+		s.releaseBlobs(ctx, []string{hash})
+		return v1alpha1.File{}, err
+	}
+
+	res, err := s.readOne(ctx, resource, id)
+	if err != nil {
+		s.releaseBlobs(ctx, []string{hash})
+		return v1alpha1.File{}, err
+	}
+
+	prev, hadFile := res[field].(v1alpha1.File)
+
+	res[field] = f
+
+	if err := s.update(ctx, resource, res); err != nil {
+		s.releaseBlobs(ctx, []string{hash})
+		return v1alpha1.File{}, err
+	}
+
+	if hadFile && prev.Hash != hash {
+		s.releaseBlobs(ctx, []string{prev.Hash})
+	}
+
+	return f, nil
+}
+
+// checkFile is the schema of field, when it is a file field of resource
+// and the record id exists.
+func (s *Store) checkFile(ctx context.Context, resource string, id string, field string) (v1alpha1.FieldSchema, error) {
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	fs, err := s.fileField(resource, field)
+	if err != nil {
+		return v1alpha1.FieldSchema{}, err
+	}
+
+	if _, err := s.readOne(ctx, resource, id); err != nil {
+		return v1alpha1.FieldSchema{}, err
+	}
+
+	return fs, nil
+}
+
+// GetFile returns the metadata and content held by a file field.
+func (s *Store) GetFile(ctx context.Context, resource string, id string, field string) (_ v1alpha1.File, _ io.ReadCloser, err error) {
+	ctx, span := s.startSpan(ctx, "store.GetFile", resource, attribute.String("record.id", id), attribute.String("field", field))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	if _, err := s.fileField(resource, field); err != nil {
+		return v1alpha1.File{}, nil, err
+	}
+
+	res, err := s.readOne(ctx, resource, id)
+	if err != nil {
+		return v1alpha1.File{}, nil, err
+	}
+
+	f, ok := res[field].(v1alpha1.File)
+	if !ok {
+		return v1alpha1.File{}, nil, ErrNotFound
+	}
+
+	rc, err := s.options.Blob.Get(ctx, f.Hash)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return v1alpha1.File{}, nil, ErrNotFound
+		}
+		return v1alpha1.File{}, nil, err
+	}
+
+	return f, rc, nil
+}
+
+// releaseBlobs deletes the blobs of hashes that no file field holds any
+// longer. Blobs are content addressed, so records may share one, and an
+// upload in flight may have just put one that no record holds yet, so
+// while there are any it waits for them. Failing to tell or to delete
+// leaves a blob behind, which the caller's write does not depend on, so it
+// is only logged. The caller holds blobsMtx.
+func (s *Store) releaseBlobs(ctx context.Context, hashes []string) {
+	if s.options.Blob == nil || len(hashes) == 0 {
+		return
+	}
+
+	if s.uploads > 0 {
+		s.deferred = append(s.deferred, hashes...)
+		return
+	}
+
+	held := map[string]bool{}
+
+	for resource, schemas := range s.schemas {
+		fields := []string{}
+		for _, fs := range schemas {
+			if v1alpha1.BaseType(fs.Type) == "file" {
+				fields = append(fields, fs.Field)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		rs, err := s.list(ctx, resource, "")
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to release blobs", "resource.name", resource, "error", err)
+			return
+		}
+
+		for _, r := range rs {
+			for _, field := range fields {
+				if f, ok := r[field].(v1alpha1.File); ok {
+					held[f.Hash] = true
+				}
+			}
+		}
+	}
+
+	for _, hash := range hashes {
+		if held[hash] {
+			continue
+		}
+		held[hash] = true // once
+
+		if err := s.options.Blob.Delete(ctx, hash); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			slog.ErrorContext(ctx, "failed to delete blob", "blob.hash", hash, "error", err)
+		}
+	}
+}
+
+// doneUpload counts an upload out and releases what waited for the last
+// one. The caller holds blobsMtx.
+func (s *Store) doneUpload(ctx context.Context) {
+	s.uploads--
+
+	if s.uploads == 0 && len(s.deferred) > 0 {
+		hashes := s.deferred
+		s.deferred = nil
+		s.releaseBlobs(ctx, hashes)
+	}
+}
+
+func (s *Store) fileField(resource string, field string) (v1alpha1.FieldSchema, error) {
+	schemas, ok := s.schemas[resource]
+	if !ok {
+		return v1alpha1.FieldSchema{}, ErrNotFound
+	}
+
+	idx := slices.IndexFunc(schemas, func(fs v1alpha1.FieldSchema) bool {
+		return fs.Field == field
+	})
+	if idx < 0 || v1alpha1.BaseType(schemas[idx].Type) != "file" {
+		return v1alpha1.FieldSchema{}, fmt.Errorf("%w: \"%s\" is not a file field of %s", ErrInvalid, field, resource)
+	}
+
+	if s.options.Blob == nil {
+		return v1alpha1.FieldSchema{}, errors.New("no blob store configured")
+	}
+
+	return schemas[idx], nil
+}
+
+// limitedReader counts what passes through and fails once more than max
+// bytes have been read. A max of 0 means no limit.
+type limitedReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.max > 0 && l.n > l.max {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
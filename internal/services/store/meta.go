@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+// actions are the actions a permission can grant.
+var actions = []string{"read", "create", "update", "delete"}
+
+// Resources describes every resource u may take some action on, by name.
+func (s *Store) Resources(ctx context.Context, u v1alpha1.Resource) ([]v1alpha1.ResourceMeta, error) {
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	perms, err := s.permissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metas := []v1alpha1.ResourceMeta{}
+
+	for _, resource := range slices.Sorted(maps.Keys(s.schemas)) {
+		meta, err := s.describe(perms, resource, u)
+		if errors.Is(err, ErrAuthn) || errors.Is(err, ErrAuthz) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		metas = append(metas, meta)
+	}
+
+	return metas, nil
+}
+
+// Resource describes a resource u may take some action on.
+func (s *Store) Resource(ctx context.Context, resource string, u v1alpha1.Resource) (v1alpha1.ResourceMeta, error) {
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	if _, ok := s.schemas[resource]; !ok {
+		return v1alpha1.ResourceMeta{}, ErrNotFound
+	}
+
+	perms, err := s.permissions(ctx)
+	if err != nil {
+		return v1alpha1.ResourceMeta{}, err
+	}
+
+	return s.describe(perms, resource, u)
+}
+
+// permissions reads the rules once for every resource described.
+func (s *Store) permissions(ctx context.Context) ([]v1alpha1.Resource, error) {
+	perms, err := s.list(ctx, "_permissions", "")
+	if err != nil {
+		slog.ErrorContext(ctx, "Authorization failed: could not load permissions", "error", err)
+		return nil, ErrAuthz
+	}
+
+	return perms, nil
+}
+
+// describe matches perms once per action for no record in particular.
+// Rules that grant access through a field of the record count, since u may
+// be named in some, except for create, as there is no record yet.
+func (s *Store) describe(perms []v1alpha1.Resource, resource string, u v1alpha1.Resource) (v1alpha1.ResourceMeta, error) {
+	meta := v1alpha1.ResourceMeta{
+		Name:    resource,
+		Version: v1alpha1.SchemaVersion(s.layouts[resource]),
+		Actions: []string{},
+		Fields:  slices.Clone(s.schemas[resource]),
+	}
+
+	username, roles := identify(u)
+	denied := ErrAuthz
+
+	for _, action := range actions {
+		g, err := match(perms, resource, action, username, roles)
+		switch {
+		case errors.Is(err, ErrAuthn):
+			denied = ErrAuthn
+		case err != nil:
+			continue
+		case g.public || len(g.role) > 0 || action != "create":
+			meta.Actions = append(meta.Actions, action)
+		}
+	}
+
+	if len(meta.Actions) == 0 {
+		return v1alpha1.ResourceMeta{}, denied
+	}
+
+	return meta, nil
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/blob"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+)
+
+type Option func(*Options)
+
+// ReadWriterFactory opens the read/writer backing a resource with the given
+// field schemas.
+type ReadWriterFactory func(resource string, schemas []v1alpha1.FieldSchema) (readwriter.ReadWriter, error)
+
+type Options struct {
+	Blob             blob.Blob
+	SchemaReadWriter readwriter.ReadWriter
+	Factory          ReadWriterFactory
+	Context          context.Context
+}
+
+func WithBlob(b blob.Blob) Option {
+	return func(o *Options) {
+		o.Blob = b
+	}
+}
+
+// WithSchemaReadWriter sets where schema changes are persisted. Its records
+// follow v1alpha1.SchemaFields.
+func WithSchemaReadWriter(rw readwriter.ReadWriter) Option {
+	return func(o *Options) {
+		o.SchemaReadWriter = rw
+	}
+}
+
+// WithReadWriterFactory sets how read/writers are opened when a schema
+// change creates or reshapes a resource.
+func WithReadWriterFactory(fn ReadWriterFactory) Option {
+	return func(o *Options) {
+		o.Factory = fn
+	}
+}
+
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Context: context.Background(),
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
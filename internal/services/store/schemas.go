@@ -0,0 +1,533 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/writer"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Schemas returns a copy of the field schemas of every resource.
+func (s *Store) Schemas(ctx context.Context) map[string][]v1alpha1.FieldSchema {
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	schemas := make(map[string][]v1alpha1.FieldSchema, len(s.schemas))
+
+	for resource, fields := range s.schemas {
+		schemas[resource] = slices.Clone(fields)
+	}
+
+	return schemas
+}
+
+// Schema returns a copy of the field schemas of a resource.
+func (s *Store) Schema(ctx context.Context, resource string) ([]v1alpha1.FieldSchema, error) {
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	fields, ok := s.schemas[resource]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return slices.Clone(fields), nil
+}
+
+// SchemaVersion returns the version of a resource's schema, which grows
+// with every change made to it.
+func (s *Store) SchemaVersion(ctx context.Context, resource string) (int, error) {
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	layout, ok := s.layouts[resource]
+	if !ok {
+		return 0, ErrNotFound
+	}
+
+	return v1alpha1.SchemaVersion(layout), nil
+}
+
+// CreateResource defines a new resource. The _id and _v fields are added in
+// front of the given fields.
+func (s *Store) CreateResource(ctx context.Context, resource string, fields []v1alpha1.FieldSchema) (_ []v1alpha1.FieldSchema, err error) {
+	ctx, span := s.startSpan(ctx, "store.CreateResource", resource)
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.Lock()
+	defer s.schemasMtx.Unlock()
+
+	if err := s.canChangeSchemas(); err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.schemas[resource]; ok {
+		return nil, fmt.Errorf("%w: resource %q already exists", ErrConflict, resource)
+	}
+
+	if strings.HasPrefix(resource, "_") {
+		return nil, fmt.Errorf("%w: resource names starting with '_' are reserved", ErrInvalid)
+	}
+
+	next := []v1alpha1.FieldSchema{
+		{Resource: resource, Field: "_id", Type: "text", Regex: "^.+$"},
+		{Resource: resource, Field: "_v", Type: "number", Min: 1},
+	}
+
+	for _, fs := range fields {
+		if fs.Field == "_id" || fs.Field == "_v" {
+			return nil, fmt.Errorf("%w: field %q is added automatically", ErrInvalid, fs.Field)
+		}
+		fs.Resource = resource
+		fs.Dropped = false
+		next = append(next, fs)
+	}
+
+	if err := s.checkSchema(resource, next); err != nil {
+		return nil, err
+	}
+
+	rw, err := s.open(resource, next)
+	if err != nil {
+		return nil, err
+	}
+
+	created := []string{}
+
+	for i := range next {
+		next[i].Id = GenerateId()
+		next[i].Version = 1
+
+		if err := s.options.SchemaReadWriter.Create(ctx, v1alpha1.ToSchemaRecord(next[i])); err != nil {
+			for _, id := range created {
+				if err := s.options.SchemaReadWriter.Delete(ctx, id); err != nil {
+					slog.ErrorContext(ctx, "failed to roll back schema row", "resource.name", resource, "record.id", id, "error", err)
+				}
+			}
+			s.discard(ctx, resource, rw)
+			return nil, err
+		}
+
+		created = append(created, next[i].Id)
+	}
+
+	s.install(ctx, resource, next, rw)
+
+	return slices.Clone(next), nil
+}
+
+// AddField appends a field to a resource. Records written before the
+// change read the new field as its default, as null, or as its zero value.
+func (s *Store) AddField(ctx context.Context, resource string, fs v1alpha1.FieldSchema) (_ []v1alpha1.FieldSchema, err error) {
+	ctx, span := s.startSpan(ctx, "store.AddField", resource, attribute.String("field", fs.Field))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.Lock()
+	defer s.schemasMtx.Unlock()
+
+	if err := s.canChangeSchemas(); err != nil {
+		return nil, err
+	}
+
+	layout, ok := s.layouts[resource]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if slices.ContainsFunc(s.schemas[resource], func(other v1alpha1.FieldSchema) bool { return other.Field == fs.Field }) {
+		return nil, fmt.Errorf("%w: field %q already exists", ErrConflict, fs.Field)
+	}
+
+	fs.Id = GenerateId()
+	fs.Version = 1
+	fs.Resource = resource
+	fs.Dropped = false
+
+	next := append(slices.Clone(layout), fs)
+
+	if err := s.checkSchema(resource, next); err != nil {
+		return nil, err
+	}
+
+	rw, err := s.open(resource, next)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.options.SchemaReadWriter.Create(ctx, v1alpha1.ToSchemaRecord(fs)); err != nil {
+		s.discard(ctx, resource, rw)
+		return nil, err
+	}
+
+	s.install(ctx, resource, next, rw)
+
+	return v1alpha1.LiveFields(next), nil
+}
+
+// AlterField replaces the definition of a field, which keeps its identity
+// and column. Giving it another name renames it, and the permissions that
+// grant by it. A type change is refused while a stored value would not
+// read correctly under the new type.
+func (s *Store) AlterField(ctx context.Context, resource string, field string, fs v1alpha1.FieldSchema) (_ []v1alpha1.FieldSchema, err error) {
+	ctx, span := s.startSpan(ctx, "store.AlterField", resource, attribute.String("field", field))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.Lock()
+	defer s.schemasMtx.Unlock()
+
+	if err := s.canChangeSchemas(); err != nil {
+		return nil, err
+	}
+
+	layout, idx, err := s.findField(resource, field)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fs.Field) == 0 {
+		fs.Field = field
+	}
+
+	if fs.Field != field && slices.ContainsFunc(s.schemas[resource], func(other v1alpha1.FieldSchema) bool { return other.Field == fs.Field }) {
+		return nil, fmt.Errorf("%w: field %q already exists", ErrConflict, fs.Field)
+	}
+
+	if fs.Field == "_id" || fs.Field == "_v" {
+		return nil, fmt.Errorf("%w: field %q is reserved", ErrInvalid, fs.Field)
+	}
+
+	fs.Id = layout[idx].Id
+	fs.Version = layout[idx].Version + 1
+	fs.Resource = resource
+	fs.Dropped = false
+
+	next := slices.Clone(layout)
+	next[idx] = fs
+
+	if err := s.checkSchema(resource, next); err != nil {
+		return nil, err
+	}
+
+	if fs.Type != layout[idx].Type {
+		if err := s.checkConversion(ctx, resource, idx, layout[idx], fs); err != nil {
+			return nil, err
+		}
+	}
+
+	rw, err := s.open(resource, next)
+	if err != nil {
+		return nil, err
+	}
+
+	// permissions that grant by the field follow it to its new name
+	undo, err := s.renamePermissions(ctx, resource, field, fs.Field)
+	if err != nil {
+		s.discard(ctx, resource, rw)
+		return nil, err
+	}
+
+	if err := s.options.SchemaReadWriter.Update(ctx, v1alpha1.ToSchemaRecord(fs)); err != nil {
+		undo()
+		s.discard(ctx, resource, rw)
+		return nil, err
+	}
+
+	s.install(ctx, resource, next, rw)
+
+	return v1alpha1.LiveFields(next), nil
+}
+
+// renamePermissions points the permissions that grant by field of
+// resource at its new name, and returns what takes that back.
+func (s *Store) renamePermissions(ctx context.Context, resource string, field string, name string) (func(), error) {
+	rw, ok := s.rws["_permissions"]
+	if !ok || field == name {
+		return func() {}, nil
+	}
+
+	perms, err := s.list(ctx, "_permissions", "")
+	if err != nil {
+		return nil, err
+	}
+
+	ops := []writer.Op{}
+
+	for _, p := range perms {
+		if p["resource"] != resource || p["field"] != field {
+			continue
+		}
+
+		p["field"] = name
+
+		if v, ok := p["_v"].(float64); ok {
+			p["_v"] = v + 1
+		}
+
+		rec, err := v1alpha1.ToRecord(s.layouts["_permissions"], p)
+		if err != nil {
+			return nil, err
+		}
+
+		id, _ := p["_id"].(string)
+
+		ops = append(ops, writer.Op{Action: writer.OpUpdate, Id: id, Record: rec})
+	}
+
+	if len(ops) == 0 {
+		return func() {}, nil
+	}
+
+	inverse, err := readwriter.Inverse(ctx, rw, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := readwriter.WriteBatch(ctx, rw, ops); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := readwriter.WriteBatch(ctx, rw, readwriter.Undo(inverse)); err != nil {
+			slog.ErrorContext(ctx, "failed to take back renamed permissions", "resource.name", resource, "field", field, "error", err)
+		}
+	}, nil
+}
+
+// RemoveField drops a field from a resource. Its column stays in place, so
+// other fields keep theirs, and is cleared as records are rewritten.
+func (s *Store) RemoveField(ctx context.Context, resource string, field string) (_ []v1alpha1.FieldSchema, err error) {
+	ctx, span := s.startSpan(ctx, "store.RemoveField", resource, attribute.String("field", field))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.Lock()
+	defer s.schemasMtx.Unlock()
+
+	if err := s.canChangeSchemas(); err != nil {
+		return nil, err
+	}
+
+	layout, idx, err := s.findField(resource, field)
+	if err != nil {
+		return nil, err
+	}
+
+	next := slices.Clone(layout)
+	next[idx].Dropped = true
+	next[idx].Version++
+
+	rw, err := s.open(resource, next)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.options.SchemaReadWriter.Update(ctx, v1alpha1.ToSchemaRecord(next[idx])); err != nil {
+		s.discard(ctx, resource, rw)
+		return nil, err
+	}
+
+	s.install(ctx, resource, next, rw)
+
+	return v1alpha1.LiveFields(next), nil
+}
+
+// Migrate rewrites the records that were written under an older layout of
+// their resource: short records are filled in, dropped columns cleared and
+// values re-encoded for changed types. It returns how many records of each
+// resource were rewritten.
+func (s *Store) Migrate(ctx context.Context) (_ map[string]int, err error) {
+	ctx, span := s.startSpan(ctx, "store.Migrate", "")
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.Lock()
+	defer s.schemasMtx.Unlock()
+
+	migrated := map[string]int{}
+
+	for resource, layout := range s.layouts {
+		rw, ok := s.rws[resource]
+		if !ok {
+			continue
+		}
+
+		recs, err := rw.List(ctx)
+		if err != nil {
+			return migrated, err
+		}
+
+		for _, rec := range recs {
+			res, err := v1alpha1.ToResource(layout, rec)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to read %s %s: %w", resource, rec[0], err)
+			}
+
+			upgraded, err := v1alpha1.ToRecord(layout, res)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to upgrade %s %s: %w", resource, rec[0], err)
+			}
+
+			upgraded[1] = rec[1]
+
+			// columns past the layout are not ours to rewrite
+			if len(rec) >= len(upgraded) && slices.Equal(rec[:len(upgraded)], upgraded) {
+				continue
+			}
+
+			if err := rw.Update(ctx, upgraded); err != nil {
+				return migrated, err
+			}
+
+			migrated[resource]++
+		}
+	}
+
+	return migrated, nil
+}
+
+func (s *Store) canChangeSchemas() error {
+	if s.options.SchemaReadWriter == nil || s.options.Factory == nil {
+		return errors.New("schema changes are not configured")
+	}
+
+	return nil
+}
+
+// findField looks up a live field that schema changes may touch and returns
+// the resource's layout along with the field's column.
+func (s *Store) findField(resource string, field string) ([]v1alpha1.FieldSchema, int, error) {
+	layout, ok := s.layouts[resource]
+	if !ok {
+		return nil, -1, ErrNotFound
+	}
+
+	idx := slices.IndexFunc(layout, func(fs v1alpha1.FieldSchema) bool {
+		return !fs.Dropped && fs.Field == field
+	})
+	if idx < 0 {
+		return nil, -1, ErrNotFound
+	}
+
+	if field == "_id" || field == "_v" {
+		return nil, -1, fmt.Errorf("%w: field %q cannot be changed", ErrInvalid, field)
+	}
+
+	return layout, idx, nil
+}
+
+// checkSchema validates the live fields of the layout a resource would have
+// after a change.
+func (s *Store) checkSchema(resource string, layout []v1alpha1.FieldSchema) error {
+	errs := []error{}
+
+	seen := map[string]bool{}
+
+	for _, fs := range v1alpha1.LiveFields(layout) {
+		if err := v1alpha1.ValidateFieldSchema(fs); err != nil {
+			errs = append(errs, err)
+		}
+
+		if seen[fs.Field] {
+			errs = append(errs, fmt.Errorf("field %q is defined twice", fs.Field))
+		}
+		seen[fs.Field] = true
+
+		if target, ok := v1alpha1.RefResource(fs); ok && target != resource {
+			if _, ok := s.schemas[target]; !ok {
+				errs = append(errs, fmt.Errorf("field %q references unknown resource %q", fs.Field, target))
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	return nil
+}
+
+// checkConversion makes sure every stored value of the field in column col
+// reads correctly under its new definition.
+func (s *Store) checkConversion(ctx context.Context, resource string, col int, from v1alpha1.FieldSchema, to v1alpha1.FieldSchema) error {
+	rw, ok := s.rws[resource]
+	if !ok {
+		return nil
+	}
+
+	recs, err := rw.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	target, isRef := v1alpha1.RefResource(to)
+
+	for _, rec := range recs {
+		if col >= len(rec) {
+			continue
+		}
+
+		if err := v1alpha1.CheckConversion(from, to, rec[col]); err != nil {
+			return fmt.Errorf("%w: %s %s: %w", ErrConflict, resource, rec[0], err)
+		}
+
+		if !isRef || len(rec[col]) == 0 || rec[col] == v1alpha1.NullValue {
+			continue
+		}
+
+		if _, err := s.readOne(ctx, target, rec[col]); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("%w: %s %s points at missing %s %s", ErrConflict, resource, rec[0], target, rec[col])
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// open opens a read/writer for the new layout of resource. A change opens
+// it before persisting anything, so that a read/writer that fails to open
+// leaves no trace, then installs it, or discards it when persisting fails.
+func (s *Store) open(resource string, layout []v1alpha1.FieldSchema) (readwriter.ReadWriter, error) {
+	rw, err := s.options.Factory(resource, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	return readwriter.Traced(rw, resource), nil
+}
+
+// discard closes a read/writer opened for a change that failed.
+func (s *Store) discard(ctx context.Context, resource string, rw readwriter.ReadWriter) {
+	if err := rw.Close(ctx); err != nil {
+		slog.ErrorContext(ctx, "failed to close read/writer", "resource.name", resource, "error", err)
+	}
+}
+
+// install replaces the schemas, layouts and rws maps with copies holding
+// the new layout of resource and its read/writer. The caller holds the
+// write lock, so no request sees the old and new maps mixed.
+func (s *Store) install(ctx context.Context, resource string, layout []v1alpha1.FieldSchema, rw readwriter.ReadWriter) {
+	old := s.rws[resource]
+
+	nextSchemas := maps.Clone(s.schemas)
+	nextSchemas[resource] = v1alpha1.LiveFields(layout)
+
+	nextLayouts := maps.Clone(s.layouts)
+	nextLayouts[resource] = layout
+
+	nextRws := maps.Clone(s.rws)
+	nextRws[resource] = rw
+
+	s.schemas, s.layouts, s.rws = nextSchemas, nextLayouts, nextRws
+
+	if old != nil {
+		s.discard(ctx, resource, old)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"slices"
 	"sync"
 	"time"
@@ -11,14 +13,35 @@ import (
 	"github.com/w-h-a/backend/api/v1alpha1"
 	"github.com/w-h-a/backend/internal/clients/reader"
 	"github.com/w-h-a/backend/internal/clients/readwriter"
-	"github.com/w-h-a/backend/internal/clients/writer"
+	"github.com/w-h-a/backend/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Store struct {
+	options Options
+	// schemas holds the live fields of each resource and layouts all of
+	// their columns, dropped fields included.
 	schemas   map[string][]v1alpha1.FieldSchema
+	layouts   map[string][]v1alpha1.FieldSchema
 	rws       map[string]readwriter.ReadWriter
 	isRunning bool
+	tracer    trace.Tracer
 	mtx       sync.RWMutex
+	// schemasMtx guards schemas, layouts and rws. Public methods hold it for
+	// reading for their whole duration so a schema change waits for
+	// in-flight calls and is never seen half applied, except PutFile, which
+	// lets go of it while the content streams in. Unexported methods never
+	// take it.
+	schemasMtx sync.RWMutex
+	// blobsMtx guards uploads and deferred, and keeps a blob from being
+	// released while a record comes to hold it.
+	blobsMtx sync.Mutex
+	// uploads counts the puts in flight, whose blobs no record holds yet,
+	// and deferred the blobs to release once there are none.
+	uploads  int
+	deferred []string
 }
 
 func (s *Store) Run(stop chan struct{}) error {
@@ -71,9 +94,18 @@ func (s *Store) stop(ctx context.Context) error {
 
 	gracefulStopDone := make(chan struct{})
 	go func() {
-		for _, rw := range s.rws {
+		s.schemasMtx.Lock()
+		defer s.schemasMtx.Unlock()
+
+		if s.options.SchemaReadWriter != nil {
+			if err := s.options.SchemaReadWriter.Close(context.Background()); err != nil {
+				slog.Error("failed to close read/writer", "resource.name", "_schemas", "error", err)
+			}
+		}
+
+		for resource, rw := range s.rws {
 			if err := rw.Close(context.Background()); err != nil {
-				// log error
+				slog.Error("failed to close read/writer", "resource.name", resource, "error", err)
 			}
 		}
 		close(gracefulStopDone)
@@ -91,36 +123,40 @@ func (s *Store) stop(ctx context.Context) error {
 }
 
 func (s *Store) Authenticate(ctx context.Context, username string, password string) (v1alpha1.Resource, error) {
-	// ctx, span := s.tracer.Start(ctx, "store.Authenticate", trace.WithAttributes(attribute.String("user.id", username)))
-	// defer span.End()
+	ctx, span := s.startSpan(ctx, "store.Authenticate", "_users", attribute.String("user.id", username))
+	defer span.End()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
 
 	u, err := s.readOne(ctx, "_users", username)
 	if err != nil {
-		// span.RecordError(err)
-		// slog.WarnContext(ctx, "Authentication failed: user not found", "user.id", username, "error", err)
+		span.RecordError(err)
+		slog.WarnContext(ctx, "Authentication failed: user not found", "user.id", username, "error", err)
+		metrics.AuthnFailed("user_not_found")
 		return nil, ErrAuthn
 	}
 
 	salt, ok := u["salt"].(string)
 	if !ok {
-		// err := fmt.Errorf("user %q has invalid salt data", username)
-		// span.RecordError(err)
-		// slog.ErrorContext(ctx, "Authentication failed: invalid password data", "user.id", username)
+		span.RecordError(fmt.Errorf("user %q has invalid salt data", username))
+		slog.ErrorContext(ctx, "Authentication failed: invalid salt data", "user.id", username)
+		metrics.AuthnFailed("invalid_user")
 		return nil, ErrAuthn
 	}
 
 	expectedPassword, ok := u["password"].(string)
 	if !ok {
-		// err = fmt.Errorf("user %q has invalid password data", username)
-		// span.RecordError(err)
-		// slog.ErrorContext(ctx, "Authentication failed: invalid password data", "user.id", username)
+		span.RecordError(fmt.Errorf("user %q has invalid password data", username))
+		slog.ErrorContext(ctx, "Authentication failed: invalid password data", "user.id", username)
+		metrics.AuthnFailed("invalid_user")
 		return nil, ErrAuthn
 	}
 
 	if expectedPassword != HashPassword(password, salt) {
-		// err := errors.New("password mismatch")
-		// span.RecordError(err)
-		// slog.WarnContext(ctx, "Authentication failed: password mismatch", "user.id", username)
+		span.RecordError(errors.New("password mismatch"))
+		slog.WarnContext(ctx, "Authentication failed: password mismatch", "user.id", username)
+		metrics.AuthnFailed("password_mismatch")
 		return nil, ErrAuthn
 	}
 
@@ -128,78 +164,182 @@ func (s *Store) Authenticate(ctx context.Context, username string, password stri
 }
 
 func (s *Store) Authorize(ctx context.Context, resource string, id string, action string, u v1alpha1.Resource) error {
-	username := ""
-	if u != nil {
-		username = u["_id"].(string)
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	err := s.authorize(ctx, resource, id, action, u)
+
+	s.countAuthz(resource, action, err)
+
+	return err
+}
+
+// countAuthz counts the refusals among the decisions of authorize.
+func (s *Store) countAuthz(resource string, action string, err error) {
+	switch {
+	case errors.Is(err, ErrAuthn):
+		metrics.AuthzFailed(s.resourceLabel(resource), action, "unauthenticated")
+	case errors.Is(err, ErrAuthz):
+		metrics.AuthzFailed(s.resourceLabel(resource), action, "denied")
 	}
+}
+
+// resourceLabel is resource when it exists and empty otherwise, so that
+// made up resources do not each get their own series.
+func (s *Store) resourceLabel(resource string) string {
+	if _, ok := s.schemas[resource]; ok || resource == "_schemas" {
+		return resource
+	}
+	return ""
+}
+
+func (s *Store) authorize(ctx context.Context, resource string, id string, action string, u v1alpha1.Resource) error {
+	return s.authorizeWith(ctx, nil, resource, id, action, u)
+}
+
+// authorizeWith decides by perms, the records of _permissions, which it
+// loads when nil. Many decisions in a row can load them once.
+func (s *Store) authorizeWith(ctx context.Context, perms []v1alpha1.Resource, resource string, id string, action string, u v1alpha1.Resource) (err error) {
+	username, roles := identify(u)
+
+	ctx, span := s.startSpan(ctx, "store.Authorize", resource,
+		attribute.String("record.id", id),
+		attribute.String("action", action),
+		attribute.String("user.id", username), // Include username if available
+	)
+	defer func() {
+		// the decision and the rule that made it
+		span.SetAttributes(attribute.Bool("authz.allowed", err == nil))
+		endSpan(span, err)
+	}()
 
-	roles := []string{}
-	if u != nil {
-		if rs, rolesOk := u["roles"].([]string); rolesOk {
-			roles = rs
+	if perms == nil {
+		perms, err = s.list(ctx, "_permissions", "")
+		if err != nil {
+			span.RecordError(err)
+			slog.ErrorContext(ctx, "Authorization failed: could not load permissions", "error", err)
+			return ErrAuthz
 		}
 	}
 
-	// ctx, span := s.tracer.Start(ctx, "store.Authorize", trace.WithAttributes(
-	// 	attribute.String("resource.name", resource),
-	// 	attribute.String("record.id", id),
-	// 	attribute.String("action", action),
-	// 	attribute.String("user.id", username), // Include username if available
-	// ))
-	// defer span.End()
+	g, err := match(perms, resource, action, username, roles)
+	if errors.Is(err, ErrAuthn) {
+		slog.DebugContext(ctx, "Authorization failed: not authenticated", "resource.name", resource, "record.id", id, "action", action)
+		return ErrAuthn
+	}
 
-	rs, err := s.list(ctx, "_permissions", "")
-	if err != nil {
-		// span.Record(err)
-		// slog.ErrorContext(ctx, "Authorization failed: could not load permissions", "error", err)
-		return ErrAuthz
+	switch {
+	case g.public:
+		span.SetAttributes(attribute.String("authz.rule", "public"))
+		return nil
+	case len(g.role) > 0:
+		span.SetAttributes(attribute.String("authz.rule", "role"), attribute.String("authz.role", g.role))
+		return nil
 	}
 
-	for _, p := range rs {
+	if len(id) > 0 && len(g.owners) > 0 {
+		res, err := s.readOne(ctx, resource, id)
+		if err != nil {
+			return err
+		}
+		for _, field := range g.owners {
+			if names(res, field, username) {
+				span.SetAttributes(attribute.String("authz.rule", "field"), attribute.String("authz.field", field))
+				return nil // user name matches or is in the requested resource field
+			}
+		}
+	}
+
+	slog.DebugContext(ctx, "Authorization failed: no permission applies", "resource.name", resource, "record.id", id, "action", action, "user.id", username)
+
+	return ErrAuthz
+}
+
+// identify is the name and roles of u. A user without an id is nobody in
+// particular, with no name and no roles.
+func identify(u v1alpha1.Resource) (string, []string) {
+	uid, ok := u["_id"].(string)
+	if !ok || len(uid) == 0 {
+		return "", []string{}
+	}
+
+	roles, ok := u["roles"].([]string)
+	if !ok {
+		roles = []string{}
+	}
+
+	return uid, roles
+}
+
+// grant is what the rules for an action on a resource grant a user: every
+// record, when public or by role, or else the records whose owner fields
+// name the user.
+type grant struct {
+	public bool
+	role   string
+	owners []string
+}
+
+// match reads perms, in order, for the rules on action over resource that
+// apply to the user named username, empty for nobody, with roles. It fails
+// with ErrAuthn when a rule that is not public comes first and there is no
+// user, and with ErrAuthz when no rule applies at all.
+func match(perms []v1alpha1.Resource, resource string, action string, username string, roles []string) (grant, error) {
+	g := grant{}
+
+	for _, p := range perms {
 		if p["resource"] != resource || (p["action"] != "*" && p["action"] != action) {
 			continue // find what we're looking for
 		}
 
 		if p["field"] == "" && p["role"] == "" {
-			return nil // public
+			return grant{public: true}, nil
 		}
 
-		if u == nil {
-			return ErrAuthn
+		if len(username) == 0 {
+			return grant{}, ErrAuthn
 		}
 
-		if role, roleOK := p["role"].(string); roleOK {
-			if role == "*" || slices.Contains(roles, role) {
-				return nil // rbac
-			}
+		if role, ok := p["role"].(string); ok && (role == "*" || slices.Contains(roles, role)) {
+			return grant{role: role}, nil // rbac
 		}
 
-		if len(id) > 0 {
-			res, err := s.readOne(ctx, resource, id)
-			if err != nil {
-				return err
-			}
-			if field, ok := p["field"].(string); ok {
-				if user, ok := res[field]; ok && user == username {
-					return nil // user name matches requested resource field
-				} else if users, ok := res[field].([]string); ok && slices.Contains(users, username) {
-					return nil // user name is in the requested resource field
-				}
-			}
+		if field, ok := p["field"].(string); ok && len(field) > 0 && !slices.Contains(g.owners, field) {
+			g.owners = append(g.owners, field)
 		}
 	}
 
-	return ErrAuthz
+	if len(g.owners) == 0 {
+		return grant{}, ErrAuthz
+	}
+
+	return g, nil
 }
 
-// TODO: traces
-func (s *Store) List(ctx context.Context, resource string, sortBy string) ([]v1alpha1.Resource, error) {
-	return s.list(ctx, resource, sortBy)
+// names reports whether field of res is username, or a list that holds
+// it.
+func names(res v1alpha1.Resource, field string, username string) bool {
+	if user, ok := res[field]; ok && user == username {
+		return true
+	}
+	users, ok := res[field].([]string)
+	return ok && slices.Contains(users, username)
 }
 
-// TODO: traces
-func (s *Store) list(ctx context.Context, resource string, sortBy string) ([]v1alpha1.Resource, error) {
-	schemas, ok := s.schemas[resource]
+// List returns the records of resource, sorted by sortBy, keys like
+// -priority,description, unless opts sort them otherwise.
+func (s *Store) List(ctx context.Context, resource string, sortBy string, opts ...reader.ListOption) (rs []v1alpha1.Resource, err error) {
+	ctx, span := s.startSpan(ctx, "store.List", resource)
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	return s.list(ctx, resource, sortBy, opts...)
+}
+
+func (s *Store) list(ctx context.Context, resource string, sortBy string, opts ...reader.ListOption) ([]v1alpha1.Resource, error) {
+	layout, ok := s.layouts[resource]
 	if !ok {
 		return nil, ErrNotFound
 	}
@@ -209,15 +349,34 @@ func (s *Store) list(ctx context.Context, resource string, sortBy string) ([]v1a
 		return nil, ErrNotFound
 	}
 
+	keys, err := v1alpha1.ParseSort(sortBy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	opts = append([]reader.ListOption{reader.WithSort(v1alpha1.Sort{Keys: keys})}, opts...)
+
+	options := reader.NewListOptions(opts...)
+
+	if err := options.Sort.Validate(s.schemas[resource]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	p := options.Projection
+	if err := p.Validate(s.schemas[resource]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
 	rs := []v1alpha1.Resource{}
 
-	recs, err := rw.List(ctx, reader.WithSortBy(sortBy))
+	recs, err := rw.List(ctx, opts...)
 	if err != nil {
+		slog.ErrorContext(ctx, "failed to list records", "resource.name", resource, "error", err)
 		return nil, err
 	}
 
 	for _, rec := range recs {
-		r, err := v1alpha1.ToResource(schemas, rec)
+		r, err := v1alpha1.ProjectResource(layout, rec, p)
 		if err != nil {
 			return rs, err
 		}
@@ -227,79 +386,208 @@ func (s *Store) list(ctx context.Context, resource string, sortBy string) ([]v1a
 	return rs, nil
 }
 
-func (s *Store) ReadOne(ctx context.Context, resource string, id string) (v1alpha1.Resource, error) {
-	// ctx, span := s.tracer.Start(ctx, "store.ReadOne", trace.WithAttributes(
-	// 	attribute.String("resource.name", resource),
-	// 	attribute.String("record.id", id),
-	// ))
-	// defer span.End()
+func (s *Store) Nearest(ctx context.Context, resource string, q reader.VectorQuery) (ns []v1alpha1.Neighbor, err error) {
+	ctx, span := s.startSpan(ctx, "store.Nearest", resource, attribute.String("field", q.Field), attribute.Int("k", q.K))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	schemas, ok := s.schemas[resource]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	rw, ok := s.rws[resource]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	idx := slices.IndexFunc(schemas, func(fs v1alpha1.FieldSchema) bool {
+		return fs.Field == q.Field
+	})
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: unknown field %q", ErrInvalid, q.Field)
+	}
+
+	if dim, ok := v1alpha1.VectorDim(schemas[idx]); !ok || dim != len(q.Vector) {
+		return nil, fmt.Errorf("%w: field %q is not a vector of dimension %d", ErrInvalid, q.Field, len(q.Vector))
+	}
+
+	if !v1alpha1.ValidMetric(q.Metric) {
+		return nil, fmt.Errorf("%w: unknown metric %q", ErrInvalid, q.Metric)
+	}
+
+	if q.K < 1 || q.K > maxNearest {
+		return nil, fmt.Errorf("%w: k must be between 1 and %d", ErrInvalid, maxNearest)
+	}
 
-	return s.readOne(ctx, resource, id)
+	recs, err := rw.List(ctx, reader.WithNearest(q))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list records", "resource.name", resource, "error", err)
+		return nil, err
+	}
+
+	query := v1alpha1.ToFloat32s(q.Vector)
+
+	ns = []v1alpha1.Neighbor{}
+
+	for _, rec := range recs {
+		r, err := v1alpha1.ToResource(s.layouts[resource], rec)
+		if err != nil {
+			return ns, err
+		}
+
+		vec, ok := r[q.Field].([]float64)
+		if !ok {
+			continue
+		}
+
+		ns = append(ns, v1alpha1.Neighbor{
+			Record:   r,
+			Distance: v1alpha1.VectorDistance(q.Metric, query, v1alpha1.ToFloat32s(vec)),
+		})
+	}
+
+	return ns, nil
 }
 
-func (s *Store) readOne(ctx context.Context, resource string, id string) (v1alpha1.Resource, error) {
-	// ctx, span := s.tracer.Start(ctx, "store.ReadOne", trace.WithAttributes(
-	// 	attribute.String("resource.name", resource),
-	// 	attribute.String("record.id", id),
-	// ))
-	// defer span.End()
+func (s *Store) ReadOne(ctx context.Context, resource string, id string, opts ...reader.ReadOneOption) (res v1alpha1.Resource, err error) {
+	ctx, span := s.startSpan(ctx, "store.ReadOne", resource, attribute.String("record.id", id))
+	defer func() { endSpan(span, err) }()
 
-	schemas, ok := s.schemas[resource]
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	return s.readOne(ctx, resource, id, opts...)
+}
+
+// readOne records its errors on the span of the operation that calls it.
+func (s *Store) readOne(ctx context.Context, resource string, id string, opts ...reader.ReadOneOption) (v1alpha1.Resource, error) {
+	layout, ok := s.layouts[resource]
 	if !ok {
-		// err := errors.New("schema not found")
-		// span.RecordError(err)
 		return nil, ErrNotFound
 	}
 
 	rw, ok := s.rws[resource]
 	if !ok {
-		// ditto
 		return nil, ErrNotFound
 	}
 
-	rec, err := rw.ReadOne(ctx, id)
+	p := reader.NewReadOneOptions(opts...).Projection
+	if err := p.Validate(s.schemas[resource]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	rec, err := rw.ReadOne(ctx, id, opts...)
 	if err != nil {
 		if errors.Is(err, reader.ErrNotFound) {
 			return nil, ErrNotFound
 		}
-		// err := fmt.Errorf("persistence layer error: %w", err)
-		// span.RecordError(err)
+		slog.ErrorContext(ctx, "failed to read record", "resource.name", resource, "record.id", id, "error", err)
 		return nil, err
 	}
 
-	rs, err := v1alpha1.ToResource(schemas, rec)
+	rs, err := v1alpha1.ProjectResource(layout, rec, p)
 	if err != nil {
-		// span.RecordError(err)
+		trace.SpanFromContext(ctx).RecordError(err)
 		return nil, err
 	}
 
 	return rs, nil
 }
 
-// TODO: traces
-func (s *Store) Create(ctx context.Context, resource string, newRes v1alpha1.Resource) (string, error) {
-	schemas := s.schemas[resource]
-	rw := s.rws[resource]
+// Create parses raw against the schema of resource and writes it as a new
+// record, returning its id.
+func (s *Store) Create(ctx context.Context, resource string, raw v1alpha1.Resource) (id string, err error) {
+	ctx, span := s.startSpan(ctx, "store.Create", resource)
+	defer func() {
+		span.SetAttributes(attribute.String("record.id", id))
+		endSpan(span, err)
+	}()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	schemas, ok := s.schemas[resource]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	rw, ok := s.rws[resource]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	newRes, err := v1alpha1.ParseResource(schemas, raw)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.view().checkRefs(ctx, schemas, newRes); err != nil {
+		return "", err
+	}
 
 	newId := GenerateId()
 
 	newRes["_id"] = newId
 	newRes["_v"] = 1.0
 
-	rec, err := v1alpha1.ToRecord(schemas, newRes)
+	rec, err := v1alpha1.ToRecord(s.layouts[resource], newRes)
 	if err != nil {
 		return "", err
 	}
 
-	return newId, rw.Create(ctx, rec)
+	if err := rw.Create(ctx, rec); err != nil {
+		slog.ErrorContext(ctx, "failed to create record", "resource.name", resource, "record.id", newId, "error", err)
+		return "", err
+	}
+
+	return newId, nil
+}
+
+// Update parses raw against the schema of resource and writes it over the
+// record at raw's _id, keeping what it leaves out that parsing does not
+// fill, like files. It returns the record as written.
+func (s *Store) Update(ctx context.Context, resource string, raw v1alpha1.Resource) (res v1alpha1.Resource, err error) {
+	id, _ := raw["_id"].(string)
+	ctx, span := s.startSpan(ctx, "store.Update", resource, attribute.String("record.id", id))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	schemas, ok := s.schemas[resource]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	updatedRes, err := v1alpha1.ParseResource(schemas, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedRes["_id"] = id
+
+	if err := s.view().checkRefs(ctx, schemas, updatedRes); err != nil {
+		return nil, err
+	}
+
+	if err := s.update(ctx, resource, updatedRes); err != nil {
+		return nil, err
+	}
+
+	return updatedRes, nil
 }
 
-// TODO: traces
-func (s *Store) Update(ctx context.Context, resource string, updatedRes v1alpha1.Resource) error {
+func (s *Store) update(ctx context.Context, resource string, updatedRes v1alpha1.Resource) error {
 	schemas := s.schemas[resource]
 	rw := s.rws[resource]
 
-	id := updatedRes["_id"].(string)
+	id, ok := updatedRes["_id"].(string)
+	if !ok {
+		return ErrNotFound
+	}
 
 	oldRes, err := s.readOne(ctx, resource, id)
 	if err != nil {
@@ -319,43 +607,249 @@ func (s *Store) Update(ctx context.Context, resource string, updatedRes v1alpha1
 
 	updatedRes["_v"] = v + 1
 
-	updatedRec, err := v1alpha1.ToRecord(schemas, updatedRes)
+	updatedRec, err := v1alpha1.ToRecord(s.layouts[resource], updatedRes)
 	if err != nil {
 		return err
 	}
 
-	return rw.Update(ctx, updatedRec)
+	if err := rw.Update(ctx, updatedRec); err != nil {
+		slog.ErrorContext(ctx, "failed to update record", "resource.name", resource, "record.id", id, "error", err)
+		return err
+	}
+
+	return nil
 }
 
-// TODO: traces
-func (s *Store) Delete(ctx context.Context, resource string, id string) error {
-	rw, ok := s.rws[resource]
-	if !ok {
+// Delete deletes the record at id of resource with what its delete
+// policies require, taking back what it wrote as writeAll does when a
+// write fails. u must be allowed to delete the records the delete cascades
+// to and to update those it sets null; the record itself is for the caller
+// to authorize.
+func (s *Store) Delete(ctx context.Context, resource string, id string, u v1alpha1.Resource) (err error) {
+	ctx, span := s.startSpan(ctx, "store.Delete", resource, attribute.String("record.id", id))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	if _, ok := s.rws[resource]; !ok {
 		return ErrNotFound
 	}
 
-	if err := rw.Delete(ctx, id); err != nil {
-		if errors.Is(err, writer.ErrNotFound) {
-			return ErrNotFound
-		}
+	b := &bulk{view: s.view()}
+
+	if err := b.stageDelete(ctx, 0, nil, recordKey{resource, id}, u); err != nil {
+		return err
+	}
+
+	if _, err := s.writeAll(ctx, b.batches()); err != nil {
 		return err
 	}
 
+	s.blobsMtx.Lock()
+	s.releaseBlobs(ctx, b.blobs(nil))
+	s.blobsMtx.Unlock()
+
+	return nil
+}
+
+// Expand replaces the ids held by the given ref fields with the records they
+// point at. References the user may not read, or that no longer resolve, are
+// left as ids.
+func (s *Store) Expand(ctx context.Context, resource string, rs []v1alpha1.Resource, fields []string, u v1alpha1.Resource) (err error) {
+	ctx, span := s.startSpan(ctx, "store.Expand", resource, attribute.StringSlice("fields", fields))
+	defer func() { endSpan(span, err) }()
+
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	targets := map[string]string{}
+
+	for _, field := range fields {
+		idx := slices.IndexFunc(s.schemas[resource], func(fs v1alpha1.FieldSchema) bool {
+			return fs.Field == field
+		})
+		if idx < 0 {
+			return fmt.Errorf("%w: unknown field %q", ErrRef, field)
+		}
+		target, ok := v1alpha1.RefResource(s.schemas[resource][idx])
+		if !ok {
+			return fmt.Errorf("%w: field %q is not a reference", ErrRef, field)
+		}
+		targets[field] = target
+	}
+
+	cache := map[recordKey]v1alpha1.Resource{}
+
+	for _, r := range rs {
+		for field, target := range targets {
+			id, ok := r[field].(string)
+			if !ok || len(id) == 0 {
+				continue
+			}
+
+			key := recordKey{target, id}
+
+			expanded, ok := cache[key]
+			if !ok {
+				var err error
+				expanded, err = s.expandOne(ctx, key, u)
+				if err != nil {
+					return err
+				}
+				cache[key] = expanded
+			}
+
+			if expanded != nil {
+				r[field] = expanded
+			}
+		}
+	}
+
 	return nil
 }
 
-func (s *Store) CheckHealth(ctx context.Context) error {
-	// TODO
+func (s *Store) expandOne(ctx context.Context, key recordKey, u v1alpha1.Resource) (v1alpha1.Resource, error) {
+	if err := s.authorize(ctx, key.resource, key.id, "read", u); err != nil {
+		if errors.Is(err, ErrAuthn) || errors.Is(err, ErrAuthz) || errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	res, err := s.readOne(ctx, key.resource, key.id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+
+	return res, err
+}
+
+// Stats returns what the read/writer of each resource holds, the schemas
+// included. Read/writers that cannot tell are left out; those that fail to
+// are left out too, and their errors joined.
+func (s *Store) Stats(ctx context.Context) (map[string]readwriter.Stats, error) {
+	s.schemasMtx.RLock()
+	defer s.schemasMtx.RUnlock()
+
+	rws := maps.Clone(s.rws)
+	if s.options.SchemaReadWriter != nil {
+		rws["_schemas"] = s.options.SchemaReadWriter
+	}
+
+	stats := map[string]readwriter.Stats{}
+	errs := []error{}
+
+	for resource, rw := range rws {
+		st, ok, err := readwriter.ReadStats(ctx, rw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read stats of %s: %w", resource, err))
+			continue
+		}
+		if ok {
+			stats[resource] = st
+		}
+	}
+
+	return stats, errors.Join(errs...)
+}
+
+// CheckLiveness checks that the store is running. It fails once the store
+// is stopped, since a stopped store cannot be started again.
+func (s *Store) CheckLiveness(ctx context.Context) v1alpha1.Health {
+	return v1alpha1.NewHealth(map[string]error{
+		"store": s.checkRunning(),
+	})
+}
+
+// CheckHealth checks that the store is running and that the read/writer of
+// every resource, the schemas' included, works. A schema change in
+// progress is waited for as long as ctx allows.
+func (s *Store) CheckHealth(ctx context.Context) v1alpha1.Health {
+	checks := map[string]error{
+		"store": s.checkRunning(),
+	}
+
+	if err := s.rlockSchemas(ctx); err != nil {
+		checks["store"] = errors.Join(checks["store"], fmt.Errorf("schemas are being changed: %w", err))
+		return v1alpha1.NewHealth(checks)
+	}
+	defer s.schemasMtx.RUnlock()
+
+	for resource, rw := range s.rws {
+		checks["readwriter."+resource] = readwriter.CheckHealth(ctx, rw)
+	}
+
+	if s.options.SchemaReadWriter != nil {
+		checks["readwriter._schemas"] = readwriter.CheckHealth(ctx, s.options.SchemaReadWriter)
+	}
+
+	return v1alpha1.NewHealth(checks)
+}
+
+func (s *Store) checkRunning() error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if !s.isRunning {
+		return errors.New("store not running")
+	}
+
+	return nil
+}
+
+// rlockSchemas takes the schemas lock for reading, unless ctx is done
+// first.
+func (s *Store) rlockSchemas(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !s.schemasMtx.TryRLock() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
 	return nil
 }
 
 func New(
 	schemas map[string][]v1alpha1.FieldSchema,
 	rws map[string]readwriter.ReadWriter,
+	opts ...Option,
 ) *Store {
+	if schemas == nil {
+		schemas = map[string][]v1alpha1.FieldSchema{}
+	}
+
+	if rws == nil {
+		rws = map[string]readwriter.ReadWriter{}
+	}
+
+	live := map[string][]v1alpha1.FieldSchema{}
+	for resource, layout := range schemas {
+		live[resource] = v1alpha1.LiveFields(layout)
+	}
+
+	traced := map[string]readwriter.ReadWriter{}
+	for resource, rw := range rws {
+		traced[resource] = readwriter.Traced(rw, resource)
+	}
+
+	options := NewOptions(opts...)
+
+	if options.SchemaReadWriter != nil {
+		options.SchemaReadWriter = readwriter.Traced(options.SchemaReadWriter, "_schemas")
+	}
+
 	return &Store{
-		schemas: schemas,
-		rws:     rws,
+		options: options,
+		schemas: live,
+		layouts: schemas,
+		rws:     traced,
+		tracer:  otel.Tracer(tracerName),
 		mtx:     sync.RWMutex{},
 	}
 }
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/w-h-a/backend/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/w-h-a/backend/internal/services/store"
+
+// startSpan starts a span for an operation on resource, or on no resource in
+// particular when it is empty. How long the operation took is recorded
+// when the span ends.
+func (s *Store) startSpan(ctx context.Context, name string, resource string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if len(resource) > 0 {
+		attrs = append(attrs, attribute.String("resource.name", resource))
+	}
+
+	ctx, span := s.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+
+	return ctx, &timedSpan{
+		Span:      span,
+		operation: strings.TrimPrefix(name, "store."),
+		start:     time.Now(),
+	}
+}
+
+// timedSpan records how long the operation it spans took, traced or not.
+type timedSpan struct {
+	trace.Span
+	operation string
+	start     time.Time
+}
+
+func (s *timedSpan) End(opts ...trace.SpanEndOption) {
+	metrics.ObserveStoreOperation(s.operation, time.Since(s.start))
+	s.Span.End(opts...)
+}
+
+// endSpan records err on span and ends it. Errors that answer the caller,
+// like a missing record or a denied request, are recorded without failing
+// the span.
+func endSpan(span trace.Span, err error) {
+	defer span.End()
+
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+
+	for _, answer := range []error{ErrNotFound, ErrAuthn, ErrAuthz, ErrRef, ErrConflict, ErrInvalid, ErrTooLarge} {
+		if errors.Is(err, answer) {
+			return
+		}
+	}
+
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -7,10 +7,25 @@ import (
 )
 
 const (
-	charset  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	idLength = 12
+	charset    = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	idLength   = 12
+	maxNearest = 1000
+	maxBulk    = 1000
+	maxGroups  = 10000
 )
 
+type recordKey struct {
+	resource string
+	id       string
+}
+
+type deletePlan struct {
+	deletes    []recordKey
+	nulls      map[recordKey][]string
+	restricted []recordKey
+	seen       map[recordKey]bool
+}
+
 var (
 	GenerateId = func() string {
 		bs := make([]byte, idLength)
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+// view reads the records of the store as the writes staged in pending
+// would leave them, so that the ops of a bulk write see those before them.
+// A nil record in pending is staged for deletion. Like the unexported
+// methods of Store, it never takes the schemas lock.
+type view struct {
+	s       *Store
+	pending map[recordKey]v1alpha1.Resource
+}
+
+// view reads the records of s as they are.
+func (s *Store) view() *view {
+	return &view{s: s, pending: map[recordKey]v1alpha1.Resource{}}
+}
+
+func (v *view) readOne(ctx context.Context, key recordKey) (v1alpha1.Resource, error) {
+	if res, ok := v.pending[key]; ok {
+		if res == nil {
+			return nil, ErrNotFound
+		}
+		return maps.Clone(res), nil
+	}
+
+	return v.s.readOne(ctx, key.resource, key.id)
+}
+
+func (v *view) list(ctx context.Context, resource string) ([]v1alpha1.Resource, error) {
+	rs, err := v.s.list(ctx, resource, "")
+	if err != nil || len(v.pending) == 0 {
+		return rs, err
+	}
+
+	listed := []v1alpha1.Resource{}
+	seen := map[string]bool{}
+
+	for _, r := range rs {
+		id, _ := r["_id"].(string)
+		seen[id] = true
+
+		if res, ok := v.pending[recordKey{resource, id}]; ok {
+			if res != nil {
+				listed = append(listed, res)
+			}
+			continue
+		}
+
+		listed = append(listed, r)
+	}
+
+	for key, res := range v.pending {
+		if key.resource == resource && !seen[key.id] && res != nil {
+			listed = append(listed, res)
+		}
+	}
+
+	return listed, nil
+}
+
+// checkRefs verifies that every ref field of res points at an existing
+// record.
+func (v *view) checkRefs(ctx context.Context, schemas []v1alpha1.FieldSchema, res v1alpha1.Resource) error {
+	for _, fs := range schemas {
+		target, ok := v1alpha1.RefResource(fs)
+		if !ok {
+			continue
+		}
+
+		id, ok := res[fs.Field].(string)
+		if !ok || len(id) == 0 {
+			continue
+		}
+
+		if _, err := v.readOne(ctx, recordKey{target, id}); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("%w: field %q points at missing %s %s", ErrRef, fs.Field, target, id)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planDelete walks every ref field pointing at key and records what the
+// delete policies require: cascaded deletes, fields to null out, and
+// references that restrict the delete.
+func (v *view) planDelete(ctx context.Context, key recordKey, plan *deletePlan) error {
+	if plan.seen[key] {
+		return nil
+	}
+
+	if _, err := v.readOne(ctx, key); err != nil {
+		return err
+	}
+
+	plan.seen[key] = true
+	plan.deletes = append(plan.deletes, key)
+
+	for resource, schemas := range v.s.schemas {
+		for _, fs := range schemas {
+			if target, ok := v1alpha1.RefResource(fs); !ok || target != key.resource {
+				continue
+			}
+
+			rs, err := v.list(ctx, resource)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range rs {
+				if r[fs.Field] != key.id {
+					continue
+				}
+
+				fromId, _ := r["_id"].(string)
+				from := recordKey{resource, fromId}
+
+				switch fs.OnDelete {
+				case v1alpha1.OnDeleteCascade:
+					if err := v.planDelete(ctx, from, plan); err != nil {
+						return err
+					}
+				case v1alpha1.OnDeleteSetNull:
+					plan.nulls[from] = append(plan.nulls[from], fs.Field)
+				default:
+					plan.restricted = append(plan.restricted, from)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package traces
+
+import (
+	"io"
+	"os"
+)
+
+type Option func(*Options)
+
+type Options struct {
+	Exporter string
+	// Endpoint is the URL of the OTLP collector. When it is empty, the
+	// exporter reads OTEL_EXPORTER_OTLP_ENDPOINT or uses its default.
+	Endpoint string
+	// File is where the file exporter appends spans, one JSON object per
+	// line.
+	File string
+	// Writer is where the stdout exporter writes.
+	Writer      io.Writer
+	SampleRatio float64
+	ServiceName string
+}
+
+func WithExporter(exporter string) Option {
+	return func(o *Options) {
+		o.Exporter = exporter
+	}
+}
+
+func WithEndpoint(endpoint string) Option {
+	return func(o *Options) {
+		o.Endpoint = endpoint
+	}
+}
+
+func WithFile(path string) Option {
+	return func(o *Options) {
+		o.File = path
+	}
+}
+
+func WithWriter(w io.Writer) Option {
+	return func(o *Options) {
+		o.Writer = w
+	}
+}
+
+// WithSampleRatio sets the share of traces that are recorded, from 0 to 1.
+// Traces started elsewhere keep the decision of their caller.
+func WithSampleRatio(ratio float64) Option {
+	return func(o *Options) {
+		o.SampleRatio = ratio
+	}
+}
+
+func WithServiceName(name string) Option {
+	return func(o *Options) {
+		o.ServiceName = name
+	}
+}
+
+func NewOptions(opts ...Option) Options {
+	options := Options{
+		Exporter:    ExporterNone,
+		Writer:      os.Stdout,
+		SampleRatio: 1,
+		ServiceName: "backend",
+	}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
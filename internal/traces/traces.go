@@ -0,0 +1,120 @@
+// Package traces sets up OpenTelemetry tracing for the server. Code that
+// traces takes its tracer from the global provider, so spans go wherever
+// Setup sends them, and nowhere before that.
+package traces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	ExporterNone     = "none"
+	ExporterOTLP     = "otlp"
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterStdout   = "stdout"
+	ExporterFile     = "file"
+)
+
+// Exporters are the values WithExporter takes.
+var Exporters = []string{ExporterNone, ExporterOTLP, ExporterOTLPHTTP, ExporterStdout, ExporterFile}
+
+// Setup installs a tracer provider exporting to the configured exporter,
+// and W3C trace context and baggage as the propagators. The returned
+// function flushes what is left and shuts the provider down.
+func Setup(ctx context.Context, opts ...Option) (func(context.Context) error, error) {
+	options := NewOptions(opts...)
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if options.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if options.SampleRatio < 0 || options.SampleRatio > 1 {
+		return nil, fmt.Errorf("sample ratio %v is not between 0 and 1", options.SampleRatio)
+	}
+
+	exporter, closer, err := newExporter(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", options.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// a sampled parent keeps its children, whatever the ratio
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+func newExporter(ctx context.Context, options Options) (sdktrace.SpanExporter, io.Closer, error) {
+	switch options.Exporter {
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{}
+		if len(options.Endpoint) > 0 {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(options.Endpoint))
+		}
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		return exporter, nil, err
+	case ExporterOTLPHTTP:
+		opts := []otlptracehttp.Option{}
+		if len(options.Endpoint) > 0 {
+			opts = append(opts, otlptracehttp.WithEndpointURL(options.Endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		return exporter, nil, err
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(options.Writer))
+		return exporter, nil, err
+	case ExporterFile:
+		if len(options.File) == 0 {
+			return nil, nil, errors.New("the file exporter needs a file")
+		}
+		f, err := os.OpenFile(options.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		// one span per line, so the file can be read as JSON lines
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			return nil, nil, errors.Join(err, f.Close())
+		}
+		return exporter, f, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown trace exporter %q", options.Exporter)
+	}
+}
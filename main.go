@@ -1,27 +1,91 @@
 package main
 
 import (
+	"log/slog"
 	"os"
 
 	"github.com/urfave/cli/v2"
 	"github.com/w-h-a/backend/cmd"
 )
 
+var codegenFlags = []cli.Flag{
+	&cli.StringFlag{Name: "out", Usage: "directory to write to", Required: true},
+	&cli.StringFlag{Name: "schemas", Usage: "path to _schemas.csv or schema file"},
+}
+
 func main() {
 	app := &cli.App{
 		Name: "backend",
 		Commands: []*cli.Command{
 			{
-				Name: "backend",
+				Name:  "backend",
+				Flags: cmd.ConfigFlags(),
 				Action: func(ctx *cli.Context) error {
 					return cmd.Run(ctx)
 				},
 			},
+			{
+				Name: "config",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "print",
+						Flags: cmd.ConfigFlags(),
+						Action: func(ctx *cli.Context) error {
+							return cmd.PrintConfig(ctx)
+						},
+					},
+				},
+			},
+			{
+				Name: "schema",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "lint",
+						ArgsUsage: "[path to _schemas.csv or schema file]",
+						Action: func(ctx *cli.Context) error {
+							return cmd.LintSchemas(ctx)
+						},
+					},
+					{
+						Name:      "convert",
+						ArgsUsage: "SRC DST",
+						Action: func(ctx *cli.Context) error {
+							return cmd.ConvertSchemas(ctx)
+						},
+					},
+				},
+			},
+			{
+				Name: "codegen",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "go",
+						Flags: append(codegenFlags, &cli.StringFlag{Name: "package", Usage: "name of the generated package", Value: "client"}),
+						Action: func(ctx *cli.Context) error {
+							return cmd.Codegen(ctx, "go")
+						},
+					},
+					{
+						Name:  "ts",
+						Flags: codegenFlags,
+						Action: func(ctx *cli.Context) error {
+							return cmd.Codegen(ctx, "ts")
+						},
+					},
+				},
+			},
+			{
+				Name:  "migrate",
+				Flags: cmd.ConfigFlags(),
+				Action: func(ctx *cli.Context) error {
+					return cmd.Migrate(ctx)
+				},
+			},
 		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		// log
+		slog.Error("command failed", "error", err)
 		os.Exit(1)
 	}
 }
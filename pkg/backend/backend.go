@@ -0,0 +1,407 @@
+// Package backend embeds the backend in another Go program. New loads a
+// project directory, Store reads and writes its records, and Handler serves
+// the HTTP API on whatever mux the program already has:
+//
+//	b, err := backend.New(backend.Config{Dir: "data"})
+//	if err != nil {
+//		return err
+//	}
+//	if err := b.Start(ctx); err != nil {
+//		return err
+//	}
+//	defer b.Stop(ctx)
+//
+//	mux.Handle("/api/", b.Handler())
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/w-h-a/backend/api/v1alpha1/recordspb"
+	"github.com/w-h-a/backend/internal/clients/blob"
+	"github.com/w-h-a/backend/internal/clients/blob/local"
+	grpchandlers "github.com/w-h-a/backend/internal/handlers/grpc"
+	httphandlers "github.com/w-h-a/backend/internal/handlers/http"
+	"github.com/w-h-a/backend/internal/ratelimit"
+	"github.com/w-h-a/backend/internal/servers"
+	grpcserver "github.com/w-h-a/backend/internal/servers/grpc"
+	httpserver "github.com/w-h-a/backend/internal/servers/http"
+	"github.com/w-h-a/backend/internal/services/store"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type Config struct {
+	// Dir holds the schemas, as _schemas.csv or a schema file, along with
+	// uploaded files and, by default, the records.
+	Dir string
+	// Addr is where Run serves the HTTP API. Run serves no HTTP when it is
+	// empty.
+	Addr string
+	// GRPCAddr is where Run serves gRPC, the standard health service
+	// included. Run serves no gRPC when it is empty.
+	GRPCAddr string
+	// TLSCertFile and TLSKeyFile make Run serve over TLS when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// ReadTimeout, WriteTimeout and IdleTimeout bound HTTP requests and
+	// connections. Zero means no limit.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout is how long Run waits for requests in flight when it
+	// stops. Zero means 10 seconds.
+	ShutdownTimeout time.Duration
+	// CORSOrigins are the origins browsers may call the HTTP API from, "*"
+	// standing for any. Browsers may call it from no other origin when it
+	// is empty. CORSCredentials lets them send credentials along.
+	CORSOrigins     []string
+	CORSCredentials bool
+	// MaxBodyBytes bounds request bodies, except file uploads, which their
+	// field bounds. Zero means no limit.
+	MaxBodyBytes int64
+	// RequestTimeout bounds how long the handling of a request may take,
+	// except for file uploads and downloads. RouteTimeouts set it by route
+	// template, like /api/{resource}/_knn, files included. Zero means no
+	// limit.
+	RequestTimeout time.Duration
+	RouteTimeouts  map[string]time.Duration
+	// RateLimit bounds how often a user, or an address that does not
+	// authenticate, may call a route of the HTTP API, and how often an
+	// address may call a gRPC method. RateLimits set it for the HTTP API by
+	// RESOURCE:ACTION, like notes:create, either part * for any, where
+	// bulk is the action of /api/{resource}/_bulk. The zero RateLimit means
+	// no limit.
+	RateLimit  RateLimit
+	RateLimits map[string]RateLimit
+	// LoginAttempts is how many failed logins in a row lock out a username,
+	// and the address they came from, for LoginLockout, which doubles with
+	// every failure after that, up to an hour. Zero LoginAttempts means no
+	// lockout, and zero LoginLockout 30 seconds.
+	LoginAttempts int
+	LoginLockout  time.Duration
+}
+
+type Backend struct {
+	config  Config
+	options Options
+	store   *store.Store
+	handler http.Handler
+	// lockout is shared by HTTP and gRPC logins, nil for none
+	lockout *ratelimit.Lockout
+}
+
+// Store is the store behind the HTTP API. It does not check permissions;
+// callers that act for a user call Authorize first.
+func (b *Backend) Store() Store {
+	return b.store
+}
+
+// Handler serves the HTTP API, authentication, CORS, request ids, rate
+// limits, access logs, traces, Prometheus metrics at /metrics and health
+// checks at /healthz and /readyz included. A handler that panics answers
+// 500. Logs go to the default slog logger and spans to the global
+// OpenTelemetry tracer provider. Its routes are absolute, so it is mounted
+// at the root of a mux or behind http.StripPrefix.
+func (b *Backend) Handler() http.Handler {
+	return b.handler
+}
+
+// Start starts the store and runs the start hooks. Programs that serve
+// Handler themselves call Start and Stop around it.
+func (b *Backend) Start(ctx context.Context) error {
+	if err := b.store.Start(); err != nil {
+		return err
+	}
+
+	for _, hook := range b.options.OnStart {
+		if err := hook(ctx, b); err != nil {
+			return errors.Join(fmt.Errorf("start hook failed: %w", err), b.store.Stop())
+		}
+	}
+
+	return nil
+}
+
+// Stop runs the stop hooks and stops the store, even when a hook fails.
+func (b *Backend) Stop(ctx context.Context) error {
+	errs := []error{}
+
+	for i := len(b.options.OnStop) - 1; i >= 0; i-- {
+		if err := b.options.OnStop[i](ctx, b); err != nil {
+			errs = append(errs, fmt.Errorf("stop hook failed: %w", err))
+		}
+	}
+
+	if err := b.store.Stop(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run starts the backend, serves the HTTP API on Config.Addr and gRPC on
+// Config.GRPCAddr until ctx is done or serving fails, and stops it again.
+func (b *Backend) Run(ctx context.Context) error {
+	srvs, err := b.servers()
+	if err != nil {
+		return err
+	}
+
+	if err := b.Start(ctx); err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	errCh := make(chan error, len(srvs))
+
+	var wg sync.WaitGroup
+
+	for _, srv := range srvs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- srv.Run(stop)
+		}()
+	}
+
+	// one server failing stops the others
+	var runErr error
+
+	select {
+	case runErr = <-errCh:
+	case <-ctx.Done():
+	}
+
+	close(stop)
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		runErr = errors.Join(runErr, err)
+	}
+
+	// ctx is done by now, and the stop hooks still need one
+	return errors.Join(runErr, b.Stop(context.WithoutCancel(ctx)))
+}
+
+func (b *Backend) servers() ([]servers.Server, error) {
+	opts := []servers.Option{}
+
+	if b.config.ShutdownTimeout > 0 {
+		opts = append(opts, servers.WithShutdownTimeout(b.config.ShutdownTimeout))
+	}
+
+	useTLS := len(b.config.TLSCertFile) > 0 && len(b.config.TLSKeyFile) > 0
+
+	srvs := []servers.Server{}
+
+	if len(b.config.Addr) > 0 {
+		httpOpts := append(slices.Clone(opts),
+			servers.WithAddress(b.config.Addr),
+			httpserver.WithTimeouts(b.config.ReadTimeout, b.config.WriteTimeout, b.config.IdleTimeout),
+		)
+		if useTLS {
+			httpOpts = append(httpOpts, httpserver.WithTLS(b.config.TLSCertFile, b.config.TLSKeyFile))
+		}
+
+		srv := httpserver.NewServer(httpOpts...)
+
+		if err := srv.Handle(b.handler); err != nil {
+			return nil, fmt.Errorf("failed to attach root handler: %w", err)
+		}
+
+		srvs = append(srvs, srv)
+	}
+
+	if len(b.config.GRPCAddr) > 0 {
+		unary := []grpc.UnaryServerInterceptor{
+			grpchandlers.RequestIDUnaryInterceptor(),
+			grpchandlers.RecoverUnaryInterceptor(),
+		}
+		stream := []grpc.StreamServerInterceptor{
+			grpchandlers.RequestIDStreamInterceptor(),
+			grpchandlers.RecoverStreamInterceptor(),
+		}
+
+		if !b.config.RateLimit.IsZero() {
+			limiter := ratelimit.NewLimiter()
+			unary = append(unary, grpchandlers.RateLimitUnaryInterceptor(limiter, b.config.RateLimit))
+			stream = append(stream, grpchandlers.RateLimitStreamInterceptor(limiter, b.config.RateLimit))
+		}
+
+		grpcOpts := append(slices.Clone(opts),
+			servers.WithAddress(b.config.GRPCAddr),
+			grpcserver.WithUnaryInterceptors(unary...),
+			grpcserver.WithStreamInterceptors(stream...),
+		)
+		if useTLS {
+			grpcOpts = append(grpcOpts, grpcserver.WithTLS(b.config.TLSCertFile, b.config.TLSKeyFile))
+		}
+
+		srv := grpcserver.NewServer(grpcOpts...)
+
+		if err := srv.Handle(grpcserver.GrpcServiceRegistration{
+			Desc: &healthpb.Health_ServiceDesc,
+			Impl: grpchandlers.NewHealthHandler(b.store),
+		}); err != nil {
+			return nil, fmt.Errorf("failed to attach health service: %w", err)
+		}
+
+		recordsOpts := []grpchandlers.RecordsOption{}
+		if b.lockout != nil {
+			recordsOpts = append(recordsOpts, grpchandlers.WithLockout(b.lockout))
+		}
+
+		if err := srv.Handle(grpcserver.GrpcServiceRegistration{
+			Desc: &recordspb.Records_ServiceDesc,
+			Impl: grpchandlers.NewRecordsHandler(b.store, recordsOpts...),
+		}); err != nil {
+			return nil, fmt.Errorf("failed to attach records service: %w", err)
+		}
+
+		srvs = append(srvs, srv)
+	}
+
+	return srvs, nil
+}
+
+// New loads the project in config.Dir. Schemas with errors are refused.
+func New(config Config, opts ...Option) (*Backend, error) {
+	options := NewOptions(opts...)
+
+	if options.Factory == nil {
+		options.Factory = CSVReadWriterFactory(config.Dir)
+	}
+
+	if err := checkSchemas(SchemaPath(config.Dir)); err != nil {
+		return nil, err
+	}
+
+	schemas, rws, schemaRW, err := loadReadWriters(config.Dir, options.Factory)
+	if err != nil {
+		return nil, err
+	}
+
+	b := local.NewBlob(
+		blob.WithLocation(filepath.Join(config.Dir, "_files")),
+	)
+
+	s := store.New(
+		schemas,
+		rws,
+		store.WithBlob(b),
+		store.WithSchemaReadWriter(schemaRW),
+		store.WithReadWriterFactory(options.Factory),
+	)
+
+	lockout := newLockout(config)
+
+	handler, err := newHandler(config, s, lockout)
+	if err != nil {
+		// the store never ran, so it is left to close what was loaded
+		errs := []error{err}
+		for _, rw := range rws {
+			errs = append(errs, rw.Close(context.Background()))
+		}
+		if schemaRW != nil {
+			errs = append(errs, schemaRW.Close(context.Background()))
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	return &Backend{
+		config:  config,
+		options: options,
+		store:   s,
+		handler: handler,
+		lockout: lockout,
+	}, nil
+}
+
+// newHandler puts the middleware in front of the router, the first one
+// outermost.
+// newLockout locks out logins after config.LoginAttempts failures, or is
+// nil when they are not limited.
+func newLockout(config Config) *ratelimit.Lockout {
+	if config.LoginAttempts <= 0 {
+		return nil
+	}
+
+	opts := []ratelimit.Option{ratelimit.WithThreshold(config.LoginAttempts)}
+	if config.LoginLockout > 0 {
+		opts = append(opts, ratelimit.WithLockout(config.LoginLockout))
+	}
+
+	return ratelimit.NewLockout(opts...)
+}
+
+func newHandler(config Config, s *store.Store, lockout *ratelimit.Lockout) (http.Handler, error) {
+	for _, key := range slices.Sorted(maps.Keys(config.RateLimits)) {
+		resource, action, ok := strings.Cut(key, ":")
+		if !ok || len(resource) == 0 || !slices.Contains([]string{"*", "read", "create", "update", "delete", "bulk"}, action) {
+			return nil, fmt.Errorf("rate limit set for %q, which is not RESOURCE:ACTION", key)
+		}
+	}
+
+	authOpts := []httphandlers.AuthOption{}
+
+	if lockout != nil {
+		authOpts = append(authOpts, httphandlers.WithLockout(lockout))
+	}
+
+	middleware := []httpserver.Middleware{
+		httphandlers.NewTraceMiddleware(),
+		httphandlers.NewRequestIDMiddleware(),
+		httphandlers.NewLogMiddleware(),
+		httphandlers.NewRecoverMiddleware(),
+		httphandlers.NewMetricsMiddleware(),
+		httphandlers.NewCORSMiddleware(
+			httphandlers.WithCORSOrigins(config.CORSOrigins...),
+			httphandlers.WithCORSCredentials(config.CORSCredentials),
+		),
+		httphandlers.NewAuthMiddleware(s, authOpts...),
+	}
+
+	router := httphandlers.NewRouter(s,
+		httphandlers.WithMaxBodyBytes(config.MaxBodyBytes),
+		httphandlers.WithTimeout(config.RequestTimeout),
+		httphandlers.WithRouteTimeouts(config.RouteTimeouts),
+		httphandlers.WithRateLimit(config.RateLimit),
+		httphandlers.WithRateLimits(config.RateLimits),
+	)
+
+	// a timeout for a route that does not exist is a typo
+	templates := []string{}
+	if err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		templates = append(templates, tmpl)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, tmpl := range slices.Sorted(maps.Keys(config.RouteTimeouts)) {
+		if !slices.Contains(templates, tmpl) {
+			return nil, fmt.Errorf("timeout set for unknown route %q", tmpl)
+		}
+	}
+
+	var handler http.Handler = router
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler, nil
+}
@@ -0,0 +1,161 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/readwriter/csv"
+	"github.com/w-h-a/backend/internal/clients/readwriter/memory"
+)
+
+// SchemaFileNames are the schema files a project directory may have in
+// place of _schemas.csv, in the order they are looked for.
+var SchemaFileNames = []string{"schema.yaml", "schema.yml", "schema.json"}
+
+// SchemaPath is the file the schemas of dir are loaded from: a schema file
+// when there is one, and _schemas.csv otherwise.
+func SchemaPath(dir string) string {
+	for _, name := range SchemaFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, "_schemas.csv")
+}
+
+// LintSchemaPath reports every problem of the _schemas.csv or schema file
+// at path.
+func LintSchemaPath(path string) ([]v1alpha1.SchemaProblem, error) {
+	if filepath.Ext(path) != ".csv" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return v1alpha1.LintSchemaFile(data)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return v1alpha1.LintSchemas(f)
+}
+
+// checkSchemas refuses schemas with errors, listing all of them, and logs
+// the warnings.
+func checkSchemas(path string) error {
+	problems, err := LintSchemaPath(path)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, p := range problems {
+		if p.Severity == v1alpha1.SeverityError {
+			errs = append(errs, fmt.Errorf("%s: %s", path, p))
+		} else {
+			slog.Warn("schema problem", "path", path, "problem", p.String())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// loadReadWriters opens every resource of dir. Schemas loaded from a
+// schema file cannot be changed at runtime, so there is no schema
+// ReadWriter for them.
+func loadReadWriters(dir string, factory ReadWriterFactory) (map[string][]FieldSchema, map[string]ReadWriter, ReadWriter, error) {
+	path := SchemaPath(dir)
+
+	if filepath.Ext(path) != ".csv" {
+		schemas, rws, err := loadFileReadWriters(path, factory)
+		return schemas, rws, nil, err
+	}
+
+	schemas := map[string][]FieldSchema{}
+
+	schemaRW := csv.NewReadWriter(
+		readwriter.WithLocation(path),
+		readwriter.WithFieldSchemas(v1alpha1.SchemaFields),
+	)
+
+	recs, err := schemaRW.List(context.Background())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, rec := range recs {
+		schema := v1alpha1.ToFieldSchema(rec)
+
+		schemas[schema.Resource] = append(schemas[schema.Resource], schema)
+	}
+
+	rws := map[string]ReadWriter{}
+
+	for name, fields := range schemas {
+		rw, err := factory(name, fields)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rws[name] = rw
+	}
+
+	return schemas, rws, schemaRW, nil
+}
+
+// loadFileReadWriters is loadReadWriters for a schema file. Permissions the
+// file lists are kept in memory; without any, _permissions is opened like
+// any other resource.
+func loadFileReadWriters(path string, factory ReadWriterFactory) (map[string][]FieldSchema, map[string]ReadWriter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := v1alpha1.ParseSchemaFile(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	schemas := file.FieldSchemas()
+
+	rws := map[string]ReadWriter{}
+
+	for name, fields := range schemas {
+		if name == "_permissions" && len(file.Permissions) > 0 {
+			rw := memory.NewReadWriter(
+				readwriter.WithFieldSchemas(fields),
+			)
+
+			for _, rec := range file.PermissionRecords() {
+				// the file may give _permissions more fields than a row has
+				for len(rec) < len(fields) {
+					rec = append(rec, "")
+				}
+				if err := rw.Create(context.Background(), rec); err != nil {
+					return nil, nil, fmt.Errorf("%s: permission %s: %w", path, rec[0], err)
+				}
+			}
+
+			rws[name] = rw
+			continue
+		}
+
+		rw, err := factory(name, fields)
+		if err != nil {
+			return nil, nil, err
+		}
+		rws[name] = rw
+	}
+
+	return schemas, rws, nil
+}
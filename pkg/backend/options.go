@@ -0,0 +1,48 @@
+package backend
+
+import "context"
+
+// Hook runs when a backend starts or stops.
+type Hook func(ctx context.Context, b *Backend) error
+
+type Option func(*Options)
+
+type Options struct {
+	Factory ReadWriterFactory
+	OnStart []Hook
+	OnStop  []Hook
+}
+
+// WithReadWriterFactory sets how the records of a resource are stored. By
+// default each resource is a CSV file in the project directory.
+func WithReadWriterFactory(fn ReadWriterFactory) Option {
+	return func(o *Options) {
+		o.Factory = fn
+	}
+}
+
+// OnStart adds a hook that runs after the store has started and before the
+// HTTP API is served. A failing hook stops the backend from starting.
+func OnStart(fn Hook) Option {
+	return func(o *Options) {
+		o.OnStart = append(o.OnStart, fn)
+	}
+}
+
+// OnStop adds a hook that runs after the HTTP API has stopped and before
+// the store stops. Hooks run in the reverse order they were added.
+func OnStop(fn Hook) Option {
+	return func(o *Options) {
+		o.OnStop = append(o.OnStop, fn)
+	}
+}
+
+func NewOptions(opts ...Option) Options {
+	options := Options{}
+
+	for _, fn := range opts {
+		fn(&options)
+	}
+
+	return options
+}
@@ -0,0 +1,31 @@
+package backend
+
+import (
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/readwriter/csv"
+	"github.com/w-h-a/backend/internal/clients/readwriter/memory"
+)
+
+// NewCSVReadWriter keeps records in the CSV file at path, as the backend
+// does by default.
+func NewCSVReadWriter(path string, fields []FieldSchema) ReadWriter {
+	return csv.NewReadWriter(
+		readwriter.WithLocation(path),
+		readwriter.WithFieldSchemas(fields),
+	)
+}
+
+// NewMemoryReadWriter keeps records in memory only, so they are gone when
+// the program exits.
+func NewMemoryReadWriter(fields []FieldSchema) ReadWriter {
+	return memory.NewReadWriter(
+		readwriter.WithFieldSchemas(fields),
+	)
+}
+
+// CSVReadWriterFactory stores each resource in a CSV file in dir.
+func CSVReadWriterFactory(dir string) ReadWriterFactory {
+	return func(resource string, fields []FieldSchema) (ReadWriter, error) {
+		return NewCSVReadWriter(dir+"/"+resource+".csv", fields), nil
+	}
+}
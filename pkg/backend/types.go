@@ -0,0 +1,121 @@
+package backend
+
+import (
+	"context"
+	"io"
+
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/reader"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/writer"
+	"github.com/w-h-a/backend/internal/ratelimit"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+// The types a ReadWriter works with. They are aliases, so values pass
+// between this package and the backend as they are.
+type (
+	Record      = v1alpha1.Record
+	Resource    = v1alpha1.Resource
+	FieldSchema = v1alpha1.FieldSchema
+
+	ReadWriter = readwriter.ReadWriter
+
+	ListOption     = reader.ListOption
+	ListOptions    = reader.ListOptions
+	ReadOneOption  = reader.ReadOneOption
+	ReadOneOptions = reader.ReadOneOptions
+	GeoQuery       = reader.GeoQuery
+	VectorQuery    = reader.VectorQuery
+	Projection     = v1alpha1.Projection
+
+	WriteOption   = writer.WriteOption
+	WriteOptions  = writer.WriteOptions
+	UpdateOption  = writer.UpdateOption
+	UpdateOptions = writer.UpdateOptions
+	DeleteOption  = writer.DeleteOption
+	DeleteOptions = writer.DeleteOptions
+
+	// ReadWriterFactory opens the ReadWriter of a resource. It is called
+	// for every resource at startup and again whenever a schema change
+	// creates or reshapes one.
+	ReadWriterFactory = store.ReadWriterFactory
+
+	// BulkResult is what became of one op of a bulk write.
+	BulkResult = store.BulkResult
+
+	// RateLimit lets Count requests through per Per, all at once or spread
+	// out.
+	RateLimit = ratelimit.Limit
+)
+
+// ParseRateLimit reads a RateLimit like 10/s, 100/m or 1000/h, or 0 for
+// none.
+var ParseRateLimit = ratelimit.ParseLimit
+
+// ParseProjection reads a Projection like title,year, or -body to leave
+// body out.
+var ParseProjection = v1alpha1.ParseProjection
+
+// ReadWriters read their options with these.
+var (
+	NewListOptions    = reader.NewListOptions
+	NewReadOneOptions = reader.NewReadOneOptions
+	NewWriteOptions   = writer.NewWriteOptions
+	NewUpdateOptions  = writer.NewUpdateOptions
+	NewDeleteOptions  = writer.NewDeleteOptions
+)
+
+var (
+	// ErrReaderNotFound is what a ReadWriter returns from ReadOne for a
+	// record it does not have, and ErrWriterNotFound from Update and Delete.
+	ErrReaderNotFound = reader.ErrNotFound
+	ErrWriterNotFound = writer.ErrNotFound
+
+	// The errors of Store.
+	ErrNotFound = store.ErrNotFound
+	ErrAuthn    = store.ErrAuthn
+	ErrAuthz    = store.ErrAuthz
+	ErrRef      = store.ErrRef
+	ErrConflict = store.ErrConflict
+	ErrInvalid  = store.ErrInvalid
+	ErrTooLarge = store.ErrTooLarge
+	ErrAborted  = store.ErrAborted
+)
+
+// Store reads and writes the records of every resource, checks who may do
+// so, and changes schemas.
+type Store interface {
+	Authenticate(ctx context.Context, username string, password string) (Resource, error)
+	Authorize(ctx context.Context, resource string, id string, action string, u Resource) error
+
+	List(ctx context.Context, resource string, sortBy string, opts ...ListOption) ([]Resource, error)
+	Nearest(ctx context.Context, resource string, q VectorQuery) ([]v1alpha1.Neighbor, error)
+	ReadOne(ctx context.Context, resource string, id string, opts ...ReadOneOption) (Resource, error)
+	Create(ctx context.Context, resource string, raw Resource) (string, error)
+	Update(ctx context.Context, resource string, raw Resource) (Resource, error)
+	Delete(ctx context.Context, resource string, id string, u Resource) error
+	Expand(ctx context.Context, resource string, rs []Resource, fields []string, u Resource) error
+	Bulk(ctx context.Context, resource string, ops []v1alpha1.BulkOp, atomic bool, u Resource) ([]BulkResult, error)
+	Aggregate(ctx context.Context, resource string, agg v1alpha1.Aggregate, u Resource, opts ...ListOption) ([]v1alpha1.AggregateRow, error)
+
+	PutFile(ctx context.Context, resource string, id string, field string, name string, r io.Reader) (v1alpha1.File, error)
+	GetFile(ctx context.Context, resource string, id string, field string) (v1alpha1.File, io.ReadCloser, error)
+
+	Schemas(ctx context.Context) map[string][]FieldSchema
+	Schema(ctx context.Context, resource string) ([]FieldSchema, error)
+	SchemaVersion(ctx context.Context, resource string) (int, error)
+	Resources(ctx context.Context, u Resource) ([]v1alpha1.ResourceMeta, error)
+	Resource(ctx context.Context, resource string, u Resource) (v1alpha1.ResourceMeta, error)
+
+	CreateResource(ctx context.Context, resource string, fields []FieldSchema) ([]FieldSchema, error)
+	AddField(ctx context.Context, resource string, fs FieldSchema) ([]FieldSchema, error)
+	AlterField(ctx context.Context, resource string, field string, fs FieldSchema) ([]FieldSchema, error)
+	RemoveField(ctx context.Context, resource string, field string) ([]FieldSchema, error)
+	Migrate(ctx context.Context) (map[string]int, error)
+
+	CheckLiveness(ctx context.Context) v1alpha1.Health
+	CheckHealth(ctx context.Context) v1alpha1.Health
+}
+
+var _ Store = (*store.Store)(nil)
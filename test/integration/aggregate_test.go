@@ -0,0 +1,186 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/services/store"
+	"github.com/w-h-a/backend/pkg/backend"
+)
+
+func TestStoreAggregateWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/aggregate")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+
+	admin, err := s.Authenticate(ctx, "admin", "admin123")
+	require.NoError(t, err)
+
+	bob, err := s.Authenticate(ctx, "bob", "bobpass")
+	require.NoError(t, err)
+
+	alice, err := s.Authenticate(ctx, "alice", "alicepass")
+	require.NoError(t, err)
+
+	// the admin role reads every book; books without a genre group as null, last
+	rows, err := s.Aggregate(ctx, "books", v1alpha1.Aggregate{
+		GroupBy: []string{"genre"},
+		Count:   true,
+		Sum:     []string{"pages"},
+		Avg:     []string{"year"},
+		Min:     []string{"title"},
+		Max:     []string{"year"},
+	}, admin)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	require.Equal(t, map[string]any{"genre": "romance"}, rows[0].Group)
+	require.Equal(t, 1, *rows[0].Count)
+	require.Equal(t, map[string]float64{"pages": 249}, rows[0].Sum)
+	require.Equal(t, map[string]any{"year": 1817.0}, rows[0].Avg)
+	require.Equal(t, map[string]any{"title": "Persuasion"}, rows[0].Min)
+
+	require.Equal(t, map[string]any{"genre": "scifi"}, rows[1].Group)
+	require.Equal(t, 3, *rows[1].Count)
+	require.Equal(t, map[string]float64{"pages": 1165}, rows[1].Sum)
+	require.InDelta(t, 1979.33, rows[1].Avg["year"], 0.01)
+	require.Equal(t, map[string]any{"title": "Dune"}, rows[1].Min)
+	require.Equal(t, map[string]any{"year": 1989.0}, rows[1].Max)
+
+	require.Equal(t, map[string]any{"genre": nil}, rows[2].Group)
+	require.Equal(t, 1, *rows[2].Count)
+	require.Equal(t, map[string]any{"title": "Emma"}, rows[2].Min)
+
+	// others only count the books they own or share
+	total := v1alpha1.Aggregate{Count: true, Sum: []string{"pages"}}
+
+	rows, err = s.Aggregate(ctx, "books", total, bob)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Nil(t, rows[0].Group)
+	require.Equal(t, 4, *rows[0].Count)
+	require.Equal(t, map[string]float64{"pages": 1406}, rows[0].Sum)
+
+	rows, err = s.Aggregate(ctx, "books", total, alice)
+	require.NoError(t, err)
+	require.Equal(t, 3, *rows[0].Count)
+	require.Equal(t, map[string]float64{"pages": 1143}, rows[0].Sum)
+
+	// a group of no books is not a row, but a total over none is
+	rows, err = s.Aggregate(ctx, "books", v1alpha1.Aggregate{GroupBy: []string{"genre"}, Count: true, Avg: []string{"year"}, Min: []string{"year"}}, alice)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	mallory := v1alpha1.Resource{"_id": "mallory"}
+
+	rows, err = s.Aggregate(ctx, "books", v1alpha1.Aggregate{Count: true, Avg: []string{"year"}, Min: []string{"year"}}, mallory)
+	require.NoError(t, err)
+	require.Equal(t, []v1alpha1.AggregateRow{{Count: new(int), Avg: map[string]any{"year": nil}, Min: map[string]any{"year": nil}}}, rows)
+
+	// the rows counted are those the user may read one by one
+	books, err := s.List(ctx, "books", "")
+	require.NoError(t, err)
+
+	for _, u := range []v1alpha1.Resource{admin, bob, alice, mallory} {
+		count, pages := 0, 0.0
+		for _, book := range books {
+			if s.Authorize(ctx, "books", book["_id"].(string), "read", u) == nil {
+				count++
+				pages += book["pages"].(float64)
+			}
+		}
+
+		rows, err = s.Aggregate(ctx, "books", total, u)
+		require.NoError(t, err)
+		require.Equal(t, count, *rows[0].Count, u["_id"])
+		require.Equal(t, pages, rows[0].Sum["pages"], u["_id"])
+	}
+
+	_, err = s.Aggregate(ctx, "books", total, nil)
+	require.ErrorIs(t, err, store.ErrAuthn)
+
+	_, err = s.Aggregate(ctx, "books", v1alpha1.Aggregate{Sum: []string{"title"}}, admin)
+	require.ErrorIs(t, err, store.ErrInvalid)
+
+	_, err = s.Aggregate(ctx, "authors", total, admin)
+	require.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestAggregateWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	b, err := backend.New(backend.Config{
+		Dir: testData(t, "../testdata/rest"),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+	defer b.Stop(context.Background())
+
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	aggregate := func(query string) (int, []v1alpha1.AggregateRow) {
+		rsp, err := http.Get(srv.URL + "/api/books/_aggregate?" + query)
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+
+		if rsp.StatusCode != http.StatusOK {
+			return rsp.StatusCode, nil
+		}
+
+		var rows []v1alpha1.AggregateRow
+		require.NoError(t, json.NewDecoder(rsp.Body).Decode(&rows))
+		return rsp.StatusCode, rows
+	}
+
+	status, rows := aggregate("count&sum=year&avg=year&min=year&max=year")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, rows, 1)
+	require.Equal(t, 2, *rows[0].Count)
+	require.Equal(t, map[string]float64{"year": 3964}, rows[0].Sum)
+	require.Equal(t, map[string]any{"year": 1982.0}, rows[0].Avg)
+	require.Equal(t, map[string]any{"year": 1949.0}, rows[0].Min)
+	require.Equal(t, map[string]any{"year": 2015.0}, rows[0].Max)
+
+	status, rows = aggregate("group_by=author&count&max=title&max=year")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, rows, 2)
+	require.Equal(t, map[string]any{"author": "Brian Kernighan"}, rows[0].Group)
+	require.Equal(t, 1, *rows[0].Count)
+	require.Equal(t, map[string]any{"title": "The Go Programming Language", "year": 2015.0}, rows[0].Max)
+	require.Equal(t, map[string]any{"author": "George Orwell"}, rows[1].Group)
+	require.Equal(t, map[string]any{"title": "1984", "year": 1949.0}, rows[1].Max)
+
+	// without count, rows carry no count
+	status, rows = aggregate("sum=year&count=false")
+	require.Equal(t, http.StatusOK, status)
+	require.Nil(t, rows[0].Count)
+
+	for _, query := range []string{"", "group_by=author", "sum=title", "group_by=tags&count", "count=maybe", "count&near=1,2&radius=3"} {
+		status, _ = aggregate(query)
+		require.Equal(t, http.StatusBadRequest, status, query)
+	}
+
+	rsp, err := http.Get(srv.URL + "/api/authors/_aggregate?count")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+}
@@ -0,0 +1,252 @@
+package integration
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/pkg/backend"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestEmbeddedBackendWithMemoryRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	dir := testData(t, "../testdata/admin")
+
+	stopped := false
+
+	b, err := backend.New(
+		backend.Config{Dir: dir},
+		backend.WithReadWriterFactory(func(resource string, fields []backend.FieldSchema) (backend.ReadWriter, error) {
+			if resource == "notes" {
+				return backend.NewMemoryReadWriter(fields), nil
+			}
+			return backend.CSVReadWriterFactory(dir)(resource, fields)
+		}),
+		backend.OnStart(func(ctx context.Context, b *backend.Backend) error {
+			_, err := b.Store().Create(ctx, "notes", backend.Resource{"title": "seeded"})
+			return err
+		}),
+		backend.OnStop(func(ctx context.Context, b *backend.Backend) error {
+			stopped = true
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+
+	// the API sits next to routes of our own
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	mux.Handle("/", b.Handler())
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	rsp, err := http.Get(srv.URL + "/hello")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	require.Equal(t, "hello", string(body))
+
+	rsp, err = http.Get(srv.URL + "/api/notes")
+	require.NoError(t, err)
+	var notes []backend.Resource
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&notes))
+	rsp.Body.Close()
+	require.Len(t, notes, 1)
+	require.Equal(t, "seeded", notes[0]["title"])
+
+	// notes were kept in memory
+	data, err := os.ReadFile(filepath.Join(dir, "notes.csv"))
+	require.NoError(t, err)
+	require.Empty(t, data)
+
+	require.NoError(t, b.Stop(context.Background()))
+	require.True(t, stopped)
+}
+
+func TestEmbeddedBackendRunWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	t.Cleanup(http.DefaultClient.CloseIdleConnections)
+
+	b, err := backend.New(backend.Config{
+		Dir:  testData(t, "../testdata/admin"),
+		Addr: ":4000",
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- b.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		rsp, err := http.Get("http://localhost:4000/api/notes")
+		if err != nil {
+			return false
+		}
+		rsp.Body.Close()
+		return rsp.StatusCode == http.StatusOK
+	}, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after its context was done")
+	}
+}
+
+func TestEmbeddedBackendRefusesBadSchemas(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	dir := testData(t, "../testdata/admin")
+
+	f, err := os.OpenFile(filepath.Join(dir, "_schemas.csv"), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("s15,1,notes,colour,rgb,,,\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = backend.New(backend.Config{Dir: dir})
+	require.ErrorContains(t, err, `notes.colour: unknown type "rgb"`)
+}
+
+func TestEmbeddedBackendRunWithTLS(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	certFile, keyFile, pool := selfSignedCert(t)
+
+	b, err := backend.New(backend.Config{
+		Dir:             testData(t, "../testdata/admin"),
+		Addr:            ":4443",
+		GRPCAddr:        ":4444",
+		TLSCertFile:     certFile,
+		TLSKeyFile:      keyFile,
+		ReadTimeout:     time.Second,
+		ShutdownTimeout: time.Second,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- b.Run(ctx)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	defer client.CloseIdleConnections()
+
+	require.Eventually(t, func() bool {
+		rsp, err := client.Get("https://localhost:4443/api/notes")
+		if err != nil {
+			return false
+		}
+		rsp.Body.Close()
+		return rsp.StatusCode == http.StatusOK
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// the handshake goes through, to the health service and nothing else
+	conn, err := grpc.NewClient("localhost:4444", grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(pool, "")))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, health.GetStatus())
+
+	err = conn.Invoke(context.Background(), "/backend.Nothing/Here", &emptypb.Empty{}, &emptypb.Empty{})
+	require.Equal(t, codes.Unimplemented, status.Code(err), err)
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after its context was done")
+	}
+}
+
+// selfSignedCert writes a certificate for localhost and its key to files,
+// and returns them with a pool that trusts the certificate.
+func selfSignedCert(t *testing.T) (string, string, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return certFile, keyFile, pool
+}
@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/pkg/backend"
+)
+
+func TestBulkWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	dir := testData(t, "../testdata/rest")
+
+	b, err := backend.New(backend.Config{Dir: dir})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+
+	srv := httptest.NewServer(b.Handler())
+
+	bulk := func(query string, body string, auth [2]string) (int, []v1alpha1.BulkResult) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/books/_bulk"+query, strings.NewReader(body))
+		require.NoError(t, err)
+		if len(auth[0]) > 0 {
+			req.SetBasicAuth(auth[0], auth[1])
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+
+		var results []v1alpha1.BulkResult
+		if rsp.Header.Get("Content-Type") == "application/json" {
+			require.NoError(t, json.NewDecoder(rsp.Body).Decode(&results))
+		}
+
+		return rsp.StatusCode, results
+	}
+
+	admin := [2]string{"admin", "admin123"}
+	user1 := [2]string{"user1", "user1pass"}
+
+	// every op succeeds
+	status, results := bulk("", `[
+		{"op":"create","record":{"title":"Dune","author":"Frank Herbert","year":1965}},
+		{"op":"create","record":{"title":"Emma","author":"Jane Austen","year":1900}}
+	]`, user1)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, results, 2)
+	for _, res := range results {
+		require.Equal(t, v1alpha1.BulkCreate, res.Op)
+		require.Equal(t, http.StatusCreated, res.Status)
+		require.NotEmpty(t, res.Id)
+		require.Nil(t, res.Error)
+	}
+	dune := results[0].Id
+
+	// each op is authorized and validated on its own
+	status, results = bulk("", `[
+		{"op":"create","record":{"title":"","author":"Nobody","year":1800}},
+		{"op":"delete","_id":"book1"},
+		{"op":"create","record":{"title":"Walden","author":"Henry Thoreau","year":1954}}
+	]`, user1)
+	require.Equal(t, http.StatusMultiStatus, status)
+	require.Len(t, results, 3)
+
+	require.Equal(t, http.StatusBadRequest, results[0].Status)
+	require.Equal(t, v1alpha1.CodeValidationFailed, results[0].Error.Code)
+	require.Equal(t, "/api/books/_bulk#0", results[0].Error.Instance)
+	require.Len(t, results[0].Error.Errors, 2)
+
+	require.Equal(t, http.StatusForbidden, results[1].Status)
+	require.Equal(t, v1alpha1.CodeForbidden, results[1].Error.Code)
+
+	require.Equal(t, http.StatusCreated, results[2].Status)
+
+	// an atomic batch writes nothing when an op fails
+	status, results = bulk("?atomic=true", `[
+		{"op":"delete","_id":"book1"},
+		{"op":"delete","_id":"missing"}
+	]`, admin)
+	require.Equal(t, http.StatusMultiStatus, status)
+	require.Equal(t, http.StatusFailedDependency, results[0].Status)
+	require.Equal(t, v1alpha1.CodeAborted, results[0].Error.Code)
+	require.Equal(t, http.StatusNotFound, results[1].Status)
+	require.Equal(t, v1alpha1.CodeNotFound, results[1].Error.Code)
+
+	status, results = bulk("?atomic=true", `[
+		{"op":"create","record":{"title":"Ulysses","author":"James Joyce","year":1922}},
+		{"op":"delete","_id":"`+dune+`"}
+	]`, admin)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, http.StatusCreated, results[0].Status)
+	require.Equal(t, http.StatusNoContent, results[1].Status)
+
+	// the request as a whole
+	status, _ = bulk("?atomic=maybe", `[]`, admin)
+	require.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = bulk("", `{"op":"create"}`, admin)
+	require.Equal(t, http.StatusBadRequest, status)
+
+	srv.Close()
+	require.NoError(t, b.Stop(context.Background()))
+
+	// what was written is read back
+	b, err = backend.New(backend.Config{Dir: dir})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+	defer b.Stop(context.Background())
+
+	srv = httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	rsp, err := http.Get(srv.URL + "/api/books")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	var books []v1alpha1.Resource
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&books))
+
+	titles := []any{}
+	for _, book := range books {
+		titles = append(titles, book["title"])
+	}
+	require.Equal(t, []any{"The Go Programming Language", "1984", "Emma", "Walden", "Ulysses"}, titles)
+}
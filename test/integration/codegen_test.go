@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/internal/codegen"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+// a program that drives the generated client against a running server
+const codegenMain = `package main
+
+import (
+	"context"
+	"fmt"
+
+	"gen/client"
+)
+
+func main() {
+	ctx := context.Background()
+	c := client.NewClient("http://localhost:4000/")
+
+	check := func(err error) {
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	for _, title := range []string{"b", "a"} {
+		_, err := c.CreateNotes(ctx, client.Notes{Title: title})
+		check(err)
+	}
+
+	notes, err := c.ListNotes(ctx, &client.NotesListOptions{SortBy: client.NotesFieldTitle})
+	check(err)
+	fmt.Println("list", notes[0].Title, notes[1].Title)
+
+	note, err := c.GetNotes(ctx, notes[0].Id)
+	check(err)
+	fmt.Println("get", note.Title, note.Version)
+
+	note.Title = "c"
+	note, err = c.UpdateNotes(ctx, note.Id, note)
+	check(err)
+	fmt.Println("update", note.Title)
+
+	check(c.DeleteNotes(ctx, note.Id))
+
+	_, err = c.GetNotes(ctx, note.Id)
+	fmt.Println("deleted", err.(*client.Error).StatusCode, err.(*client.Error).Code)
+
+	_, err = c.CreateNotes(ctx, client.Notes{})
+	invalid := err.(*client.Error)
+	fmt.Println("invalid", invalid.StatusCode, invalid.Code, invalid.Errors[0].Field, invalid.Errors[0].Rule)
+}
+`
+
+func TestCodegenGoClientWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/admin")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	err = s.Start()
+	require.NoError(t, err)
+
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+
+	err = srv.Start()
+	require.NoError(t, err)
+
+	defer srv.Stop()
+
+	dir := t.TempDir()
+
+	files, err := codegen.Go(schemas, "client")
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "client"), 0755))
+	for name, src := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "client", name), src, 0644))
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module gen\n\ngo 1.24\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(codegenMain), 0644))
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	require.Equal(t, []string{
+		"list a b",
+		"get a 1",
+		"update c",
+		"deleted 404 not_found",
+		"invalid 400 validation_failed title pattern",
+	}, strings.Split(strings.TrimSpace(string(out)), "\n"))
+}
@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	grpchandlers "github.com/w-h-a/backend/internal/handlers/grpc"
+	"github.com/w-h-a/backend/internal/servers"
+	grpcserver "github.com/w-h-a/backend/internal/servers/grpc"
+	"github.com/w-h-a/backend/internal/services/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealthWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/meta")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	require.NoError(t, s.Start())
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+
+	grpcSrv := grpcserver.NewServer(servers.WithAddress(":4001"))
+	require.NoError(t, grpcSrv.Handle(grpcserver.GrpcServiceRegistration{
+		Desc: &healthpb.Health_ServiceDesc,
+		Impl: grpchandlers.NewHealthHandler(s),
+	}))
+	require.NoError(t, grpcSrv.Start())
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("localhost:4001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+
+	get := func(path string) (int, v1alpha1.Health) {
+		rsp, err := http.Get("http://localhost:4000" + path)
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+
+		var health v1alpha1.Health
+		require.NoError(t, json.NewDecoder(rsp.Body).Decode(&health))
+
+		return rsp.StatusCode, health
+	}
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		rsp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return rsp.GetStatus()
+	}
+
+	// everything works, and every read/writer says so
+	code, health := get("/readyz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, v1alpha1.HealthOK, health.Status)
+	require.Equal(t, v1alpha1.ComponentHealth{Status: v1alpha1.HealthOK}, health.Components["store"])
+	require.Equal(t, v1alpha1.ComponentHealth{Status: v1alpha1.HealthOK}, health.Components["readwriter.notes"])
+	require.Equal(t, v1alpha1.ComponentHealth{Status: v1alpha1.HealthOK}, health.Components["readwriter._schemas"])
+
+	code, health = get("/healthz")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, v1alpha1.HealthOK, health.Status)
+
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check(""))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check(grpchandlers.LivenessService))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check("readwriter.notes"))
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "nothing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	watch, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	first, err := watch.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, first.GetStatus())
+
+	// a read/writer that no longer works makes the backend unready, but it
+	// is still alive
+	require.NoError(t, rws["notes"].Close(context.Background()))
+
+	code, health = get("/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, v1alpha1.HealthFailing, health.Status)
+	require.Equal(t, v1alpha1.HealthFailing, health.Components["readwriter.notes"].Status)
+	require.Contains(t, health.Components["readwriter.notes"].Error, "file is not open")
+	require.Equal(t, v1alpha1.HealthOK, health.Components["readwriter.drafts"].Status)
+
+	code, _ = get("/healthz")
+	require.Equal(t, http.StatusOK, code)
+
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check("readwriter.notes"))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check("readwriter.drafts"))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, check(grpchandlers.LivenessService))
+
+	list, err := client.List(context.Background(), &healthpb.HealthListRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, list.GetStatuses()["readwriter.notes"].GetStatus())
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, list.GetStatuses()[grpchandlers.LivenessService].GetStatus())
+
+	// and a stopped store is dead
+	require.NoError(t, s.Stop())
+
+	code, health = get("/healthz")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, v1alpha1.ComponentHealth{Status: v1alpha1.HealthFailing, Error: "store not running"}, health.Components["store"])
+
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(grpchandlers.LivenessService))
+}
@@ -2,8 +2,10 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"testing"
@@ -19,16 +21,16 @@ func TestHTTPServerWithCSVRW(t *testing.T) {
 		return
 	}
 
-	schemas, rws, err := initReadWriters(t, "../testdata/rest")
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/rest")
 	require.NoError(t, err)
 
-	s := store.New(schemas, rws)
+	s := store.New(schemas, rws, opts...)
 	err = s.Start()
 	require.NoError(t, err)
 
 	defer s.Stop()
 
-	srv, err := initHttpServer(t, schemas, s)
+	srv, err := initHttpServer(t, s)
 	require.NoError(t, err)
 
 	err = srv.Start()
@@ -135,3 +137,949 @@ func TestHTTPServerWithCSVRW(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPFilesWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/files")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, append(opts, store.WithBlob(initBlob(t)))...)
+	err = s.Start()
+	require.NoError(t, err)
+
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+
+	err = srv.Start()
+	require.NoError(t, err)
+
+	defer srv.Stop()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		content  []byte
+		auth     [2]string // username, password
+		status   int
+		validate func(*testing.T, *http.Response)
+	}{
+		{
+			name:   "Download before upload",
+			method: "GET",
+			path:   "/api/docs/doc1/files/attachment",
+			auth:   [2]string{"user1", "user1pass"},
+			status: http.StatusNotFound,
+		},
+		{
+			name:    "Upload as non-owner",
+			method:  "POST",
+			path:    "/api/docs/doc1/files/attachment",
+			content: []byte("hello"),
+			auth:    [2]string{"admin", "admin123"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name:    "Upload disallowed type",
+			method:  "POST",
+			path:    "/api/docs/doc1/files/attachment",
+			content: []byte("\x89PNG\r\n\x1a\n"),
+			auth:    [2]string{"user1", "user1pass"},
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "Upload too large",
+			method:  "POST",
+			path:    "/api/docs/doc1/files/attachment",
+			content: bytes.Repeat([]byte("a"), 65),
+			auth:    [2]string{"user1", "user1pass"},
+			status:  http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "Upload to non-file field",
+			method:  "POST",
+			path:    "/api/docs/doc1/files/owner",
+			content: []byte("hello"),
+			auth:    [2]string{"user1", "user1pass"},
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "Upload as owner",
+			method:  "POST",
+			path:    "/api/docs/doc1/files/attachment",
+			content: []byte("hello"),
+			auth:    [2]string{"user1", "user1pass"},
+			status:  http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				var f v1alpha1.File
+				json.NewDecoder(r.Body).Decode(&f)
+				require.Equal(t, int64(5), f.Size)
+				require.Equal(t, "notes.txt", f.Name)
+				require.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", f.Hash)
+			},
+		},
+		{
+			name:   "Download as non-owner",
+			method: "GET",
+			path:   "/api/docs/doc1/files/attachment",
+			auth:   [2]string{"admin", "admin123"},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "Download as owner",
+			method: "GET",
+			path:   "/api/docs/doc1/files/attachment",
+			auth:   [2]string{"user1", "user1pass"},
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				bs, _ := io.ReadAll(r.Body)
+				require.Equal(t, "hello", string(bs))
+				require.Contains(t, r.Header.Get("Content-Type"), "text/plain")
+				require.Equal(t, "nosniff", r.Header.Get("X-Content-Type-Options"))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body io.Reader
+			contentType := ""
+			if test.content != nil {
+				buf := &bytes.Buffer{}
+				mw := multipart.NewWriter(buf)
+				fw, _ := mw.CreateFormFile("file", "notes.txt")
+				fw.Write(test.content)
+				mw.Close()
+				body = buf
+				contentType = mw.FormDataContentType()
+			}
+
+			req, _ := http.NewRequest(test.method, "http://localhost:4000"+test.path, body)
+
+			if len(contentType) > 0 {
+				req.Header.Set("Content-Type", contentType)
+			}
+
+			if len(test.auth[0]) > 0 {
+				req.SetBasicAuth(test.auth[0], test.auth[1])
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer rsp.Body.Close()
+
+			if test.validate != nil {
+				test.validate(t, rsp)
+			}
+
+			require.Equal(t, test.status, rsp.StatusCode)
+		})
+	}
+}
+
+func TestHTTPGeoWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/geo")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	err = s.Start()
+	require.NoError(t, err)
+
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+
+	err = srv.Start()
+	require.NoError(t, err)
+
+	defer srv.Stop()
+
+	names := func(t *testing.T, r *http.Response) []string {
+		var places []v1alpha1.Resource
+		json.NewDecoder(r.Body).Decode(&places)
+		out := []string{}
+		for _, p := range places {
+			out = append(out, p["name"].(string))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     any
+		status   int
+		validate func(*testing.T, *http.Response)
+	}{
+		{
+			name:   "Create place with invalid point",
+			method: "POST",
+			path:   "/api/places",
+			body:   v1alpha1.Resource{"name": "Nowhere", "location": map[string]any{"lat": 100, "lon": 0}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Create place",
+			method: "POST",
+			path:   "/api/places",
+			body:   v1alpha1.Resource{"name": "Amsterdam", "location": map[string]any{"lat": 52.3676, "lon": 4.9041}},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Near ordered by distance",
+			method: "GET",
+			path:   "/api/places?near=51.5,0&radius=400",
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, []string{"London", "Brussels", "Paris", "Amsterdam"}, names(t, r))
+			},
+		},
+		{
+			name:   "Near with bbox",
+			method: "GET",
+			path:   "/api/places?near=51.5,0&radius=400&bbox=2,48,5,51",
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, []string{"Brussels", "Paris"}, names(t, r))
+			},
+		},
+		{
+			name:   "Bbox excludes deleted",
+			method: "GET",
+			path:   "/api/places?bbox=-10,45,20,55",
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				require.ElementsMatch(t, []string{"London", "Paris", "Brussels", "Amsterdam"}, names(t, r))
+			},
+		},
+		{
+			name:   "Near without radius",
+			method: "GET",
+			path:   "/api/places?near=51.5,0",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Near on unknown field",
+			method: "GET",
+			path:   "/api/places?near=51.5,0&radius=10&geo_field=name",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body io.Reader
+			if test.body != nil {
+				bs, _ := json.Marshal(test.body)
+				body = bytes.NewReader(bs)
+			}
+
+			req, _ := http.NewRequest(test.method, "http://localhost:4000"+test.path, body)
+
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer rsp.Body.Close()
+
+			if test.validate != nil {
+				test.validate(t, rsp)
+			}
+
+			require.Equal(t, test.status, rsp.StatusCode)
+		})
+	}
+}
+
+func TestHTTPNearestWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/vectors")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	err = s.Start()
+	require.NoError(t, err)
+
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+
+	err = srv.Start()
+	require.NoError(t, err)
+
+	defer srv.Stop()
+
+	titles := func(t *testing.T, r *http.Response) []string {
+		var neighbors []v1alpha1.Neighbor
+		json.NewDecoder(r.Body).Decode(&neighbors)
+		out := []string{}
+		for _, n := range neighbors {
+			out = append(out, n.Record["title"].(string))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     any
+		status   int
+		validate func(*testing.T, *http.Response)
+	}{
+		{
+			name:   "Create doc x",
+			method: "POST",
+			path:   "/api/docs",
+			body:   v1alpha1.Resource{"title": "x", "embedding": []float64{1, 0, 0}},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Create doc y",
+			method: "POST",
+			path:   "/api/docs",
+			body:   v1alpha1.Resource{"title": "y", "embedding": []float64{0, 2, 0}},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Create doc xy",
+			method: "POST",
+			path:   "/api/docs",
+			body:   v1alpha1.Resource{"title": "xy", "embedding": []float64{1, 1, 0}},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Create doc without embedding",
+			method: "POST",
+			path:   "/api/docs",
+			body:   v1alpha1.Resource{"title": "none"},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Create doc with wrong dimension",
+			method: "POST",
+			path:   "/api/docs",
+			body:   v1alpha1.Resource{"title": "bad", "embedding": []float64{1, 0}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Nearest by cosine",
+			method: "POST",
+			path:   "/api/docs/_knn",
+			body:   map[string]any{"vector": []float64{0, 1, 0}, "k": 2},
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, []string{"y", "xy"}, titles(t, r))
+			},
+		},
+		{
+			name:   "Nearest by l2",
+			method: "POST",
+			path:   "/api/docs/_knn",
+			body:   map[string]any{"field": "embedding", "vector": []float64{0.9, 0.2, 0}, "metric": "l2"},
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, []string{"x", "xy", "y"}, titles(t, r))
+			},
+		},
+		{
+			name:   "Nearest with wrong dimension",
+			method: "POST",
+			path:   "/api/docs/_knn",
+			body:   map[string]any{"vector": []float64{0, 1}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Nearest with unknown metric",
+			method: "POST",
+			path:   "/api/docs/_knn",
+			body:   map[string]any{"vector": []float64{0, 1, 0}, "metric": "dot"},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body io.Reader
+			if test.body != nil {
+				bs, _ := json.Marshal(test.body)
+				body = bytes.NewReader(bs)
+			}
+
+			req, _ := http.NewRequest(test.method, "http://localhost:4000"+test.path, body)
+
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer rsp.Body.Close()
+
+			if test.validate != nil {
+				test.validate(t, rsp)
+			}
+
+			require.Equal(t, test.status, rsp.StatusCode)
+		})
+	}
+}
+
+func TestHTTPAdminSchemasWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/admin")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	err = s.Start()
+	require.NoError(t, err)
+
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+
+	err = srv.Start()
+	require.NoError(t, err)
+
+	defer srv.Stop()
+
+	admin := [2]string{"admin", "admin123"}
+
+	fieldNames := func(t *testing.T, r *http.Response) []string {
+		var fields []v1alpha1.FieldSchema
+		json.NewDecoder(r.Body).Decode(&fields)
+		names := []string{}
+		for _, fs := range fields {
+			names = append(names, fs.Field)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     any
+		auth     [2]string // username, password
+		status   int
+		validate func(*testing.T, *http.Response)
+	}{
+		{
+			name:   "List schemas unauthenticated",
+			method: "GET",
+			path:   "/admin/schemas",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "List schemas without the admin role",
+			method: "GET",
+			path:   "/admin/schemas",
+			auth:   [2]string{"user1", "user1pass"},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "List schemas as admin",
+			method: "GET",
+			path:   "/admin/schemas",
+			auth:   admin,
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				var all map[string][]v1alpha1.FieldSchema
+				json.NewDecoder(r.Body).Decode(&all)
+				require.Contains(t, all, "notes")
+				require.Len(t, all["notes"], 3)
+			},
+		},
+		{
+			name:   "Create resource",
+			method: "POST",
+			path:   "/admin/schemas",
+			body: map[string]any{
+				"resource": "authors",
+				"fields": []v1alpha1.FieldSchema{
+					{Field: "name", Type: "text", Regex: "^.+$", Required: true},
+				},
+			},
+			auth:   admin,
+			status: http.StatusCreated,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, []string{"_id", "_v", "name"}, fieldNames(t, r))
+			},
+		},
+		{
+			name:   "Create existing resource",
+			method: "POST",
+			path:   "/admin/schemas",
+			body:   map[string]any{"resource": "authors"},
+			auth:   admin,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "Create resource with an unknown type",
+			method: "POST",
+			path:   "/admin/schemas",
+			body: map[string]any{
+				"resource": "tags",
+				"fields":   []v1alpha1.FieldSchema{{Field: "label", Type: "colour"}},
+			},
+			auth:   admin,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Create resource with a dangling reference",
+			method: "POST",
+			path:   "/admin/schemas",
+			body: map[string]any{
+				"resource": "tags",
+				"fields":   []v1alpha1.FieldSchema{{Field: "owner", Type: "ref:owners"}},
+			},
+			auth:   admin,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Use the new resource right away",
+			method: "POST",
+			path:   "/api/authors",
+			body:   v1alpha1.Resource{"name": "Ursula"},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "New resource validates its fields",
+			method: "POST",
+			path:   "/api/authors",
+			body:   v1alpha1.Resource{},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Add field to a resource holding records",
+			method: "POST",
+			path:   "/admin/schemas/authors/fields",
+			body:   v1alpha1.FieldSchema{Field: "born", Type: "number", Default: "1929"},
+			auth:   admin,
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Existing records read the added field",
+			method: "GET",
+			path:   "/api/authors",
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				var authors []v1alpha1.Resource
+				json.NewDecoder(r.Body).Decode(&authors)
+				require.Len(t, authors, 1)
+				require.Equal(t, 1929.0, authors[0]["born"])
+			},
+		},
+		{
+			name:   "Add field",
+			method: "POST",
+			path:   "/admin/schemas/notes/fields",
+			body:   v1alpha1.FieldSchema{Field: "author", Type: "ref:authors", OnDelete: v1alpha1.OnDeleteCascade},
+			auth:   admin,
+			status: http.StatusCreated,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, []string{"_id", "_v", "title", "author"}, fieldNames(t, r))
+			},
+		},
+		{
+			name:   "Add existing field",
+			method: "POST",
+			path:   "/admin/schemas/notes/fields",
+			body:   v1alpha1.FieldSchema{Field: "title", Type: "text"},
+			auth:   admin,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "Add field to an unknown resource",
+			method: "POST",
+			path:   "/admin/schemas/missing/fields",
+			body:   v1alpha1.FieldSchema{Field: "title", Type: "text"},
+			auth:   admin,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "Add a field and remove it again",
+			method: "POST",
+			path:   "/admin/schemas/notes/fields",
+			body:   v1alpha1.FieldSchema{Field: "pinned", Type: "number", Max: 1},
+			auth:   admin,
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Remove field",
+			method: "DELETE",
+			path:   "/admin/schemas/notes/fields/pinned",
+			auth:   admin,
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "Remove _id",
+			method: "DELETE",
+			path:   "/admin/schemas/notes/fields/_id",
+			auth:   admin,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Create note with the added field",
+			method: "POST",
+			path:   "/api/notes",
+			body:   v1alpha1.Resource{"title": "Earthsea"},
+			status: http.StatusCreated,
+		},
+		{
+			name:   "Alter field constraints on a resource holding records",
+			method: "PUT",
+			path:   "/admin/schemas/notes/fields/title",
+			body:   v1alpha1.FieldSchema{Type: "text", Regex: "^[A-Z]"},
+			auth:   admin,
+			status: http.StatusOK,
+		},
+		{
+			name:   "Altered constraints apply",
+			method: "POST",
+			path:   "/api/notes",
+			body:   v1alpha1.Resource{"title": "lowercase"},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Alter field type on a resource holding records",
+			method: "PUT",
+			path:   "/admin/schemas/notes/fields/title",
+			body:   v1alpha1.FieldSchema{Type: "number"},
+			auth:   admin,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "Alter field with an invalid regex",
+			method: "PUT",
+			path:   "/admin/schemas/notes/fields/title",
+			body:   v1alpha1.FieldSchema{Type: "text", Regex: "("},
+			auth:   admin,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Get schema",
+			method: "GET",
+			path:   "/admin/schemas/notes",
+			auth:   admin,
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				var fields []v1alpha1.FieldSchema
+				json.NewDecoder(r.Body).Decode(&fields)
+				require.Len(t, fields, 4)
+				require.Equal(t, "^[A-Z]", fields[2].Regex)
+				require.Equal(t, "ref:authors", fields[3].Type)
+			},
+		},
+		{
+			name:   "Get unknown schema",
+			method: "GET",
+			path:   "/admin/schemas/missing",
+			auth:   admin,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "Remove field from a resource holding records",
+			method: "DELETE",
+			path:   "/admin/schemas/notes/fields/title",
+			auth:   admin,
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "Removed field is gone from records",
+			method: "GET",
+			path:   "/api/notes",
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				var notes []v1alpha1.Resource
+				json.NewDecoder(r.Body).Decode(&notes)
+				require.Len(t, notes, 1)
+				require.NotContains(t, notes[0], "title")
+				require.Contains(t, notes[0], "author")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body io.Reader
+			if test.body != nil {
+				bs, _ := json.Marshal(test.body)
+				body = bytes.NewReader(bs)
+			}
+
+			req, _ := http.NewRequest(test.method, "http://localhost:4000"+test.path, body)
+
+			if len(test.auth[0]) > 0 {
+				req.SetBasicAuth(test.auth[0], test.auth[1])
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer rsp.Body.Close()
+
+			if test.validate != nil {
+				test.validate(t, rsp)
+			}
+
+			require.Equal(t, test.status, rsp.StatusCode)
+		})
+	}
+
+	// the changes were persisted to _schemas
+	recs, err := store.NewOptions(opts...).SchemaReadWriter.List(context.Background())
+	require.NoError(t, err)
+
+	persisted := map[string][]string{}
+	for _, rec := range recs {
+		fs := v1alpha1.ToFieldSchema(rec)
+		if !fs.Dropped {
+			persisted[fs.Resource] = append(persisted[fs.Resource], fs.Field)
+		}
+	}
+
+	require.Equal(t, []string{"_id", "_v", "name", "born"}, persisted["authors"])
+	require.Equal(t, []string{"_id", "_v", "author"}, persisted["notes"])
+}
+
+func TestHTTPMetaWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/meta")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	err = s.Start()
+	require.NoError(t, err)
+
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+
+	err = srv.Start()
+	require.NoError(t, err)
+
+	defer srv.Stop()
+
+	actions := func(t *testing.T, r *http.Response) map[string][]string {
+		var metas []v1alpha1.ResourceMeta
+		json.NewDecoder(r.Body).Decode(&metas)
+		got := map[string][]string{}
+		for _, meta := range metas {
+			got[meta.Name] = meta.Actions
+		}
+		return got
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		auth     [2]string // username, password
+		status   int
+		validate func(*testing.T, *http.Response)
+	}{
+		{
+			name:   "List resources unauthenticated",
+			path:   "/api/_meta/resources",
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, map[string][]string{
+					"notes": {"read"},
+				}, actions(t, r))
+			},
+		},
+		{
+			name:   "List resources as a user owning records",
+			path:   "/api/_meta/resources",
+			auth:   [2]string{"user1", "user1pass"},
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, map[string][]string{
+					"drafts": {"read", "update", "delete"},
+					"notes":  {"read"},
+				}, actions(t, r))
+			},
+		},
+		{
+			name:   "List resources as admin",
+			path:   "/api/_meta/resources",
+			auth:   [2]string{"admin", "admin123"},
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				require.Equal(t, map[string][]string{
+					"drafts":  {"read", "update", "delete"},
+					"notes":   {"read", "create"},
+					"secrets": {"read", "create", "update", "delete"},
+				}, actions(t, r))
+			},
+		},
+		{
+			name:   "Get resource",
+			path:   "/api/_meta/resources/notes",
+			status: http.StatusOK,
+			validate: func(t *testing.T, r *http.Response) {
+				var meta v1alpha1.ResourceMeta
+				json.NewDecoder(r.Body).Decode(&meta)
+				require.Equal(t, "notes", meta.Name)
+				require.Equal(t, 4, meta.Version)
+				require.Len(t, meta.Fields, 4)
+				require.Equal(t, v1alpha1.FieldSchema{Id: "s14", Version: 1, Resource: "notes", Field: "title", Type: "text", Regex: "^.+$", Required: true}, meta.Fields[2])
+				require.Equal(t, 5.0, meta.Fields[3].Max)
+			},
+		},
+		{
+			name:   "Get resource unauthenticated",
+			path:   "/api/_meta/resources/secrets",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Get resource without the admin role",
+			path:   "/api/_meta/resources/secrets",
+			auth:   [2]string{"user1", "user1pass"},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "Get unknown resource",
+			path:   "/api/_meta/resources/missing",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "http://localhost:4000"+test.path, nil)
+
+			if len(test.auth[0]) > 0 {
+				req.SetBasicAuth(test.auth[0], test.auth[1])
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer rsp.Body.Close()
+
+			if test.validate != nil {
+				test.validate(t, rsp)
+			}
+
+			require.Equal(t, test.status, rsp.StatusCode)
+		})
+	}
+}
+
+func TestHTTPOpenAPIWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/meta")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	err = s.Start()
+	require.NoError(t, err)
+
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+
+	err = srv.Start()
+	require.NoError(t, err)
+
+	defer srv.Stop()
+
+	type document struct {
+		OpenAPI    string                    `json:"openapi"`
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+				Required   []string                  `json:"required"`
+			} `json:"schemas"`
+			SecuritySchemes map[string]any `json:"securitySchemes"`
+		} `json:"components"`
+	}
+
+	fetch := func(t *testing.T) document {
+		rsp, err := http.Get("http://localhost:4000/openapi.json")
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+		var doc document
+		require.NoError(t, json.NewDecoder(rsp.Body).Decode(&doc))
+		return doc
+	}
+
+	methods := func(item map[string]any) []string {
+		got := []string{}
+		for _, m := range []string{"get", "post", "put", "delete"} {
+			if _, ok := item[m]; ok {
+				got = append(got, m)
+			}
+		}
+		return got
+	}
+
+	doc := fetch(t)
+
+	require.Equal(t, "3.1.0", doc.OpenAPI)
+	require.Contains(t, doc.Components.SecuritySchemes, "basicAuth")
+
+	// operations follow from the permissions
+	require.Equal(t, []string{"get", "post"}, methods(doc.Paths["/api/notes"]))
+	require.Equal(t, []string{"get"}, methods(doc.Paths["/api/notes/{id}"]))
+	require.Equal(t, []string{"get"}, methods(doc.Paths["/api/drafts"]))
+	require.Equal(t, []string{"get", "put", "delete"}, methods(doc.Paths["/api/drafts/{id}"]))
+	require.Equal(t, []string{"get", "put", "delete"}, methods(doc.Paths["/api/secrets/{id}"]))
+	require.NotContains(t, doc.Paths, "/api/_users")
+	require.NotContains(t, doc.Components.Schemas, "_users")
+
+	require.Equal(t, []any{map[string]any{}}, doc.Paths["/api/notes"]["get"].(map[string]any)["security"])
+	require.Equal(t, []any{map[string]any{"basicAuth": []any{}}}, doc.Paths["/api/notes"]["post"].(map[string]any)["security"])
+
+	// fields map to JSON Schema
+	notes := doc.Components.Schemas["notes"]
+	require.Equal(t, []string{"title"}, notes.Required)
+	require.Equal(t, "^.+$", notes.Properties["title"]["pattern"])
+	require.Equal(t, 0.0, notes.Properties["stars"]["minimum"])
+	require.Equal(t, 5.0, notes.Properties["stars"]["maximum"])
+	require.Equal(t, true, notes.Properties["_id"]["readOnly"])
+
+	// schema changes show up right away
+	_, err = s.AddField(context.Background(), "notes", v1alpha1.FieldSchema{Field: "pinned", Type: "number", Max: 1})
+	require.NoError(t, err)
+
+	doc = fetch(t)
+	require.Equal(t, 1.0, doc.Components.Schemas["notes"].Properties["pinned"]["maximum"])
+
+	rsp, err := http.Get("http://localhost:4000/docs")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "text/html; charset=utf-8", rsp.Header.Get("Content-Type"))
+}
@@ -0,0 +1,140 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	httphandlers "github.com/w-h-a/backend/internal/handlers/http"
+	"github.com/w-h-a/backend/internal/logs"
+	"github.com/w-h-a/backend/internal/services/store"
+)
+
+// syncBuffer is written to by the server while the test reads it.
+type syncBuffer struct {
+	mtx sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buf.Write(p)
+}
+
+// records returns what was logged with message msg.
+func (b *syncBuffer) records(t *testing.T, msg string) []map[string]any {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	recs := []map[string]any{}
+
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		rec := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		if rec["msg"] == msg {
+			recs = append(recs, rec)
+		}
+	}
+
+	return recs
+}
+
+// captureLogs sends what is logged to the default logger, at any level, to
+// the returned buffer until the test ends.
+func captureLogs(t *testing.T) *syncBuffer {
+	t.Helper()
+
+	buf := &syncBuffer{}
+
+	logger, err := logs.New(buf, logs.FormatJSON, slog.LevelDebug)
+	require.NoError(t, err)
+
+	// slog.SetDefault redirects the log package, which restoring the
+	// default logger does not undo
+	prev, w, flags := slog.Default(), log.Writer(), log.Flags()
+	t.Cleanup(func() {
+		slog.SetDefault(prev)
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	})
+
+	slog.SetDefault(logger)
+
+	return buf
+}
+
+func TestHTTPAccessLogsWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	buf := captureLogs(t)
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/meta")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+
+	// a request id sent along is kept
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:4000/api/secrets", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("user1", "user1pass")
+	req.Header.Set(httphandlers.RequestIDHeader, "req-1")
+
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusForbidden, rsp.StatusCode)
+	require.Equal(t, "req-1", rsp.Header.Get(httphandlers.RequestIDHeader))
+
+	// and one is made up otherwise
+	req, err = http.NewRequest(http.MethodGet, "http://localhost:4000/api/notes", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("user1", "wrong")
+
+	rsp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, rsp.StatusCode)
+	generated := rsp.Header.Get(httphandlers.RequestIDHeader)
+	require.NotEmpty(t, generated)
+
+	access := buf.records(t, "request")
+	require.Len(t, access, 2)
+
+	require.Equal(t, "req-1", access[0]["request.id"])
+	require.Equal(t, "user1", access[0]["user.id"])
+	require.Equal(t, "GET", access[0]["method"])
+	require.Equal(t, "/api/secrets", access[0]["path"])
+	require.EqualValues(t, http.StatusForbidden, access[0]["status"])
+
+	require.Equal(t, generated, access[1]["request.id"])
+	require.NotContains(t, access[1], "user.id")
+	require.EqualValues(t, http.StatusUnauthorized, access[1]["status"])
+
+	// what the store logs while serving a request names it too
+	denied := buf.records(t, "Authorization failed: no permission applies")
+	require.Len(t, denied, 1)
+	require.Equal(t, "req-1", denied[0]["request.id"])
+	require.Equal(t, "secrets", denied[0]["resource.name"])
+
+	failed := buf.records(t, "Authentication failed: password mismatch")
+	require.Len(t, failed, 1)
+	require.Equal(t, generated, failed[0]["request.id"])
+}
@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/internal/servers"
+	grpcserver "github.com/w-h-a/backend/internal/servers/grpc"
+	"github.com/w-h-a/backend/internal/services/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// scrapeMetrics reads /metrics into a map from each series, as written in
+// the exposition format, to its value.
+func scrapeMetrics(t *testing.T, url string) map[string]float64 {
+	t.Helper()
+
+	rsp, err := http.Get(url + "/metrics")
+	require.NoError(t, err)
+	defer rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	series := map[string]float64{}
+
+	scanner := bufio.NewScanner(rsp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		require.NoError(t, err)
+		series[line[:i]] = v
+	}
+	require.NoError(t, scanner.Err())
+
+	return series
+}
+
+func TestMetricsWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/meta")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+
+	grpcSrv := grpcserver.NewServer(servers.WithAddress(":4001"))
+	require.NoError(t, grpcSrv.Handle(grpcserver.GrpcServiceRegistration{Desc: echoDesc(make(chan string, 1)), Impl: struct{}{}}))
+	require.NoError(t, grpcSrv.Start())
+	defer grpcSrv.Stop()
+
+	// counters are shared by every test in the process, so only what this
+	// test adds is checked
+	before := scrapeMetrics(t, "http://localhost:4000")
+
+	do := func(method string, path string, body string, password string) (int, map[string]string) {
+		req, err := http.NewRequest(method, "http://localhost:4000"+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.SetBasicAuth("admin", password)
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+
+		created := map[string]string{}
+		if rsp.StatusCode == http.StatusCreated {
+			require.NoError(t, json.NewDecoder(rsp.Body).Decode(&created))
+		}
+
+		return rsp.StatusCode, created
+	}
+
+	code, _ := do(http.MethodPost, "/api/secrets", `{"value":"one"}`, "admin123")
+	require.Equal(t, http.StatusCreated, code)
+	code, created := do(http.MethodPost, "/api/secrets", `{"value":"two"}`, "admin123")
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = do(http.MethodDelete, "/api/secrets/"+created["_id"], "", "admin123")
+	require.Equal(t, http.StatusNoContent, code)
+	code, _ = do(http.MethodGet, "/api/secrets", "", "admin123")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = do(http.MethodGet, "/api/secrets", "", "wrong")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = do(http.MethodGet, "/api/made-up", "", "admin123")
+	require.Equal(t, http.StatusForbidden, code)
+	code, _ = do(http.MethodGet, "/nowhere", "", "admin123")
+	require.Equal(t, http.StatusNotFound, code)
+
+	rsp, err := http.Get("http://localhost:4000/api/drafts")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, rsp.StatusCode)
+
+	conn, err := grpc.NewClient("localhost:4001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.Invoke(context.Background(), "/backend.test.Echo/Echo", &emptypb.Empty{}, &emptypb.Empty{}))
+
+	after := scrapeMetrics(t, "http://localhost:4000")
+
+	added := func(series string) float64 {
+		return after[series] - before[series]
+	}
+
+	// requests by route, resource and status, made up resources and paths
+	// included but not named
+	require.Equal(t, 2.0, added(`backend_http_requests_total{code="201",method="POST",resource="secrets",route="/api/{resource}"}`))
+	require.Equal(t, 1.0, added(`backend_http_requests_total{code="204",method="DELETE",resource="secrets",route="/api/{resource}/{id}"}`))
+	require.Equal(t, 1.0, added(`backend_http_requests_total{code="200",method="GET",resource="secrets",route="/api/{resource}"}`))
+	require.Equal(t, 1.0, added(`backend_http_requests_total{code="403",method="GET",resource="",route="/api/{resource}"}`))
+	require.Equal(t, 1.0, added(`backend_http_requests_total{code="404",method="GET",resource="",route=""}`))
+	require.Equal(t, 1.0, added(`backend_http_request_duration_seconds_count{code="200",method="GET",resource="secrets",route="/api/{resource}"}`))
+
+	// the request the auth middleware turned down never reached the router
+	require.Equal(t, 1.0, added(`backend_http_requests_total{code="401",method="GET",resource="",route=""}`))
+
+	require.Equal(t, 1.0, added(`backend_auth_authn_failures_total{reason="password_mismatch"}`))
+	require.Equal(t, 1.0, added(`backend_auth_authz_failures_total{action="read",reason="unauthenticated",resource="drafts"}`))
+	require.Equal(t, 1.0, added(`backend_auth_authz_failures_total{action="read",reason="denied",resource=""}`))
+
+	require.Equal(t, 1.0, added(`backend_grpc_requests_total{code="OK",method="Echo",service="backend.test.Echo"}`))
+	require.Equal(t, 1.0, added(`backend_grpc_request_duration_seconds_count{code="OK",method="Echo",service="backend.test.Echo"}`))
+
+	require.Equal(t, 2.0, added(`backend_store_operation_duration_seconds_count{operation="Create"}`))
+	require.Equal(t, 1.0, added(`backend_store_operation_duration_seconds_count{operation="Delete"}`))
+
+	// two secrets were written and one deleted: its row and the tombstone are dead
+	require.Equal(t, 1.0, after[`backend_resource_records{resource="secrets"}`])
+	require.Equal(t, 3.0, after[`backend_resource_rows{resource="secrets"}`])
+	require.Equal(t, 2.0/3, after[`backend_resource_dead_row_ratio{resource="secrets"}`])
+	require.Equal(t, 2.0, after[`backend_resource_index_entries{resource="secrets"}`])
+	require.Positive(t, after[`backend_resource_file_size_bytes{resource="secrets"}`])
+	require.Equal(t, 2.0, after[`backend_resource_records{resource="_users"}`])
+	require.Contains(t, after, `backend_resource_records{resource="_schemas"}`)
+}
@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	grpchandlers "github.com/w-h-a/backend/internal/handlers/grpc"
+	httphandlers "github.com/w-h-a/backend/internal/handlers/http"
+	"github.com/w-h-a/backend/internal/servers"
+	grpcserver "github.com/w-h-a/backend/internal/servers/grpc"
+	"github.com/w-h-a/backend/pkg/backend"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestEmbeddedBackendMiddlewareWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	b, err := backend.New(backend.Config{
+		Dir:          testData(t, "../testdata/admin"),
+		CORSOrigins:  []string{"https://app.example.com"},
+		MaxBodyBytes: 64,
+		RouteTimeouts: map[string]time.Duration{
+			"/api/{resource}/{id}": time.Nanosecond,
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+	defer b.Stop(context.Background())
+
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	do := func(method string, path string, body string, header http.Header) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		for k, vs := range header {
+			req.Header[k] = vs
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		rsp.Body.Close()
+		return rsp
+	}
+
+	// browsers ask first, and are answered before authentication
+	rsp := do(http.MethodOptions, "/api/notes", "", http.Header{
+		"Origin":                         {"https://app.example.com"},
+		"Access-Control-Request-Method":  {http.MethodPost},
+		"Access-Control-Request-Headers": {"Authorization, Content-Type"},
+	})
+	require.Equal(t, http.StatusNoContent, rsp.StatusCode)
+	require.Equal(t, "https://app.example.com", rsp.Header.Get("Access-Control-Allow-Origin"))
+	require.NotEmpty(t, rsp.Header.Get(httphandlers.RequestIDHeader))
+
+	rsp = do(http.MethodPost, "/api/notes", `{"title":"short"}`, http.Header{"Origin": {"https://app.example.com"}})
+	require.Equal(t, http.StatusCreated, rsp.StatusCode)
+	require.Equal(t, "https://app.example.com", rsp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, httphandlers.RequestIDHeader+", Retry-After", rsp.Header.Get("Access-Control-Expose-Headers"))
+
+	rsp = do(http.MethodPost, "/api/notes", `{"title":"short"}`, http.Header{"Origin": {"https://evil.example.com"}})
+	require.Equal(t, http.StatusCreated, rsp.StatusCode)
+	require.Empty(t, rsp.Header.Get("Access-Control-Allow-Origin"))
+
+	// bodies past the limit are refused
+	rsp = do(http.MethodPost, "/api/notes", `{"title":"`+strings.Repeat("long", 32)+`"}`, nil)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rsp.StatusCode)
+
+	// and routes time out as configured
+	rsp = do(http.MethodGet, "/api/notes/n1", "", nil)
+	require.Equal(t, http.StatusServiceUnavailable, rsp.StatusCode)
+
+	rsp = do(http.MethodGet, "/api/notes", "", nil)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	// a timeout for a route that is not there is a mistake
+	_, err = backend.New(backend.Config{
+		Dir:           testData(t, "../testdata/admin"),
+		RouteTimeouts: map[string]time.Duration{"/api/{resource}/_nearest": time.Second},
+	})
+	require.ErrorContains(t, err, `timeout set for unknown route "/api/{resource}/_nearest"`)
+}
+
+// panicDesc is a gRPC service for tests whose one method panics.
+func panicDesc() *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "backend.test.Panic",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Panic",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := &emptypb.Empty{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					panic("boom")
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: "/backend.test.Panic/Panic"}, handler)
+			},
+		}},
+	}
+}
+
+func TestGRPCRequestIDAndRecovery(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	buf := captureLogs(t)
+
+	srv := grpcserver.NewServer(
+		servers.WithAddress(":4001"),
+		grpcserver.WithUnaryInterceptors(
+			grpchandlers.RequestIDUnaryInterceptor(),
+			grpchandlers.RecoverUnaryInterceptor(),
+		),
+	)
+
+	require.NoError(t, srv.Handle(grpcserver.GrpcServiceRegistration{Desc: panicDesc(), Impl: struct{}{}}))
+
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("localhost:4001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpchandlers.RequestIDMetadata, "req-1")
+
+	var header metadata.MD
+
+	// the server lives on, and says only that it failed
+	err = conn.Invoke(ctx, "/backend.test.Panic/Panic", &emptypb.Empty{}, &emptypb.Empty{}, grpc.Header(&header))
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Equal(t, "internal error", status.Convert(err).Message())
+	require.Equal(t, []string{"req-1"}, header.Get(grpchandlers.RequestIDMetadata))
+
+	panics := buf.records(t, "panic serving call")
+	require.Len(t, panics, 1)
+	require.Equal(t, "req-1", panics[0]["request.id"])
+	require.Equal(t, "boom", panics[0]["panic"])
+	require.Contains(t, panics[0]["stack"], "panicDesc")
+
+	// callers that send no id get one
+	err = conn.Invoke(context.Background(), "/backend.test.Panic/Panic", &emptypb.Empty{}, &emptypb.Empty{}, grpc.Header(&header))
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Len(t, header.Get(grpchandlers.RequestIDMetadata), 1)
+	require.NotEqual(t, "req-1", header.Get(grpchandlers.RequestIDMetadata)[0])
+}
@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/pkg/backend"
+)
+
+func TestProblemsWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	b, err := backend.New(backend.Config{
+		Dir: testData(t, "../testdata/rest"),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+	defer b.Stop(context.Background())
+
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		auth   [2]string
+		status int
+		code   string
+		errors []v1alpha1.FieldError
+	}{
+		{
+			name:   "every invalid field",
+			method: http.MethodPost,
+			path:   "/api/books",
+			body:   `{"title":"","year":1800,"tags":"fiction"}`,
+			auth:   [2]string{"user1", "user1pass"},
+			status: http.StatusBadRequest,
+			code:   v1alpha1.CodeValidationFailed,
+			errors: []v1alpha1.FieldError{
+				{Field: "title", Rule: v1alpha1.RulePattern, Expected: "^.+$", Message: `failed to parse field "title" as a valid string`},
+				{Field: "author", Rule: v1alpha1.RulePattern, Expected: "^.+$", Message: `failed to parse field "author" as a valid string`},
+				{Field: "year", Rule: v1alpha1.RuleMin, Expected: "1900", Message: `failed to parse field "year" as a valid number`},
+				{Field: "tags", Rule: v1alpha1.RuleType, Expected: "list", Message: `failed to parse field "tags" as a list`},
+			},
+		},
+		{
+			name:   "malformed json",
+			method: http.MethodPost,
+			path:   "/api/books",
+			body:   `{"title":`,
+			auth:   [2]string{"user1", "user1pass"},
+			status: http.StatusBadRequest,
+			code:   v1alpha1.CodeInvalidJSON,
+		},
+		{
+			name:   "wrong password",
+			method: http.MethodPost,
+			path:   "/api/books",
+			body:   `{}`,
+			auth:   [2]string{"user1", "wrongpass"},
+			status: http.StatusUnauthorized,
+			code:   v1alpha1.CodeUnauthenticated,
+		},
+		{
+			name:   "not an admin",
+			method: http.MethodDelete,
+			path:   "/api/books/book1",
+			auth:   [2]string{"user1", "user1pass"},
+			status: http.StatusForbidden,
+			code:   v1alpha1.CodeForbidden,
+		},
+		{
+			name:   "missing record",
+			method: http.MethodGet,
+			path:   "/api/books/nope",
+			status: http.StatusNotFound,
+			code:   v1alpha1.CodeNotFound,
+		},
+		{
+			name:   "unknown route",
+			method: http.MethodGet,
+			path:   "/nowhere",
+			status: http.StatusNotFound,
+			code:   v1alpha1.CodeNotFound,
+		},
+		{
+			name:   "wrong method",
+			method: http.MethodPatch,
+			path:   "/api/books/book1",
+			status: http.StatusMethodNotAllowed,
+			code:   v1alpha1.CodeMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			if len(tt.auth[0]) > 0 {
+				req.SetBasicAuth(tt.auth[0], tt.auth[1])
+			}
+
+			rsp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer rsp.Body.Close()
+
+			require.Equal(t, tt.status, rsp.StatusCode)
+			require.Equal(t, v1alpha1.ProblemContentType, rsp.Header.Get("Content-Type"))
+
+			var problem v1alpha1.Problem
+			require.NoError(t, json.NewDecoder(rsp.Body).Decode(&problem))
+
+			require.Equal(t, "about:blank", problem.Type)
+			require.Equal(t, http.StatusText(tt.status), problem.Title)
+			require.Equal(t, tt.status, problem.Status)
+			require.Equal(t, tt.code, problem.Code)
+			require.Equal(t, tt.path, problem.Instance)
+			require.NotEmpty(t, problem.Detail)
+			require.Equal(t, tt.errors, problem.Errors)
+		})
+	}
+}
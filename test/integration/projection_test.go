@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/api/v1alpha1/recordspb"
+	grpchandlers "github.com/w-h-a/backend/internal/handlers/grpc"
+	"github.com/w-h-a/backend/internal/servers"
+	grpcserver "github.com/w-h-a/backend/internal/servers/grpc"
+	"github.com/w-h-a/backend/internal/services/store"
+	"github.com/w-h-a/backend/pkg/backend"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestProjectionWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	b, err := backend.New(backend.Config{
+		Dir: testData(t, "../testdata/rest"),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+	defer b.Stop(context.Background())
+
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	get := func(path string, fields string, out any) (int, string) {
+		rsp, err := http.Get(srv.URL + path + "?" + url.Values{"fields": {fields}}.Encode())
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+
+		if rsp.StatusCode != http.StatusOK {
+			var problem v1alpha1.Problem
+			require.NoError(t, json.NewDecoder(rsp.Body).Decode(&problem))
+			return rsp.StatusCode, problem.Code
+		}
+
+		require.NoError(t, json.NewDecoder(rsp.Body).Decode(out))
+		return rsp.StatusCode, ""
+	}
+
+	var books []v1alpha1.Resource
+
+	status, _ := get("/api/books", "title,year", &books)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []v1alpha1.Resource{
+		{"_id": "book1", "title": "The Go Programming Language", "year": 2015.0},
+		{"_id": "book2", "title": "1984", "year": 1949.0},
+	}, books)
+
+	// sorting does not need the field to be returned
+	rsp, err := http.Get(srv.URL + "/api/books?sort_by=year&fields=title")
+	require.NoError(t, err)
+	books = nil
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&books))
+	rsp.Body.Close()
+	require.Equal(t, []v1alpha1.Resource{
+		{"_id": "book2", "title": "1984"},
+		{"_id": "book1", "title": "The Go Programming Language"},
+	}, books)
+
+	var book v1alpha1.Resource
+
+	status, _ = get("/api/books/book2", "-tags,-_v,-year", &book)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, v1alpha1.Resource{"_id": "book2", "title": "1984", "author": "George Orwell"}, book)
+
+	status, code := get("/api/books", "title,publisher", &books)
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, v1alpha1.CodeInvalidRequest, code)
+
+	status, code = get("/api/books/book1", "-_id", &book)
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, v1alpha1.CodeInvalidRequest, code)
+
+	status, code = get("/api/books", "title,-year", &books)
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, v1alpha1.CodeInvalidRequest, code)
+}
+
+func TestProjectionWithGRPC(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/rest")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	grpcSrv := grpcserver.NewServer(servers.WithAddress(":4001"))
+	require.NoError(t, grpcSrv.Handle(grpcserver.GrpcServiceRegistration{
+		Desc: &recordspb.Records_ServiceDesc,
+		Impl: grpchandlers.NewRecordsHandler(s),
+	}))
+	require.NoError(t, grpcSrv.Start())
+	defer grpcSrv.Stop()
+
+	conn, err := grpc.NewClient("localhost:4001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := recordspb.NewRecordsClient(conn)
+	ctx := context.Background()
+
+	list, err := client.ListRecords(ctx, &recordspb.ListRecordsRequest{
+		Resource: "books",
+		Sort:     "year",
+		ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, list.GetRecords(), 2)
+	require.Equal(t, map[string]any{"_id": "book2", "title": "1984"}, list.GetRecords()[0].AsMap())
+	require.Equal(t, map[string]any{"_id": "book1", "title": "The Go Programming Language"}, list.GetRecords()[1].AsMap())
+
+	// no mask is every field
+	get, err := client.GetRecord(ctx, &recordspb.GetRecordRequest{Resource: "books", Id: "book2"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"_id": "book2", "_v": 1.0, "title": "1984", "author": "George Orwell", "year": 1949.0,
+		"tags": []any{"fiction", "dystopian"},
+	}, get.GetRecord().AsMap())
+
+	_, err = client.GetRecord(ctx, &recordspb.GetRecordRequest{
+		Resource: "books",
+		Id:       "book2",
+		ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"publisher"}},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetRecord(ctx, &recordspb.GetRecordRequest{Resource: "books", Id: "book3"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// credentials are checked as over HTTP
+	wrong := metadata.AppendToOutgoingContext(ctx, grpchandlers.AuthorizationMetadata, "Basic "+base64.StdEncoding.EncodeToString([]byte("admin:wrong")))
+	_, err = client.ListRecords(wrong, &recordspb.ListRecordsRequest{Resource: "books"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	admin := metadata.AppendToOutgoingContext(ctx, grpchandlers.AuthorizationMetadata, "Basic "+base64.StdEncoding.EncodeToString([]byte("admin:admin123")))
+	list, err = client.ListRecords(admin, &recordspb.ListRecordsRequest{Resource: "books"})
+	require.NoError(t, err)
+	require.Len(t, list.GetRecords(), 2)
+}
@@ -0,0 +1,168 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	grpchandlers "github.com/w-h-a/backend/internal/handlers/grpc"
+	"github.com/w-h-a/backend/internal/ratelimit"
+	"github.com/w-h-a/backend/internal/servers"
+	grpcserver "github.com/w-h-a/backend/internal/servers/grpc"
+	"github.com/w-h-a/backend/pkg/backend"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestRateLimitsWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	buf := captureLogs(t)
+
+	b, err := backend.New(backend.Config{
+		Dir:       testData(t, "../testdata/admin"),
+		RateLimit: backend.RateLimit{Count: 100, Per: time.Minute},
+		RateLimits: map[string]backend.RateLimit{
+			"notes:create": {Count: 2, Per: time.Minute},
+		},
+		LoginAttempts: 2,
+		LoginLockout:  time.Minute,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+	defer b.Stop(context.Background())
+
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	before := scrapeMetrics(t, srv.URL)
+
+	do := func(method string, path string, body string, username string, password string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if len(username) > 0 {
+			req.SetBasicAuth(username, password)
+		}
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		rsp.Body.Close()
+		return rsp
+	}
+
+	// creating notes is held to its own limit
+	for range 2 {
+		rsp := do(http.MethodPost, "/api/notes", `{"title":"spam"}`, "", "")
+		require.Equal(t, http.StatusCreated, rsp.StatusCode)
+	}
+
+	rsp := do(http.MethodPost, "/api/notes", `{"title":"spam"}`, "", "")
+	require.Equal(t, http.StatusTooManyRequests, rsp.StatusCode)
+	require.Equal(t, "30", rsp.Header.Get("Retry-After"))
+
+	// which leaves reading them, and other users, alone
+	rsp = do(http.MethodGet, "/api/notes", "", "", "")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	rsp = do(http.MethodPost, "/api/notes", `{"title":"mine"}`, "user1", "user1pass")
+	require.Equal(t, http.StatusCreated, rsp.StatusCode)
+
+	// failed logins lock the username out, right password or not
+	for range 2 {
+		rsp = do(http.MethodGet, "/api/notes", "", "admin", "guess")
+		require.Equal(t, http.StatusUnauthorized, rsp.StatusCode)
+	}
+
+	rsp = do(http.MethodGet, "/api/notes", "", "admin", "admin123")
+	require.Equal(t, http.StatusTooManyRequests, rsp.StatusCode)
+	require.Equal(t, "60", rsp.Header.Get("Retry-After"))
+
+	// and the address they came from, whoever it tries next
+	rsp = do(http.MethodGet, "/api/notes", "", "user1", "user1pass")
+	require.Equal(t, http.StatusTooManyRequests, rsp.StatusCode)
+
+	// while health checks still get through
+	rsp = do(http.MethodGet, "/healthz", "", "", "")
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	locked := buf.records(t, "Authentication refused: locked out after failed logins")
+	require.Len(t, locked, 2)
+	require.Equal(t, "admin", locked[0]["user.id"])
+
+	// the password was never checked while locked out
+	require.Len(t, buf.records(t, "Authentication failed: password mismatch"), 2)
+
+	after := scrapeMetrics(t, srv.URL)
+	for series, want := range map[string]float64{
+		`backend_ratelimit_rejected_total{protocol="http",reason="rate"}`:    1,
+		`backend_ratelimit_rejected_total{protocol="http",reason="lockout"}`: 2,
+	} {
+		require.Equal(t, want, after[series]-before[series], series)
+	}
+
+	// rate limits name a resource and an action
+	_, err = backend.New(backend.Config{
+		Dir:        testData(t, "../testdata/admin"),
+		RateLimits: map[string]backend.RateLimit{"notes:write": {Count: 1, Per: time.Second}},
+	})
+	require.ErrorContains(t, err, `rate limit set for "notes:write", which is not RESOURCE:ACTION`)
+}
+
+func TestGRPCRateLimits(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	limiter := ratelimit.NewLimiter()
+	limit := ratelimit.Limit{Count: 1, Per: time.Minute}
+
+	srv := grpcserver.NewServer(
+		servers.WithAddress(":4001"),
+		grpcserver.WithUnaryInterceptors(grpchandlers.RateLimitUnaryInterceptor(limiter, limit)),
+		grpcserver.WithStreamInterceptors(grpchandlers.RateLimitStreamInterceptor(limiter, limit)),
+	)
+
+	require.NoError(t, srv.Handle(grpcserver.GrpcServiceRegistration{Desc: echoDesc(make(chan string, 2)), Impl: struct{}{}}))
+	require.NoError(t, srv.Handle(grpcserver.GrpcServiceRegistration{Desc: &healthpb.Health_ServiceDesc, Impl: health.NewServer()}))
+
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("localhost:4001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := context.Background()
+
+	require.NoError(t, conn.Invoke(ctx, "/backend.test.Echo/Echo", &emptypb.Empty{}, &emptypb.Empty{}))
+
+	err = conn.Invoke(ctx, "/backend.test.Echo/Echo", &emptypb.Empty{}, &emptypb.Empty{})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	retry, ok := details[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.InDelta(t, time.Minute.Seconds(), retry.RetryDelay.AsDuration().Seconds(), 1)
+
+	// health checks are not limited
+	for range 3 {
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/pkg/backend"
+)
+
+func TestSortWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	b, err := backend.New(backend.Config{
+		Dir: testData(t, "../testdata/rest"),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, b.Start(context.Background()))
+	defer b.Stop(context.Background())
+
+	srv := httptest.NewServer(b.Handler())
+	defer srv.Close()
+
+	for _, book := range []string{
+		`{"title":"brave new world","author":"Aldous Huxley","year":1932}`,
+		`{"title":"Animal Farm","author":"George Orwell","year":1945}`,
+		`{"title":"Émile","author":"Jean-Jacques Rousseau","year":1950}`,
+	} {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/books", strings.NewReader(book))
+		require.NoError(t, err)
+		req.SetBasicAuth("user1", "user1pass")
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		bs, _ := io.ReadAll(rsp.Body)
+		rsp.Body.Close()
+		require.Equal(t, http.StatusCreated, rsp.StatusCode, string(bs))
+	}
+
+	list := func(query string) (int, []string) {
+		rsp, err := http.Get(srv.URL + "/api/books?" + query)
+		require.NoError(t, err)
+		defer rsp.Body.Close()
+
+		if rsp.StatusCode != http.StatusOK {
+			return rsp.StatusCode, nil
+		}
+
+		var books []v1alpha1.Resource
+		require.NoError(t, json.NewDecoder(rsp.Body).Decode(&books))
+
+		titles := []string{}
+		for _, book := range books {
+			titles = append(titles, book["title"].(string))
+		}
+		return rsp.StatusCode, titles
+	}
+
+	status, titles := list("sort=-year,title")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"The Go Programming Language", "Émile", "1984", "Animal Farm", "brave new world"}, titles)
+
+	status, titles = list("sort=author,-year")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"brave new world", "The Go Programming Language", "1984", "Animal Farm", "Émile"}, titles)
+
+	status, titles = list("sort=title")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"1984", "Animal Farm", "The Go Programming Language", "brave new world", "Émile"}, titles)
+
+	status, titles = list("sort=title&collation=nocase")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"1984", "Animal Farm", "brave new world", "The Go Programming Language", "Émile"}, titles)
+
+	status, titles = list("sort=title&collation=fr")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"1984", "Animal Farm", "brave new world", "Émile", "The Go Programming Language"}, titles)
+
+	// empty lists sort as nulls
+	status, titles = list("sort=tags,title")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"1984", "The Go Programming Language", "Animal Farm", "brave new world", "Émile"}, titles)
+
+	status, titles = list("sort=tags,title&nulls=first")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"Animal Farm", "brave new world", "Émile", "1984", "The Go Programming Language"}, titles)
+
+	// the older form still sorts by one field
+	status, titles = list("sort_by=year")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"brave new world", "Animal Farm", "1984", "Émile", "The Go Programming Language"}, titles)
+
+	for _, query := range []string{"sort=publisher", "sort=year&sort_by=year", "sort=year&nulls=middle", "sort=title&collation=not-a-language!", "sort=year,-year"} {
+		status, _ = list(query)
+		require.Equal(t, http.StatusBadRequest, status, query)
+	}
+}
@@ -3,11 +3,17 @@ package integration
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/blob"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/readwriter/csv"
 	"github.com/w-h-a/backend/internal/services/store"
 )
 
@@ -88,10 +94,10 @@ func TestStoreAuthorizationWithCSVRW(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			schemas, rws, err := initReadWriters(t, "../testdata/authz")
+			schemas, rws, opts, err := initReadWriters(t, "../testdata/authz")
 			require.NoError(t, err)
 
-			s := store.New(schemas, rws)
+			s := store.New(schemas, rws, opts...)
 
 			u, _ := s.Authenticate(context.Background(), test.username, test.password)
 			err = s.Authorize(context.Background(), test.resource, test.id, test.action, u)
@@ -158,7 +164,7 @@ func TestStoreCRUDWithCSVRW(t *testing.T) {
 				})
 				return err
 			},
-			err: false,
+			err: true,
 		},
 		{
 			name: "Update book",
@@ -174,7 +180,7 @@ func TestStoreCRUDWithCSVRW(t *testing.T) {
 				if err != nil {
 					return err
 				}
-				return s.Update(context.Background(), "books", v1alpha1.Resource{
+				_, err = s.Update(context.Background(), "books", v1alpha1.Resource{
 					"_id":              "test-id-2",
 					"title":            "Updated Title",
 					"author":           "Author",
@@ -182,6 +188,7 @@ func TestStoreCRUDWithCSVRW(t *testing.T) {
 					"genres":           []string{"New"},
 					"isbn":             "111-1111111111",
 				})
+				return err
 			},
 			err: false,
 			postCheck: func(s *store.Store) error {
@@ -209,7 +216,7 @@ func TestStoreCRUDWithCSVRW(t *testing.T) {
 				if err != nil {
 					return err
 				}
-				return s.Delete(context.Background(), "books", "test-id-3")
+				return s.Delete(context.Background(), "books", "test-id-3", nil)
 			},
 			err: false,
 			postCheck: func(s *store.Store) error {
@@ -263,10 +270,10 @@ func TestStoreCRUDWithCSVRW(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			schemas, rws, err := initReadWriters(t, "../testdata/basic")
+			schemas, rws, opts, err := initReadWriters(t, "../testdata/basic")
 			require.NoError(t, err)
 
-			s := store.New(schemas, rws)
+			s := store.New(schemas, rws, opts...)
 
 			err = test.operation(s)
 
@@ -283,3 +290,867 @@ func TestStoreCRUDWithCSVRW(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreReferencesWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	tests := []struct {
+		name      string
+		operation func(*store.Store) error
+		err       error
+		postCheck func(*store.Store) error
+	}{
+		{
+			name: "Create with existing reference",
+			operation: func(s *store.Store) error {
+				_, err := s.Create(context.Background(), "books", v1alpha1.Resource{
+					"title":  "Animal Farm",
+					"author": "orwell",
+					"editor": nil,
+				})
+				return err
+			},
+		},
+		{
+			name: "Create with dangling reference",
+			operation: func(s *store.Store) error {
+				_, err := s.Create(context.Background(), "books", v1alpha1.Resource{
+					"title":  "Unknown",
+					"author": "nobody",
+					"editor": nil,
+				})
+				return err
+			},
+			err: store.ErrRef,
+		},
+		{
+			name: "Update with dangling reference",
+			operation: func(s *store.Store) error {
+				_, err := s.Update(context.Background(), "books", v1alpha1.Resource{
+					"_id":    "1984",
+					"title":  "1984",
+					"author": "orwell",
+					"editor": "nobody",
+				})
+				return err
+			},
+			err: store.ErrRef,
+		},
+		{
+			name: "Delete restricted",
+			operation: func(s *store.Store) error {
+				return s.Delete(context.Background(), "authors", "orwell", nil)
+			},
+			err: store.ErrConflict,
+		},
+		{
+			name: "Delete cascades",
+			operation: func(s *store.Store) error {
+				admin, err := s.Authenticate(context.Background(), "admin", "admin123")
+				if err != nil {
+					return err
+				}
+				return s.Delete(context.Background(), "books", "1984", admin)
+			},
+			postCheck: func(s *store.Store) error {
+				reviews, err := s.List(context.Background(), "reviews", "")
+				if err != nil {
+					return err
+				}
+				if len(reviews) != 0 {
+					return errors.New("reviews not cascaded")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Delete sets null",
+			operation: func(s *store.Store) error {
+				admin, err := s.Authenticate(context.Background(), "admin", "admin123")
+				if err != nil {
+					return err
+				}
+				return s.Delete(context.Background(), "_users", "bob", admin)
+			},
+			postCheck: func(s *store.Store) error {
+				book, err := s.ReadOne(context.Background(), "books", "1984")
+				if err != nil {
+					return err
+				}
+				if book["editor"] != nil {
+					return errors.New("editor not set to null")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Delete may not cascade",
+			operation: func(s *store.Store) error {
+				bob, err := s.Authenticate(context.Background(), "bob", "bobpass")
+				if err != nil {
+					return err
+				}
+				return s.Delete(context.Background(), "books", "1984", bob)
+			},
+			err: store.ErrAuthz,
+			postCheck: func(s *store.Store) error {
+				if _, err := s.ReadOne(context.Background(), "books", "1984"); err != nil {
+					return err
+				}
+				reviews, err := s.List(context.Background(), "reviews", "")
+				if err != nil {
+					return err
+				}
+				if len(reviews) != 2 {
+					return errors.New("reviews deleted without permission")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Delete may not set null",
+			operation: func(s *store.Store) error {
+				return s.Delete(context.Background(), "_users", "bob", nil)
+			},
+			err: store.ErrAuthn,
+			postCheck: func(s *store.Store) error {
+				book, err := s.ReadOne(context.Background(), "books", "1984")
+				if err != nil {
+					return err
+				}
+				if book["editor"] != "bob" {
+					return errors.New("editor set to null without permission")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Expand respects permissions",
+			operation: func(s *store.Store) error {
+				admin, err := s.Authenticate(context.Background(), "admin", "admin123")
+				if err != nil {
+					return err
+				}
+				books, err := s.List(context.Background(), "books", "")
+				if err != nil {
+					return err
+				}
+				if err := s.Expand(context.Background(), "books", books, []string{"author"}, admin); err != nil {
+					return err
+				}
+				if author, ok := books[0]["author"].(v1alpha1.Resource); !ok || author["name"] != "George Orwell" {
+					return errors.New("author not expanded for admin")
+				}
+				books, err = s.List(context.Background(), "books", "")
+				if err != nil {
+					return err
+				}
+				if err := s.Expand(context.Background(), "books", books, []string{"author"}, nil); err != nil {
+					return err
+				}
+				if books[0]["author"] != "orwell" {
+					return errors.New("author expanded without permission")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Expand non-reference field",
+			operation: func(s *store.Store) error {
+				return s.Expand(context.Background(), "books", []v1alpha1.Resource{}, []string{"title"}, nil)
+			},
+			err: store.ErrRef,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schemas, rws, opts, err := initReadWriters(t, "../testdata/refs")
+			require.NoError(t, err)
+
+			s := store.New(schemas, rws, opts...)
+
+			err = test.operation(s)
+
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if test.postCheck != nil {
+				err := test.postCheck(s)
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestStoreDeleteIsWholeWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/refs")
+	require.NoError(t, err)
+
+	rws["reviews"] = failingDeletes{rws["reviews"]}
+
+	s := store.New(schemas, rws, opts...)
+
+	admin, err := s.Authenticate(ctx, "admin", "admin123")
+	require.NoError(t, err)
+
+	// the book goes first, then its reviews fail to, and the book comes back
+	require.Error(t, s.Delete(ctx, "books", "1984", admin))
+
+	book, err := s.ReadOne(ctx, "books", "1984")
+	require.NoError(t, err)
+	require.Equal(t, "1984", book["title"])
+
+	reviews, err := s.List(ctx, "reviews", "")
+	require.NoError(t, err)
+	require.Len(t, reviews, 2)
+}
+
+func TestListOrderWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	open := func(location string) readwriter.ReadWriter {
+		return csv.NewReadWriter(
+			readwriter.WithLocation(location),
+			readwriter.WithFieldSchemas([]v1alpha1.FieldSchema{
+				{Field: "_id", Type: "text"},
+				{Field: "_v", Type: "number"},
+				{Field: "title", Type: "text"},
+			}),
+		)
+	}
+
+	ids := func(rw readwriter.ReadWriter) []string {
+		recs, err := rw.List(ctx)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, rec := range recs {
+			ids = append(ids, rec[0])
+		}
+		return ids
+	}
+
+	location := t.TempDir() + "/notes.csv"
+
+	rw := open(location)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, rw.Create(ctx, v1alpha1.Record{id, "", id}))
+	}
+
+	// updates append rows, but records keep their place in the list
+	require.NoError(t, rw.Update(ctx, v1alpha1.Record{"a", "", "a again"}))
+	require.Equal(t, []string{"a", "b", "c"}, ids(rw))
+
+	// a record created again after its delete goes last
+	require.NoError(t, rw.Delete(ctx, "b"))
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"b", "", "b again"}))
+	require.Equal(t, []string{"a", "c", "b"}, ids(rw))
+
+	require.NoError(t, rw.Close(ctx))
+
+	rw = open(location)
+	defer rw.Close(ctx)
+
+	require.Equal(t, []string{"a", "c", "b"}, ids(rw))
+}
+
+func TestStoreFilesWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/files")
+	require.NoError(t, err)
+
+	b := initBlob(t)
+
+	s := store.New(schemas, rws, append(opts, store.WithBlob(b))...)
+
+	store.GenerateId = func() string { return "doc2" }
+	_, err = s.Create(ctx, "docs", v1alpha1.Resource{"owner": "user1"})
+	require.NoError(t, err)
+
+	exists := func(hash string) bool {
+		rc, err := b.Get(ctx, hash)
+		if errors.Is(err, blob.ErrNotFound) {
+			return false
+		}
+		require.NoError(t, err)
+		rc.Close()
+		return true
+	}
+
+	// both docs hold the same content, so the same blob
+	hello, err := s.PutFile(ctx, "docs", "doc1", "attachment", "a.txt", strings.NewReader("hello"))
+	require.NoError(t, err)
+	_, err = s.PutFile(ctx, "docs", "doc2", "attachment", "b.txt", strings.NewReader("hello"))
+	require.NoError(t, err)
+
+	bye, err := s.PutFile(ctx, "docs", "doc1", "attachment", "a.txt", strings.NewReader("bye"))
+	require.NoError(t, err)
+	require.True(t, exists(hello.Hash))
+
+	_, err = s.PutFile(ctx, "docs", "doc2", "attachment", "b.txt", strings.NewReader("bye"))
+	require.NoError(t, err)
+	require.False(t, exists(hello.Hash))
+
+	require.NoError(t, s.Delete(ctx, "docs", "doc1", nil))
+	require.True(t, exists(bye.Hash))
+
+	require.NoError(t, s.Delete(ctx, "docs", "doc2", nil))
+	require.False(t, exists(bye.Hash))
+}
+
+func TestStoreSlowUploadWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/files")
+	require.NoError(t, err)
+
+	b := initBlob(t)
+	gated := &gatedBlob{Blob: b, started: make(chan struct{})}
+
+	s := store.New(schemas, rws, append(opts, store.WithBlob(gated))...)
+
+	store.GenerateId = func() string { return "doc2" }
+	_, err = s.Create(ctx, "docs", v1alpha1.Resource{"owner": "user1"})
+	require.NoError(t, err)
+
+	exists := func(hash string) bool {
+		rc, err := b.Get(ctx, hash)
+		if errors.Is(err, blob.ErrNotFound) {
+			return false
+		}
+		require.NoError(t, err)
+		rc.Close()
+		return true
+	}
+
+	type result struct {
+		f   v1alpha1.File
+		err error
+	}
+
+	// slow starts an upload and returns once it streams, with what ends it
+	slow := func(id string, content string) (chan struct{}, chan result) {
+		release := make(chan struct{})
+		gated.next = release
+		done := make(chan result, 1)
+		go func() {
+			f, err := s.PutFile(ctx, "docs", id, "attachment", "b.txt", strings.NewReader(content))
+			done <- result{f, err}
+		}()
+		<-gated.started
+		return release, done
+	}
+
+	hello, err := s.PutFile(ctx, "docs", "doc1", "attachment", "a.txt", strings.NewReader("hello"))
+	require.NoError(t, err)
+
+	// a slow upload holds up neither other writes nor other uploads, but
+	// what they release waits for it
+	release, done := slow("doc2", "slow")
+
+	bye, err := s.PutFile(ctx, "docs", "doc1", "attachment", "a.txt", strings.NewReader("bye"))
+	require.NoError(t, err)
+	require.True(t, exists(hello.Hash))
+
+	require.NoError(t, s.Delete(ctx, "docs", "doc1", nil))
+	require.True(t, exists(bye.Hash))
+
+	close(release)
+	res := <-done
+	require.NoError(t, res.err)
+
+	require.True(t, exists(res.f.Hash))
+	require.False(t, exists(hello.Hash))
+	require.False(t, exists(bye.Hash))
+
+	// the record is checked again once the content is in
+	release, done = slow("doc2", "late")
+
+	require.NoError(t, s.Delete(ctx, "docs", "doc2", nil))
+
+	close(release)
+	res = <-done
+	require.ErrorIs(t, res.err, store.ErrNotFound)
+	require.False(t, exists(hashOf("late")))
+}
+
+func TestStoreSchemaEvolutionWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		operation func(*store.Store) error
+		err       error
+		postCheck func(*store.Store) error
+	}{
+		{
+			name: "Read records written before a field was added or dropped",
+			operation: func(s *store.Store) error {
+				b1, err := s.ReadOne(ctx, "books", "b1")
+				if err != nil {
+					return err
+				}
+				if !reflect.DeepEqual(v1alpha1.Resource{"_id": "b1", "_v": 1.0, "title": "Dune", "pages": 100.0}, b1) {
+					return fmt.Errorf("expected %v, got %v", v1alpha1.Resource{"_id": "b1", "_v": 1.0, "title": "Dune", "pages": 100.0}, b1)
+				}
+
+				b2, err := s.ReadOne(ctx, "books", "b2")
+				if err != nil {
+					return err
+				}
+				if !reflect.DeepEqual(v1alpha1.Resource{"_id": "b2", "_v": 1.0, "title": "Emma", "pages": 320.0}, b2) {
+					return fmt.Errorf("expected %v, got %v", v1alpha1.Resource{"_id": "b2", "_v": 1.0, "title": "Emma", "pages": 320.0}, b2)
+				}
+				return nil
+			},
+		},
+		{
+			name: "Rename a field",
+			operation: func(s *store.Store) error {
+				_, err := s.AlterField(ctx, "books", "title", v1alpha1.FieldSchema{Field: "name", Type: "text", Regex: "^.+$"})
+				return err
+			},
+			postCheck: func(s *store.Store) error {
+				b1, err := s.ReadOne(ctx, "books", "b1")
+				if err != nil {
+					return err
+				}
+				if b1["name"] != "Dune" {
+					return errors.New("renamed field lost its value")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Change a field to a type its values convert to",
+			operation: func(s *store.Store) error {
+				_, err := s.AlterField(ctx, "books", "pages", v1alpha1.FieldSchema{Type: "text"})
+				return err
+			},
+			postCheck: func(s *store.Store) error {
+				b2, err := s.ReadOne(ctx, "books", "b2")
+				if err != nil {
+					return err
+				}
+				if b2["pages"] != "320" {
+					return errors.New("converted field lost its value")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Change a field to a type its values do not convert to",
+			operation: func(s *store.Store) error {
+				_, err := s.AlterField(ctx, "books", "title", v1alpha1.FieldSchema{Type: "number"})
+				return err
+			},
+			err: store.ErrConflict,
+		},
+		{
+			name: "Re-add a dropped field",
+			operation: func(s *store.Store) error {
+				_, err := s.AddField(ctx, "books", v1alpha1.FieldSchema{Field: "isbn", Type: "text"})
+				return err
+			},
+			postCheck: func(s *store.Store) error {
+				b1, err := s.ReadOne(ctx, "books", "b1")
+				if err != nil {
+					return err
+				}
+				if b1["isbn"] != "" {
+					return errors.New("re-added field picked up the dropped column")
+				}
+				return nil
+			},
+		},
+		{
+			name: "Migrate old records",
+			operation: func(s *store.Store) error {
+				migrated, err := s.Migrate(ctx)
+				if err != nil {
+					return err
+				}
+				if !reflect.DeepEqual(map[string]int{"books": 1}, migrated) {
+					return fmt.Errorf("expected %v, got %v", map[string]int{"books": 1}, migrated)
+				}
+				return nil
+			},
+			postCheck: func(s *store.Store) error {
+				migrated, err := s.Migrate(ctx)
+				if err != nil {
+					return err
+				}
+				if len(migrated) > 0 {
+					return fmt.Errorf("migrated twice: %v", migrated)
+				}
+
+				b1, err := s.ReadOne(ctx, "books", "b1")
+				if err != nil {
+					return err
+				}
+				if !reflect.DeepEqual(v1alpha1.Resource{"_id": "b1", "_v": 2.0, "title": "Dune", "pages": 100.0}, b1) {
+					return fmt.Errorf("expected %v, got %v", v1alpha1.Resource{"_id": "b1", "_v": 2.0, "title": "Dune", "pages": 100.0}, b1)
+				}
+				return nil
+			},
+		},
+		{
+			name: "Schema version grows with changes",
+			operation: func(s *store.Store) error {
+				before, err := s.SchemaVersion(ctx, "books")
+				if err != nil {
+					return err
+				}
+				if before != 6 {
+					return fmt.Errorf("expected schema version 6, got %d", before)
+				}
+
+				if _, err := s.RemoveField(ctx, "books", "pages"); err != nil {
+					return err
+				}
+
+				after, err := s.SchemaVersion(ctx, "books")
+				if err != nil {
+					return err
+				}
+				if after != before+1 {
+					return fmt.Errorf("expected schema version %d, got %d", before+1, after)
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schemas, rws, opts, err := initReadWriters(t, "../testdata/evolution")
+			require.NoError(t, err)
+
+			s := store.New(schemas, rws, opts...)
+
+			err = test.operation(s)
+
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if test.postCheck != nil {
+				err := test.postCheck(s)
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestStoreSchemaChangeFailuresWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/evolution")
+	require.NoError(t, err)
+
+	options := store.NewOptions(opts...)
+
+	failing := true
+	flaky := func(resource string, schemas []v1alpha1.FieldSchema) (readwriter.ReadWriter, error) {
+		if failing {
+			return nil, errors.New("disk full")
+		}
+		return options.Factory(resource, schemas)
+	}
+
+	s := store.New(schemas, rws, append(opts, store.WithReadWriterFactory(flaky))...)
+
+	rows, err := options.SchemaReadWriter.List(ctx)
+	require.NoError(t, err)
+
+	books, err := s.Schema(ctx, "books")
+	require.NoError(t, err)
+
+	// a read/writer that fails to open leaves the schemas as they were
+	_, err = s.CreateResource(ctx, "authors", []v1alpha1.FieldSchema{{Field: "name", Type: "text"}})
+	require.Error(t, err)
+	_, err = s.AddField(ctx, "books", v1alpha1.FieldSchema{Field: "year", Type: "number"})
+	require.Error(t, err)
+	_, err = s.AlterField(ctx, "books", "title", v1alpha1.FieldSchema{Field: "name", Type: "text"})
+	require.Error(t, err)
+	_, err = s.RemoveField(ctx, "books", "pages")
+	require.Error(t, err)
+
+	after, err := options.SchemaReadWriter.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, rows, after)
+
+	unchanged, err := s.Schema(ctx, "books")
+	require.NoError(t, err)
+	require.Equal(t, books, unchanged)
+
+	_, err = s.Schema(ctx, "authors")
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	failing = false
+
+	_, err = s.AddField(ctx, "books", v1alpha1.FieldSchema{Field: "year", Type: "number"})
+	require.NoError(t, err)
+}
+
+func TestStoreRenameOwnerFieldWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/authz")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+
+	bob, err := s.Authenticate(ctx, "bob", "bobpass")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Authorize(ctx, "books", "book123", "update", bob))
+
+	_, err = s.AlterField(ctx, "books", "owner", v1alpha1.FieldSchema{Field: "author", Type: "text", Regex: "^.+$"})
+	require.NoError(t, err)
+
+	// the owner keeps access under the new name
+	require.NoError(t, s.Authorize(ctx, "books", "book123", "update", bob))
+
+	perms, err := s.List(ctx, "_permissions", "")
+	require.NoError(t, err)
+
+	fields := map[string]any{}
+	for _, p := range perms {
+		fields[p["_id"].(string)] = p["field"]
+	}
+	require.Equal(t, "author", fields["p3"])
+	require.Equal(t, "coowners", fields["p5"])
+}
+
+func TestStoreBulkWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/bulk")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+
+	admin, err := s.Authenticate(ctx, "admin", "admin123")
+	require.NoError(t, err)
+
+	bob, err := s.Authenticate(ctx, "bob", "bobpass")
+	require.NoError(t, err)
+
+	// each op sees the records as those before it left them
+	results, err := s.Bulk(ctx, "reviews", []v1alpha1.BulkOp{
+		{Op: v1alpha1.BulkCreate, Record: v1alpha1.Resource{"book": "1984", "body": "Bleak"}},
+		{Op: v1alpha1.BulkCreate, Record: v1alpha1.Resource{"book": "nope", "body": "Lost"}},
+		{Op: v1alpha1.BulkUpdate, Id: "r1", Record: v1alpha1.Resource{"book": "1984", "body": "Chilling, still"}},
+		{Op: v1alpha1.BulkUpdate, Id: "r1", Record: v1alpha1.Resource{"book": "1984", "body": "Chilling, again"}},
+		{Op: v1alpha1.BulkDelete, Id: "r2"},
+		{Op: v1alpha1.BulkDelete, Id: "r2"},
+		{Op: "upsert", Id: "r1"},
+		{Op: v1alpha1.BulkUpdate},
+	}, false, admin)
+	require.NoError(t, err)
+	require.Len(t, results, 8)
+
+	require.NoError(t, results[0].Err)
+	require.NotEmpty(t, results[0].Id)
+	require.ErrorIs(t, results[1].Err, store.ErrRef)
+	require.NoError(t, results[2].Err)
+	require.NoError(t, results[3].Err)
+	require.Equal(t, 3.0, results[3].Record["_v"])
+	require.NoError(t, results[4].Err)
+	require.ErrorIs(t, results[5].Err, store.ErrNotFound)
+	require.ErrorIs(t, results[6].Err, store.ErrInvalid)
+	require.ErrorIs(t, results[7].Err, store.ErrInvalid)
+
+	reviews, err := s.List(ctx, "reviews", "")
+	require.NoError(t, err)
+	require.Len(t, reviews, 2)
+	require.Equal(t, "Chilling, again", reviews[0]["body"])
+	require.Equal(t, 3.0, reviews[0]["_v"])
+	require.Equal(t, "Bleak", reviews[1]["body"])
+
+	// deletes follow the policies of references made earlier in the batch
+	results, err = s.Bulk(ctx, "books", []v1alpha1.BulkOp{
+		{Op: v1alpha1.BulkDelete, Id: "1984"},
+		{Op: v1alpha1.BulkCreate, Record: v1alpha1.Resource{"title": "Homage to Catalonia", "author": "orwell"}},
+		{Op: v1alpha1.BulkDelete, Id: "brave"},
+	}, false, admin)
+	require.NoError(t, err)
+	for _, res := range results {
+		require.NoError(t, res.Err)
+	}
+
+	reviews, err = s.List(ctx, "reviews", "")
+	require.NoError(t, err)
+	require.Empty(t, reviews)
+
+	results, err = s.Bulk(ctx, "authors", []v1alpha1.BulkOp{
+		{Op: v1alpha1.BulkDelete, Id: "orwell"},
+		{Op: v1alpha1.BulkDelete, Id: "huxley"},
+	}, false, admin)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, store.ErrConflict)
+	require.NoError(t, results[1].Err)
+
+	// one failure fails an atomic batch, which then writes nothing
+	before, err := s.Stats(ctx)
+	require.NoError(t, err)
+
+	results, err = s.Bulk(ctx, "authors", []v1alpha1.BulkOp{
+		{Op: v1alpha1.BulkCreate, Record: v1alpha1.Resource{"name": "Orwell's twin"}},
+		{Op: v1alpha1.BulkUpdate, Id: "orwell", Record: v1alpha1.Resource{"name": "Eric Blair"}},
+		{Op: v1alpha1.BulkDelete, Id: "huxley"},
+	}, true, admin)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, store.ErrAborted)
+	require.Empty(t, results[0].Id)
+	require.ErrorIs(t, results[1].Err, store.ErrAborted)
+	require.ErrorIs(t, results[2].Err, store.ErrNotFound)
+
+	after, err := s.Stats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	orwell, err := s.ReadOne(ctx, "authors", "orwell")
+	require.NoError(t, err)
+	require.Equal(t, "George Orwell", orwell["name"])
+
+	// every op is authorized on its own
+	results, err = s.Bulk(ctx, "authors", []v1alpha1.BulkOp{
+		{Op: v1alpha1.BulkUpdate, Id: "orwell", Record: v1alpha1.Resource{"name": "Eric Blair"}},
+	}, false, bob)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, store.ErrAuthz)
+
+	results, err = s.Bulk(ctx, "authors", []v1alpha1.BulkOp{
+		{Op: v1alpha1.BulkUpdate, Id: "orwell", Record: v1alpha1.Resource{"name": "Eric Blair"}},
+	}, false, nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, store.ErrAuthn)
+
+	// the request as a whole
+	_, err = s.Bulk(ctx, "nowhere", nil, false, admin)
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = s.Bulk(ctx, "authors", make([]v1alpha1.BulkOp, 1001), false, admin)
+	require.ErrorIs(t, err, store.ErrTooLarge)
+}
+
+func TestStoreBulkIsWholeWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/bulk")
+	require.NoError(t, err)
+
+	rws["reviews"] = failingDeletes{rws["reviews"]}
+
+	s := store.New(schemas, rws, opts...)
+
+	admin, err := s.Authenticate(ctx, "admin", "admin123")
+	require.NoError(t, err)
+
+	alice, err := s.Authenticate(ctx, "alice", "alicepass")
+	require.NoError(t, err)
+
+	_, err = s.Update(ctx, "books", v1alpha1.Resource{"_id": "1984", "title": "1984", "author": "orwell"})
+	require.NoError(t, err)
+
+	// the books batch goes first, then the cascade to reviews fails, and
+	// the books come back whether or not the request is atomic
+	for _, atomic := range []bool{false, true} {
+		results, err := s.Bulk(ctx, "books", []v1alpha1.BulkOp{
+			{Op: v1alpha1.BulkCreate, Record: v1alpha1.Resource{"title": "Animal Farm", "author": "orwell"}},
+			{Op: v1alpha1.BulkDelete, Id: "1984"},
+		}, atomic, admin)
+		require.NoError(t, err)
+		require.ErrorIs(t, results[0].Err, store.ErrAborted)
+		require.Empty(t, results[0].Id)
+		require.Error(t, results[1].Err)
+		require.NotErrorIs(t, results[1].Err, store.ErrAborted)
+
+		books, err := s.List(ctx, "books", "")
+		require.NoError(t, err)
+		require.Len(t, books, 2)
+
+		book, err := s.ReadOne(ctx, "books", "1984")
+		require.NoError(t, err)
+		require.Equal(t, "1984", book["title"])
+		require.Equal(t, 2.0, book["_v"])
+
+		reviews, err := s.List(ctx, "reviews", "")
+		require.NoError(t, err)
+		require.Len(t, reviews, 2)
+	}
+
+	// alice may delete books but not the reviews a delete cascades to
+	results, err := s.Bulk(ctx, "books", []v1alpha1.BulkOp{
+		{Op: v1alpha1.BulkDelete, Id: "1984"},
+		{Op: v1alpha1.BulkDelete, Id: "brave"},
+	}, false, alice)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, store.ErrAuthz)
+	require.NoError(t, results[1].Err)
+
+	_, err = s.ReadOne(ctx, "books", "1984")
+	require.NoError(t, err)
+
+	_, err = s.ReadOne(ctx, "books", "brave")
+	require.ErrorIs(t, err, store.ErrNotFound)
+}
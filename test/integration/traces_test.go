@@ -0,0 +1,214 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/internal/servers"
+	grpcserver "github.com/w-h-a/backend/internal/servers/grpc"
+	"github.com/w-h-a/backend/internal/services/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var (
+	spanRecorder     *tracetest.SpanRecorder
+	spanRecorderOnce sync.Once
+)
+
+// recordSpans installs a global tracer provider that keeps every span. It
+// is only installed once: tracers taken before that follow the first
+// provider set, whatever comes later.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	spanRecorderOnce.Do(func() {
+		spanRecorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+
+	return spanRecorder
+}
+
+// spansOf returns the ended spans of a trace by name.
+func spansOf(rec *tracetest.SpanRecorder, traceID string) map[string][]sdktrace.ReadOnlySpan {
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+
+	for _, span := range rec.Ended() {
+		if span.SpanContext().TraceID().String() == traceID {
+			spans[span.Name()] = append(spans[span.Name()], span)
+		}
+	}
+
+	return spans
+}
+
+func attrsOf(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestHTTPTracesWithCSVRW(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	rec := recordSpans(t)
+
+	schemas, rws, opts, err := initReadWriters(t, "../testdata/meta")
+	require.NoError(t, err)
+
+	s := store.New(schemas, rws, opts...)
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	srv, err := initHttpServer(t, s)
+	require.NoError(t, err)
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+
+	get := func(path string, traceparent string) int {
+		req, err := http.NewRequest(http.MethodGet, "http://localhost:4000"+path, nil)
+		require.NoError(t, err)
+		req.SetBasicAuth("user1", "user1pass")
+		req.Header.Set("traceparent", traceparent)
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		rsp.Body.Close()
+
+		return rsp.StatusCode
+	}
+
+	// the trace of the caller goes on through the handler, the store and
+	// the read/writers
+	require.Equal(t, http.StatusOK, get("/api/notes", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"))
+
+	require.Eventually(t, func() bool {
+		return len(spansOf(rec, "0af7651916cd43dd8448eb211c80319c")["GET /api/{resource}"]) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	spans := spansOf(rec, "0af7651916cd43dd8448eb211c80319c")
+
+	server := spans["GET /api/{resource}"][0]
+	require.Equal(t, "b7ad6b7169203331", server.Parent().SpanID().String())
+	require.Equal(t, "/api/{resource}", attrsOf(server)["http.route"].AsString())
+
+	require.Len(t, spans["store.Authenticate"], 1)
+	require.Equal(t, "user1", attrsOf(spans["store.Authenticate"][0])["user.id"].AsString())
+
+	authz := attrsOf(spans["store.Authorize"][0])
+	require.Equal(t, "notes", authz["resource.name"].AsString())
+	require.True(t, authz["authz.allowed"].AsBool())
+	require.Equal(t, "public", authz["authz.rule"].AsString())
+
+	require.Len(t, spans["store.List"], 1)
+	require.Equal(t, server.SpanContext().SpanID(), spans["store.List"][0].Parent().SpanID())
+
+	lists := map[string]bool{}
+	for _, span := range spans["readwriter.List"] {
+		lists[attrsOf(span)["resource.name"].AsString()] = true
+	}
+	require.Equal(t, map[string]bool{"_permissions": true, "notes": true}, lists)
+
+	// a denied request is an answer, not an error
+	require.Equal(t, http.StatusForbidden, get("/api/secrets", "00-1af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"))
+
+	require.Eventually(t, func() bool {
+		return len(spansOf(rec, "1af7651916cd43dd8448eb211c80319c")["GET /api/{resource}"]) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	denied := spansOf(rec, "1af7651916cd43dd8448eb211c80319c")["store.Authorize"][0]
+	require.False(t, attrsOf(denied)["authz.allowed"].AsBool())
+	require.NotEqual(t, "Error", denied.Status().Code.String())
+	require.Len(t, denied.Events(), 1) // the error is still recorded
+}
+
+// echoDesc is a gRPC service for tests that answers with what it was sent,
+// after reporting the trace it runs in.
+func echoDesc(traceIDs chan<- string) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "backend.test.Echo",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := &emptypb.Empty{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					traceIDs <- trace.SpanContextFromContext(ctx).TraceID().String()
+					return req, nil
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: "/backend.test.Echo/Echo"}, handler)
+			},
+		}},
+	}
+}
+
+func TestGRPCTraces(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) == 0 {
+		t.Log("SKIPPING INTEGRATION TEST")
+		return
+	}
+
+	rec := recordSpans(t)
+
+	intercepted := false
+
+	srv := grpcserver.NewServer(
+		servers.WithAddress(":4001"),
+		grpcserver.WithUnaryInterceptors(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			intercepted = true
+			return handler(ctx, req)
+		}),
+	)
+
+	traceIDs := make(chan string, 1)
+	require.NoError(t, srv.Handle(grpcserver.GrpcServiceRegistration{Desc: echoDesc(traceIDs), Impl: struct{}{}}))
+
+	require.NoError(t, srv.Start())
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("localhost:4001", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "traceparent", "00-2af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	require.NoError(t, conn.Invoke(ctx, "/backend.test.Echo/Echo", &emptypb.Empty{}, &emptypb.Empty{}))
+
+	// the handler runs in the trace of the caller
+	require.Equal(t, "2af7651916cd43dd8448eb211c80319c", <-traceIDs)
+	require.True(t, intercepted)
+
+	require.Eventually(t, func() bool {
+		return len(spansOf(rec, "2af7651916cd43dd8448eb211c80319c")["backend.test.Echo/Echo"]) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	span := spansOf(rec, "2af7651916cd43dd8448eb211c80319c")["backend.test.Echo/Echo"][0]
+	require.Equal(t, trace.SpanKindServer, span.SpanKind())
+	require.Equal(t, "b7ad6b7169203331", span.Parent().SpanID().String())
+}
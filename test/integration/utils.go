@@ -2,42 +2,47 @@ package integration
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
 	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"testing"
 
-	"github.com/gorilla/mux"
 	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/blob"
+	"github.com/w-h-a/backend/internal/clients/blob/local"
 	"github.com/w-h-a/backend/internal/clients/readwriter"
 	"github.com/w-h-a/backend/internal/clients/readwriter/csv"
+	"github.com/w-h-a/backend/internal/clients/writer"
 	httphandlers "github.com/w-h-a/backend/internal/handlers/http"
 	"github.com/w-h-a/backend/internal/servers"
 	httpserver "github.com/w-h-a/backend/internal/servers/http"
 	"github.com/w-h-a/backend/internal/services/store"
 )
 
-func initHttpServer(t *testing.T, schemas map[string][]v1alpha1.FieldSchema, s *store.Store) (servers.Server, error) {
+func initHttpServer(t *testing.T, s *store.Store) (servers.Server, error) {
 	t.Helper()
 
+	// keep-alive connections to a stopped server must not leak into the next test
+	t.Cleanup(http.DefaultClient.CloseIdleConnections)
+
 	srv := httpserver.NewServer(
 		servers.WithAddress(":4000"),
 		httpserver.WithMiddleware(
+			httphandlers.NewTraceMiddleware(),
+			httphandlers.NewRequestIDMiddleware(),
+			httphandlers.NewLogMiddleware(),
+			httphandlers.NewRecoverMiddleware(),
+			httphandlers.NewMetricsMiddleware(),
 			httphandlers.NewAuthMiddleware(s),
 		),
 	)
 
-	router := mux.NewRouter()
-
-	handler := httphandlers.NewHandler(schemas, s)
-
-	router.HandleFunc("/api/{resource}", handler.ListRecords).Methods(http.MethodGet)
-	router.HandleFunc("/api/{resource}/{id}", handler.GetRecord).Methods(http.MethodGet)
-	router.HandleFunc("/api/{resource}", handler.CreateRecord).Methods(http.MethodPost)
-	router.HandleFunc("/api/{resource}/{id}", handler.UpdateRecord).Methods(http.MethodPut)
-	router.HandleFunc("/api/{resource}/{id}", handler.DeleteRecord).Methods(http.MethodDelete)
+	router := httphandlers.NewRouter(s)
 
 	if err := srv.Handle(router); err != nil {
 		return nil, err
@@ -46,78 +51,54 @@ func initHttpServer(t *testing.T, schemas map[string][]v1alpha1.FieldSchema, s *
 	return srv, nil
 }
 
-func initReadWriters(t *testing.T, dir string) (map[string][]v1alpha1.FieldSchema, map[string]readwriter.ReadWriter, error) {
+// initReadWriters copies dir to a temporary directory and opens its
+// resources. The returned options let the store change schemas there.
+func initReadWriters(t *testing.T, dir string) (map[string][]v1alpha1.FieldSchema, map[string]readwriter.ReadWriter, []store.Option, error) {
 	t.Helper()
 
 	schemas := map[string][]v1alpha1.FieldSchema{}
-	resourceData := map[string][]struct {
-		FieldSchema v1alpha1.FieldSchema
-		Index       int
-	}{}
 
 	dir = testData(t, dir)
 
 	schemaRW := csv.NewReadWriter(
-		readwriter.WithLocation(dir + "/_schemas.csv"),
+		readwriter.WithLocation(dir+"/_schemas.csv"),
+		readwriter.WithFieldSchemas(v1alpha1.SchemaFields),
 	)
 
 	recs, err := schemaRW.List(context.Background())
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	for _, rec := range recs {
-		schema := v1alpha1.FieldSchema{
-			Resource: rec[2],
-			Field:    rec[3],
-			Type:     rec[4],
-			Regex:    rec[7],
-		}
-
-		schema.Min, _ = strconv.ParseFloat(rec[5], 64)
-		schema.Max, _ = strconv.ParseFloat(rec[6], 64)
+		schema := v1alpha1.ToFieldSchema(rec)
 
 		schemas[schema.Resource] = append(schemas[schema.Resource], schema)
+	}
 
-		index := len(resourceData[schema.Resource])
-
-		resourceData[schema.Resource] = append(resourceData[schema.Resource], struct {
-			FieldSchema v1alpha1.FieldSchema
-			Index       int
-		}{
-			FieldSchema: schema,
-			Index:       index,
-		})
+	factory := func(resource string, schemas []v1alpha1.FieldSchema) (readwriter.ReadWriter, error) {
+		return csv.NewReadWriter(
+			readwriter.WithLocation(dir+"/"+resource+".csv"),
+			readwriter.WithFieldSchemas(schemas),
+		), nil
 	}
 
 	rws := map[string]readwriter.ReadWriter{}
 
-	for name, dataList := range resourceData {
-		schema := map[string]struct {
-			Index int
-			Type  string
-		}{}
-
-		for _, data := range dataList {
-			schema[data.FieldSchema.Field] = struct {
-				Index int
-				Type  string
-			}{
-				Index: data.Index,
-				Type:  data.FieldSchema.Type,
-			}
+	for name, fields := range schemas {
+		rw, err := factory(name, fields)
+		if err != nil {
+			return nil, nil, nil, err
 		}
+		rws[name] = rw
+	}
 
-		if _, ok := rws[name]; !ok {
-			rw := csv.NewReadWriter(
-				readwriter.WithLocation(dir+"/"+name+".csv"),
-				readwriter.WithSchema(schema),
-			)
-			rws[name] = rw
-		}
+	opts := []store.Option{
+		store.WithSchemaReadWriter(schemaRW),
+		store.WithReadWriterFactory(factory),
 	}
 
-	return schemas, rws, nil
+	return schemas, rws, opts, nil
 }
 
 func testData(t *testing.T, src string) string {
@@ -150,3 +131,44 @@ func testData(t *testing.T, src string) string {
 
 	return dst
 }
+
+func initBlob(t *testing.T) blob.Blob {
+	t.Helper()
+
+	return local.NewBlob(
+		blob.WithLocation(t.TempDir()),
+	)
+}
+
+// failingDeletes fails every delete. It is no BatchWriter, so batches to it
+// are written one op at a time.
+type failingDeletes struct {
+	readwriter.ReadWriter
+}
+
+func (failingDeletes) Delete(context.Context, string, ...writer.DeleteOption) error {
+	return errors.New("disk full")
+}
+
+// gatedBlob holds the next put, when next is set, until next is closed, as
+// a slow upload would, and says on started once it holds it.
+type gatedBlob struct {
+	blob.Blob
+	next    chan struct{}
+	started chan struct{}
+}
+
+func (b *gatedBlob) Put(ctx context.Context, r io.Reader, opts ...blob.PutOption) (string, error) {
+	if gate := b.next; gate != nil {
+		b.next = nil
+		b.started <- struct{}{}
+		<-gate
+	}
+	return b.Blob.Put(ctx, r, opts...)
+}
+
+// hashOf is the key content is stored under in a blob store.
+func hashOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+package unit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+func TestAggregateValidate(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	schema := []v1alpha1.FieldSchema{
+		{Field: "_id", Type: "text"},
+		{Field: "title", Type: "text"},
+		{Field: "author", Type: "ref(authors)"},
+		{Field: "year", Type: "number"},
+		{Field: "gone", Type: "number", Dropped: true},
+		{Field: "tags", Type: "list"},
+	}
+
+	tests := []struct {
+		name string
+		agg  v1alpha1.Aggregate
+		err  string
+	}{
+		{name: "count", agg: v1alpha1.Aggregate{Count: true}},
+		{name: "grouped", agg: v1alpha1.Aggregate{GroupBy: []string{"author", "year"}, Count: true, Sum: []string{"year"}, Avg: []string{"year"}, Min: []string{"title"}, Max: []string{"author"}}},
+		{name: "nothing asked", agg: v1alpha1.Aggregate{GroupBy: []string{"author"}}, err: "expected count, sum, avg, min or max"},
+		{name: "unknown field", agg: v1alpha1.Aggregate{Sum: []string{"pages"}}, err: `unknown field "pages" for sum`},
+		{name: "dropped field", agg: v1alpha1.Aggregate{Max: []string{"gone"}}, err: `unknown field "gone" for max`},
+		{name: "text sum", agg: v1alpha1.Aggregate{Avg: []string{"title"}}, err: `field "title" is a text, which avg cannot take`},
+		{name: "list group", agg: v1alpha1.Aggregate{GroupBy: []string{"tags"}, Count: true}, err: `field "tags" is a list, which group_by cannot take`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.agg.Validate(schema)
+			if len(tt.err) > 0 {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	require.Equal(t, []string{"author", "year", "title"}, v1alpha1.Aggregate{GroupBy: []string{"author"}, Sum: []string{"year"}, Min: []string{"title", "year"}}.Fields())
+}
@@ -0,0 +1,242 @@
+package unit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/readwriter/csv"
+	"github.com/w-h-a/backend/internal/clients/readwriter/memory"
+	"github.com/w-h-a/backend/internal/clients/writer"
+)
+
+func TestReadWriterBatch(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	layout := []v1alpha1.FieldSchema{
+		{Resource: "notes", Field: "_id", Type: "text"},
+		{Resource: "notes", Field: "_v", Type: "number"},
+		{Resource: "notes", Field: "title", Type: "text"},
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.csv")
+
+	rws := map[string]func() readwriter.ReadWriter{
+		"csv": func() readwriter.ReadWriter {
+			return csv.NewReadWriter(readwriter.WithLocation(path), readwriter.WithFieldSchemas(layout))
+		},
+		"memory": func() readwriter.ReadWriter {
+			return memory.NewReadWriter(readwriter.WithFieldSchemas(layout))
+		},
+	}
+
+	for name, open := range rws {
+		t.Run(name, func(t *testing.T) {
+			rw := readwriter.Traced(open(), "notes")
+			defer rw.Close(ctx)
+
+			require.NoError(t, rw.Create(ctx, v1alpha1.Record{"a", "", "first"}))
+
+			// later ops see the earlier ones
+			require.NoError(t, readwriter.WriteBatch(ctx, rw, []writer.Op{
+				{Action: writer.OpCreate, Id: "b", Record: v1alpha1.Record{"b", "", "second"}},
+				{Action: writer.OpUpdate, Id: "a", Record: v1alpha1.Record{"a", "", "first, again"}},
+				{Action: writer.OpUpdate, Id: "b", Record: v1alpha1.Record{"b", "", "second, again"}},
+				{Action: writer.OpCreate, Id: "c", Record: v1alpha1.Record{"c", "", "third"}},
+				{Action: writer.OpDelete, Id: "c"},
+			}))
+
+			recs, err := rw.List(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []v1alpha1.Record{{"a", "2", "first, again"}, {"b", "2", "second, again"}}, recs)
+
+			_, err = rw.ReadOne(ctx, "c")
+			require.Error(t, err)
+
+			// one invalid op and none is applied
+			err = readwriter.WriteBatch(ctx, rw, []writer.Op{
+				{Action: writer.OpDelete, Id: "a"},
+				{Action: writer.OpDelete, Id: "a"},
+			})
+			var batchErr *writer.BatchError
+			require.ErrorAs(t, err, &batchErr)
+			require.Equal(t, 1, batchErr.Index)
+			require.ErrorIs(t, err, writer.ErrNotFound)
+
+			// nor are the records of its ops changed
+			ops := []writer.Op{
+				{Action: writer.OpUpdate, Id: "a", Record: v1alpha1.Record{"a", "", "first, once more"}},
+				{Action: writer.OpCreate, Id: "d", Record: v1alpha1.Record{"d", ""}},
+			}
+			err = readwriter.WriteBatch(ctx, rw, ops)
+			require.ErrorAs(t, err, &batchErr)
+			require.Equal(t, 1, batchErr.Index)
+			require.Equal(t, v1alpha1.Record{"a", "", "first, once more"}, ops[0].Record)
+
+			after, err := rw.List(ctx)
+			require.NoError(t, err)
+			require.Equal(t, recs, after)
+		})
+	}
+
+	// the rows of a batch are appended as any others, and read back the same
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 6, strings.Count(string(data), "\n"))
+
+	rw := csv.NewReadWriter(readwriter.WithLocation(path), readwriter.WithFieldSchemas(layout))
+	defer rw.Close(ctx)
+
+	recs, err := rw.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []v1alpha1.Record{{"a", "2", "first, again"}, {"b", "2", "second, again"}}, recs)
+}
+
+func TestReadWriterBatchRestore(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	layout := []v1alpha1.FieldSchema{
+		{Resource: "notes", Field: "_id", Type: "text"},
+		{Resource: "notes", Field: "_v", Type: "number"},
+		{Resource: "notes", Field: "title", Type: "text"},
+	}
+
+	rws := map[string]func() readwriter.ReadWriter{
+		"csv": func() readwriter.ReadWriter {
+			path := filepath.Join(t.TempDir(), "notes.csv")
+			return csv.NewReadWriter(readwriter.WithLocation(path), readwriter.WithFieldSchemas(layout))
+		},
+		"memory": func() readwriter.ReadWriter {
+			return memory.NewReadWriter(readwriter.WithFieldSchemas(layout))
+		},
+	}
+
+	for name, open := range rws {
+		t.Run(name, func(t *testing.T) {
+			rw := open()
+			defer rw.Close(ctx)
+
+			require.NoError(t, rw.Create(ctx, v1alpha1.Record{"a", "", "first"}))
+			require.NoError(t, rw.Update(ctx, v1alpha1.Record{"a", "", "first, again"}))
+			require.NoError(t, rw.Create(ctx, v1alpha1.Record{"b", "", "second"}))
+			require.NoError(t, rw.Update(ctx, v1alpha1.Record{"b", "", "second, again"}))
+
+			before, err := rw.List(ctx)
+			require.NoError(t, err)
+
+			ops := []writer.Op{
+				{Action: writer.OpUpdate, Id: "a", Record: v1alpha1.Record{"a", "3", "first, once more"}},
+				{Action: writer.OpDelete, Id: "b"},
+				{Action: writer.OpCreate, Id: "c", Record: v1alpha1.Record{"c", "1", "third"}},
+			}
+
+			inverse, err := readwriter.Inverse(ctx, rw, ops)
+			require.NoError(t, err)
+
+			require.NoError(t, readwriter.WriteBatch(ctx, rw, ops))
+
+			// taken back, the records are as they were, versions included
+			require.NoError(t, readwriter.WriteBatch(ctx, rw, readwriter.Undo(inverse), writer.WithRestore()))
+
+			after, err := rw.List(ctx)
+			require.NoError(t, err)
+			require.Equal(t, before, after)
+
+			// a record restored without a version is refused
+			err = readwriter.WriteBatch(ctx, rw, []writer.Op{
+				{Action: writer.OpUpdate, Id: "a", Record: v1alpha1.Record{"a", "", "first"}},
+			}, writer.WithRestore())
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCSVCreateAfterDelete(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	layout := []v1alpha1.FieldSchema{
+		{Resource: "notes", Field: "_id", Type: "text"},
+		{Resource: "notes", Field: "_v", Type: "number"},
+		{Resource: "notes", Field: "title", Type: "text"},
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.csv")
+
+	rw := csv.NewReadWriter(readwriter.WithLocation(path), readwriter.WithFieldSchemas(layout))
+	defer rw.Close(ctx)
+
+	// a record created again after its delete numbers its versions from 1
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"a", "", "first"}))
+	require.NoError(t, rw.Delete(ctx, "a"))
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"a", "", "again"}))
+
+	recs, err := rw.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []v1alpha1.Record{{"a", "1", "again"}}, recs)
+}
+
+// oneAtATime hides the Batch of the read/writer it wraps.
+type oneAtATime struct {
+	readwriter.ReadWriter
+}
+
+func TestWriteBatchOneAtATime(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	layout := []v1alpha1.FieldSchema{
+		{Resource: "notes", Field: "_id", Type: "text"},
+		{Resource: "notes", Field: "_v", Type: "number"},
+		{Resource: "notes", Field: "title", Type: "text"},
+	}
+
+	rw := oneAtATime{memory.NewReadWriter(readwriter.WithFieldSchemas(layout))}
+	defer rw.Close(ctx)
+
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"a", "", "first"}))
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"b", "", "second"}))
+
+	// the ops before the one that fails are taken back
+	err := readwriter.WriteBatch(ctx, rw, []writer.Op{
+		{Action: writer.OpUpdate, Id: "a", Record: v1alpha1.Record{"a", "", "first, again"}},
+		{Action: writer.OpDelete, Id: "b"},
+		{Action: writer.OpCreate, Id: "c", Record: v1alpha1.Record{"c", "", "third"}},
+		{Action: writer.OpDelete, Id: "d"},
+	})
+	var batchErr *writer.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, 3, batchErr.Index)
+
+	recs, err := rw.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+	require.Equal(t, "first", recs[0][2])
+	require.Equal(t, "second", recs[1][2])
+
+	_, err = rw.ReadOne(ctx, "c")
+	require.Error(t, err)
+}
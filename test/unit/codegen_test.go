@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/codegen"
+)
+
+var codegenSchemas = map[string][]v1alpha1.FieldSchema{
+	"places": {
+		{Resource: "places", Field: "_id", Type: "text"},
+		{Resource: "places", Field: "_v", Type: "number"},
+		{Resource: "places", Field: "name", Type: "text", Regex: "^.+$", Required: true},
+		{Resource: "places", Field: "location", Type: "geopoint"},
+		{Resource: "places", Field: "owner", Type: "ref:_users", Nullable: true},
+		{Resource: "places", Field: "photo", Type: "file"},
+		{Resource: "places", Field: "embedding", Type: "vector(3)"},
+		{Resource: "places", Field: "tags", Type: "list"},
+		{Resource: "places", Field: "rating", Type: "number", Default: "3"},
+		{Resource: "places", Field: "closed", Type: "number", Dropped: true},
+	},
+	"line_items": {
+		{Resource: "line_items", Field: "_id", Type: "text"},
+		{Resource: "line_items", Field: "_v", Type: "number"},
+		{Resource: "line_items", Field: "unit-price", Type: "number", Required: true},
+	},
+}
+
+func TestCodegenGo(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	files, err := codegen.Go(codegenSchemas, "client")
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// the generated package type-checks on its own
+	fset := token.NewFileSet()
+	asts := []*ast.File{}
+	for name, src := range files {
+		f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		require.NoError(t, err)
+		asts = append(asts, f)
+	}
+
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("client", fset, asts, nil)
+	require.NoError(t, err)
+
+	places := pkg.Scope().Lookup("Places").Type().Underlying().(*types.Struct)
+
+	fields := map[string]string{}
+	tags := map[string]string{}
+	for i := 0; i < places.NumFields(); i++ {
+		fields[places.Field(i).Name()] = places.Field(i).Type().String()
+		tags[places.Field(i).Name()] = places.Tag(i)
+	}
+
+	require.Equal(t, map[string]string{
+		"Id":        "string",
+		"Version":   "int",
+		"Name":      "string",
+		"Location":  "*client.GeoPoint",
+		"Owner":     "*string",
+		"Photo":     "*client.File",
+		"Embedding": "[]float64",
+		"Tags":      "[]string",
+		"Rating":    "*float64",
+	}, fields)
+	require.Equal(t, `json:"name"`, tags["Name"])
+	require.Equal(t, `json:"rating,omitempty"`, tags["Rating"])
+
+	// geo filters only where there is a geopoint field
+	require.NotNil(t, pkg.Scope().Lookup("PlacesListOptions").Type().Underlying().(*types.Struct).Field(1))
+	require.Equal(t, 1, pkg.Scope().Lookup("LineItemsListOptions").Type().Underlying().(*types.Struct).NumFields())
+
+	for _, name := range []string{"ListLineItems", "GetLineItems", "CreateLineItems", "UpdateLineItems", "DeleteLineItems"} {
+		_, _, method := types.LookupFieldOrMethod(types.NewPointer(pkg.Scope().Lookup("Client").Type()), false, pkg, name)
+		require.NotNil(t, method, name)
+	}
+
+	require.NotNil(t, pkg.Scope().Lookup("LineItemsFieldUnitPrice"))
+}
+
+func TestCodegenTypeScript(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	files, err := codegen.TypeScript(codegenSchemas)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	models := string(files["models.ts"])
+
+	for _, line := range []string{
+		"export interface Places {",
+		"  name: string;",
+		"  location?: GeoPoint;",
+		"  owner?: string | null;",
+		"  embedding?: number[];",
+		`  "unit-price": number;`,
+		"export type PlacesField =",
+		"  geoField?: PlacesField;",
+	} {
+		require.Contains(t, models, line+"\n")
+	}
+
+	require.NotContains(t, models, "closed")
+
+	client := string(files["client.ts"])
+
+	for _, line := range []string{
+		"  listLineItems(opts: LineItemsListOptions = {}): Promise<LineItems[]> {",
+		"  async createPlaces(r: Places): Promise<string> {",
+		"    setGeoQuery(q, opts.geoField, opts.near, opts.radius, opts.bbox);",
+	} {
+		require.Contains(t, client, line+"\n")
+	}
+
+	require.Equal(t, 1, strings.Count(client, "setGeoQuery(q,"))
+}
@@ -0,0 +1,164 @@
+package unit
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+	"github.com/w-h-a/backend/cmd"
+	"github.com/w-h-a/backend/pkg/backend"
+)
+
+func loadConfig(t *testing.T, args ...string) (cmd.Config, error) {
+	t.Helper()
+
+	var config cmd.Config
+
+	app := &cli.App{
+		Flags: cmd.ConfigFlags(),
+		Action: func(ctx *cli.Context) (err error) {
+			config, err = cmd.LoadConfig(ctx)
+			return err
+		},
+	}
+
+	err := app.Run(append([]string{"backend"}, args...))
+
+	return config, err
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "backend.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`dir: from-file
+http_addr: ":7000"
+grpc_addr: ":7001"
+read_timeout: 5s
+max_body_size: 512KiB
+backends:
+  todos: memory
+route_timeouts:
+  /api/{resource}/_knn: 5s
+rate_limit: 10/s
+resource_rate_limits:
+  notes:create: 5/m
+`), 0644))
+
+	t.Setenv("BACKEND_CONFIG", path)
+	t.Setenv("BACKEND_HTTP_ADDR", ":8000")
+	t.Setenv("BACKEND_LOG_LEVEL", "warn")
+
+	config, err := loadConfig(t, "--http-addr", ":9000", "--dir", "from-flag")
+	require.NoError(t, err)
+
+	// flags win over the environment, which wins over the file
+	require.Equal(t, "from-flag", config.Dir)
+	require.Equal(t, ":9000", config.HTTPAddr)
+	require.Equal(t, slog.LevelWarn, config.LogLevel)
+	require.Equal(t, ":7001", config.GRPCAddr)
+	require.Equal(t, 5*time.Second, config.ReadTimeout)
+	require.Equal(t, map[string]string{"todos": "memory"}, config.Backends)
+	require.Equal(t, int64(512<<10), config.MaxBodyBytes)
+	require.Equal(t, map[string]time.Duration{"/api/{resource}/_knn": 5 * time.Second}, config.RouteTimeouts)
+	require.Equal(t, backend.RateLimit{Count: 10, Per: time.Second}, config.RateLimit)
+	require.Equal(t, map[string]backend.RateLimit{"notes:create": {Count: 5, Per: time.Minute}}, config.RateLimits)
+
+	// and the file wins over the defaults
+	require.Equal(t, 60*time.Second, config.WriteTimeout)
+	require.Equal(t, 10*time.Second, config.ShutdownTimeout)
+	require.Equal(t, 30*time.Second, config.RequestTimeout)
+	require.Empty(t, config.CORSOrigins)
+	require.Equal(t, 5, config.LoginAttempts)
+	require.Equal(t, 30*time.Second, config.LoginLockout)
+
+	config, err = loadConfig(t, "--backend", "notes=csv", "--backend", "drafts=memory", "--cors-origins", "https://a.example.com, https://b.example.com")
+	require.NoError(t, err)
+	require.Equal(t, ":8000", config.HTTPAddr)
+	require.Equal(t, map[string]string{"notes": "csv", "drafts": "memory"}, config.Backends)
+	require.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, config.CORSOrigins)
+
+	t.Setenv("BACKEND_BACKENDS", "notes=csv, drafts=memory")
+
+	config, err = loadConfig(t)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"notes": "csv", "drafts": "memory"}, config.Backends)
+}
+
+func TestConfigErrors(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	_, err := loadConfig(t, "--read-timeout", "soon", "--backend", "todos=redis", "--tls-cert", "cert.pem")
+	require.ErrorContains(t, err, `read_timeout "soon" (from flag --read-timeout)`)
+	require.ErrorContains(t, err, `backend "redis" of todos (from flag --backend): expected csv or memory`)
+	require.ErrorContains(t, err, "tls_cert and tls_key must be set together")
+
+	_, err = loadConfig(t, "--max-body-size", "1MB", "--route-timeout", "/api/{resource}=later", "--route-timeout", "/api")
+	require.ErrorContains(t, err, `max_body_size "1MB" (from flag --max-body-size): expected a number of bytes, like 512KiB`)
+	require.ErrorContains(t, err, `route-timeout "later" of /api/{resource} (from flag --route-timeout)`)
+	require.ErrorContains(t, err, `route-timeout "/api" (from flag --route-timeout): expected ROUTE=DURATION`)
+
+	_, err = loadConfig(t, "--rate-limit", "fast", "--login-attempts", "-1", "--resource-rate-limit", "notes:create=10/week")
+	require.ErrorContains(t, err, `rate_limit "fast" (from flag --rate-limit): expected COUNT/UNIT, like 100/m`)
+	require.ErrorContains(t, err, `login_attempts "-1" (from flag --login-attempts): expected a count of 0 or more`)
+	require.ErrorContains(t, err, `resource-rate-limit "10/week" of notes:create (from flag --resource-rate-limit)`)
+
+	path := filepath.Join(t.TempDir(), "backend.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("http_adr: \":7000\"\n"), 0644))
+
+	_, err = loadConfig(t, "--config", path)
+	require.ErrorContains(t, err, `unknown setting "http_adr"`)
+}
+
+func TestPrintConfig(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	var out bytes.Buffer
+
+	app := &cli.App{
+		Writer: &out,
+		Flags:  cmd.ConfigFlags(),
+		Action: cmd.PrintConfig,
+	}
+
+	require.NoError(t, app.Run([]string{"backend", "--grpc-addr", ":9001", "--backend", "todos=memory"}))
+
+	printed := out.String()
+	require.Contains(t, printed, `grpc_addr: ":9001" # flag --grpc-addr`)
+	require.Contains(t, printed, `http_addr: ":4000" # default`)
+	require.Contains(t, printed, "backends: # flag --backend\n  \"todos\": \"memory\"\n")
+
+	// what is printed reads back as a config file
+	path := filepath.Join(t.TempDir(), "backend.yaml")
+	require.NoError(t, os.WriteFile(path, out.Bytes(), 0644))
+
+	config, err := loadConfig(t, "--config", path)
+	require.NoError(t, err)
+	require.Equal(t, ":9001", config.GRPCAddr)
+	require.Equal(t, map[string]string{"todos": "memory"}, config.Backends)
+	require.Equal(t, 2*time.Minute, config.IdleTimeout)
+
+	// a flag is reported as a flag even when the environment agrees with it
+	t.Setenv("BACKEND_GRPC_ADDR", ":9001")
+	t.Setenv("BACKEND_HTTP_ADDR", ":8000")
+	out.Reset()
+	app.Flags = cmd.ConfigFlags()
+
+	require.NoError(t, app.Run([]string{"backend", "--grpc-addr", ":9001", "--backend", "todos=memory"}))
+	require.Contains(t, out.String(), `grpc_addr: ":9001" # flag --grpc-addr`)
+	require.Contains(t, out.String(), `http_addr: ":8000" # env BACKEND_HTTP_ADDR`)
+}
@@ -527,3 +527,286 @@ func TestEdgeCase(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, len(res) == 0)
 }
+
+func TestParseResourceMissingFields(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	testSchema := []v1alpha1.FieldSchema{
+		{Field: "_id", Type: "text"},
+		{Field: "_v", Type: "number", Min: 1},
+		{Field: "title", Type: "text", Required: true},
+		{Field: "rating", Type: "number", Nullable: true},
+		{Field: "status", Type: "text", Default: "open"},
+		{Field: "labels", Type: "list", Nullable: true, Default: "new"},
+	}
+
+	tests := []struct {
+		name     string
+		resource v1alpha1.Resource
+		expected v1alpha1.Resource
+		err      string
+	}{
+		{
+			name:     "defaults and nulls",
+			resource: v1alpha1.Resource{"title": "Hello"},
+			expected: v1alpha1.Resource{
+				"title":  "Hello",
+				"rating": nil,
+				"status": "open",
+				"labels": []string{"new"},
+			},
+		},
+		{
+			name:     "explicit null on nullable field skips default",
+			resource: v1alpha1.Resource{"title": "Hello", "labels": nil},
+			expected: v1alpha1.Resource{
+				"title":  "Hello",
+				"rating": nil,
+				"status": "open",
+				"labels": nil,
+			},
+		},
+		{
+			name:     "explicit null on non-nullable field",
+			resource: v1alpha1.Resource{"title": "Hello", "status": nil},
+			err:      v1alpha1.RuleNullable,
+		},
+		{
+			name:     "zero is not null",
+			resource: v1alpha1.Resource{"title": "", "rating": 0.0},
+			expected: v1alpha1.Resource{
+				"title":  "",
+				"rating": 0.0,
+				"status": "open",
+				"labels": []string{"new"},
+			},
+		},
+		{
+			name:     "missing required field",
+			resource: v1alpha1.Resource{"rating": 3.0},
+			err:      v1alpha1.RuleRequired,
+		},
+		{
+			name:     "null required field",
+			resource: v1alpha1.Resource{"title": nil},
+			err:      v1alpha1.RuleNullable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := v1alpha1.ParseResource(testSchema, test.resource)
+			if len(test.err) > 0 {
+				var invalid *v1alpha1.ValidationError
+				require.ErrorAs(t, err, &invalid)
+				require.Len(t, invalid.Errors, 1)
+				require.Equal(t, test.err, invalid.Errors[0].Rule)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, parsed)
+			}
+		})
+	}
+}
+
+func TestNullRoundTrip(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	testSchema := []v1alpha1.FieldSchema{
+		{Field: "_id", Type: "text"},
+		{Field: "_v", Type: "number", Min: 1},
+		{Field: "note", Type: "text", Nullable: true},
+		{Field: "score", Type: "number", Nullable: true},
+		{Field: "tags", Type: "list", Nullable: true},
+	}
+
+	tests := []struct {
+		name     string
+		resource v1alpha1.Resource
+		record   v1alpha1.Record
+	}{
+		{
+			name:     "nulls",
+			resource: v1alpha1.Resource{"_id": "a", "_v": 1.0, "note": nil, "score": nil, "tags": nil},
+			record:   v1alpha1.Record{"a", "1", `\N`, `\N`, `\N`},
+		},
+		{
+			name:     "empty values are not null",
+			resource: v1alpha1.Resource{"_id": "a", "_v": 1.0, "note": "", "score": 0.0, "tags": []string{}},
+			record:   v1alpha1.Record{"a", "1", "", "0", ""},
+		},
+		{
+			name:     "literal null marker is escaped",
+			resource: v1alpha1.Resource{"_id": "a", "_v": 1.0, "note": `\N`, "score": 1.0, "tags": []string{`\\N`}},
+			record:   v1alpha1.Record{"a", "1", `\\N`, "1", `\\\N`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec, err := v1alpha1.ToRecord(testSchema, test.resource)
+			require.NoError(t, err)
+			require.Equal(t, test.record, rec)
+
+			res, err := v1alpha1.ToResource(testSchema, rec)
+			require.NoError(t, err)
+			require.Equal(t, test.resource, res)
+		})
+	}
+}
+
+func TestToFieldSchema(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	fs := v1alpha1.ToFieldSchema(v1alpha1.Record{"s1", "1", "todo", "due", "number", "0", "10", "", "true", "1", "now()"})
+	require.Equal(t, v1alpha1.FieldSchema{
+		Id:       "s1",
+		Version:  1,
+		Resource: "todo",
+		Field:    "due",
+		Type:     "number",
+		Min:      0,
+		Max:      10,
+		Required: true,
+		Nullable: true,
+		Default:  "now()",
+	}, fs)
+
+	legacy := v1alpha1.ToFieldSchema(v1alpha1.Record{"s1", "1", "todo", "title", "text", "", "", "^.+$"})
+	require.False(t, legacy.Required)
+	require.False(t, legacy.Nullable)
+	require.Empty(t, legacy.Default)
+	require.False(t, legacy.Dropped)
+
+	dropped := v1alpha1.ToFieldSchema(v1alpha1.Record{"s2", "3", "todo", "old", "text", "", "", "", "", "", "", "", "true"})
+	require.True(t, dropped.Dropped)
+	require.Equal(t, 3, dropped.Version)
+
+	rec := v1alpha1.ToSchemaRecord(dropped)
+	rec[1] = "3" // the writer owns the version column
+	require.Equal(t, dropped, v1alpha1.ToFieldSchema(rec))
+}
+
+func TestToResourceLayout(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	layout := []v1alpha1.FieldSchema{
+		{Field: "_id", Type: "text"},
+		{Field: "_v", Type: "number"},
+		{Field: "isbn", Type: "text", Dropped: true},
+		{Field: "title", Type: "text"},
+		{Field: "pages", Type: "number", Default: "100"},
+		{Field: "notes", Type: "text", Nullable: true},
+		{Field: "tags", Type: "list"},
+		{Field: "added", Type: "text", Default: "now()", Nullable: true},
+		{Field: "seen", Type: "number", Default: "now()"},
+	}
+
+	res, err := v1alpha1.ToResource(layout, v1alpha1.Record{"b1", "1", "0441013597", "Dune"})
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.Resource{
+		"_id":   "b1",
+		"_v":    1.0,
+		"title": "Dune",
+		"pages": 100.0,
+		"notes": nil,
+		"tags":  []string{},
+		"added": nil,
+		"seen":  0.0,
+	}, res)
+
+	rec, err := v1alpha1.ToRecord(layout, res)
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.Record{"b1", "1", "", "Dune", "100", v1alpha1.NullValue, "", v1alpha1.NullValue, "0"}, rec)
+}
+
+func TestCheckConversion(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	text := v1alpha1.FieldSchema{Field: "f", Type: "text"}
+	number := v1alpha1.FieldSchema{Field: "f", Type: "number"}
+	geo := v1alpha1.FieldSchema{Field: "f", Type: "geopoint"}
+	vec2 := v1alpha1.FieldSchema{Field: "f", Type: "vector(2)"}
+	vec3 := v1alpha1.FieldSchema{Field: "f", Type: "vector(3)"}
+	file := v1alpha1.FieldSchema{Field: "f", Type: "file"}
+
+	require.NoError(t, v1alpha1.CheckConversion(text, number, "12.5"))
+	require.NoError(t, v1alpha1.CheckConversion(text, number, ""))
+	require.NoError(t, v1alpha1.CheckConversion(text, number, v1alpha1.NullValue))
+	require.Error(t, v1alpha1.CheckConversion(text, number, "twelve"))
+	require.NoError(t, v1alpha1.CheckConversion(number, text, "12"))
+	require.NoError(t, v1alpha1.CheckConversion(text, geo, "50.85,4.35"))
+	require.Error(t, v1alpha1.CheckConversion(text, geo, "Brussels"))
+	require.Error(t, v1alpha1.CheckConversion(file, text, `{"name":"a.txt"}`))
+	require.Error(t, v1alpha1.CheckConversion(vec2, vec3, v1alpha1.EncodeVector([]float64{1, 2})))
+	require.NoError(t, v1alpha1.CheckConversion(vec2, vec2, v1alpha1.EncodeVector([]float64{1, 2})))
+}
+
+func TestParseResourceCollectsFieldErrors(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	testSchema := []v1alpha1.FieldSchema{
+		{Field: "_id", Type: "text"},
+		{Field: "_v", Type: "number", Min: 1},
+		{Field: "title", Type: "text", Regex: "^.+$", Required: true},
+		{Field: "name", Type: "text", Regex: "^[A-Z][a-z]*$"},
+		{Field: "age", Type: "number", Min: 0, Max: 150},
+		{Field: "year", Type: "number", Min: 1900, Max: 2030},
+		{Field: "tags", Type: "list"},
+		{Field: "location", Type: "geopoint"},
+		{Field: "embedding", Type: "vector(2)"},
+	}
+
+	_, err := v1alpha1.ParseResource(testSchema, v1alpha1.Resource{
+		"name":      "john",
+		"age":       200.0,
+		"year":      1800.0,
+		"tags":      "not a list",
+		"location":  map[string]any{"lat": 91.0, "lon": 0.0},
+		"embedding": []any{1.0, 2.0, 3.0},
+	})
+
+	var invalid *v1alpha1.ValidationError
+	require.ErrorAs(t, err, &invalid)
+
+	// every field that failed, in schema order
+	require.Equal(t, []v1alpha1.FieldError{
+		{Field: "title", Rule: v1alpha1.RuleRequired, Message: `field "title" is required`},
+		{Field: "name", Rule: v1alpha1.RulePattern, Expected: "^[A-Z][a-z]*$", Message: `failed to parse field "name" as a valid string`},
+		{Field: "age", Rule: v1alpha1.RuleMax, Expected: "150", Message: `failed to parse field "age" as a valid number`},
+		{Field: "year", Rule: v1alpha1.RuleMin, Expected: "1900", Message: `failed to parse field "year" as a valid number`},
+		{Field: "tags", Rule: v1alpha1.RuleType, Expected: "list", Message: `failed to parse field "tags" as a list`},
+		{Field: "location", Rule: v1alpha1.RuleRange, Expected: "latitude from -90 to 90 and longitude from -180 to 180", Message: `failed to parse field "location" as a valid point`},
+		{Field: "embedding", Rule: v1alpha1.RuleDimension, Expected: "vector(2)", Message: `failed to parse field "embedding" as a vector of type vector(2)`},
+	}, invalid.Errors)
+
+	require.Equal(t, `field "title" is required; failed to parse field "name" as a valid string; `+
+		`failed to parse field "age" as a valid number; failed to parse field "year" as a valid number; `+
+		`failed to parse field "tags" as a list; failed to parse field "location" as a valid point; `+
+		`failed to parse field "embedding" as a vector of type vector(2)`, err.Error())
+
+	// a single field fails on its own
+	_, err = v1alpha1.ParseField[float64](v1alpha1.FieldSchema{Field: "n", Type: "number"}, "one")
+
+	var fe *v1alpha1.FieldError
+	require.ErrorAs(t, err, &fe)
+	require.Equal(t, v1alpha1.FieldError{Field: "n", Rule: v1alpha1.RuleType, Expected: "number", Message: `failed to parse field "n" as a number`}, *fe)
+}
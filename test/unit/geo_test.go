@@ -0,0 +1,102 @@
+package unit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter/index"
+)
+
+func TestParseGeoPointField(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	tests := []struct {
+		name  string
+		input any
+		want  v1alpha1.GeoPoint
+		err   bool
+	}{
+		{
+			name:  "object",
+			input: map[string]any{"lat": 51.5, "lon": -0.12},
+			want:  v1alpha1.GeoPoint{Lat: 51.5, Lon: -0.12},
+		},
+		{
+			name:  "pair",
+			input: []any{51.5, -0.12},
+			want:  v1alpha1.GeoPoint{Lat: 51.5, Lon: -0.12},
+		},
+		{
+			name:  "latitude out of range",
+			input: map[string]any{"lat": 91.0, "lon": 0.0},
+			err:   true,
+		},
+		{
+			name:  "longitude out of range",
+			input: []any{0.0, -180.5},
+			err:   true,
+		},
+		{
+			name:  "not a point",
+			input: "51.5,-0.12",
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := v1alpha1.ParseField[v1alpha1.GeoPoint](v1alpha1.FieldSchema{Type: "geopoint"}, test.input)
+			if !test.err {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+			require.Equal(t, test.want, v)
+		})
+	}
+}
+
+func TestGeoIndex(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	idx := index.NewGeo()
+	idx.Put("london", v1alpha1.GeoPoint{Lat: 51.5074, Lon: -0.1278})
+	idx.Put("paris", v1alpha1.GeoPoint{Lat: 48.8566, Lon: 2.3522})
+	idx.Put("brussels", v1alpha1.GeoPoint{Lat: 50.8503, Lon: 4.3517})
+	idx.Put("fiji", v1alpha1.GeoPoint{Lat: -17.7134, Lon: 178.065})
+	idx.Put("samoa", v1alpha1.GeoPoint{Lat: -13.759, Lon: -172.1046})
+
+	ids := func(hits []index.GeoHit) []string {
+		out := []string{}
+		for _, hit := range hits {
+			out = append(out, hit.Id)
+		}
+		return out
+	}
+
+	near := idx.Near(v1alpha1.GeoPoint{Lat: 51.5, Lon: 0}, 400, nil)
+	require.Equal(t, []string{"london", "brussels", "paris"}, ids(near))
+	require.InDelta(t, 9.1, near[0].Distance, 0.5)
+
+	near = idx.Near(v1alpha1.GeoPoint{Lat: 51.5, Lon: 0}, 300, nil)
+	require.Equal(t, []string{"london"}, ids(near))
+
+	box := v1alpha1.BBox{MinLon: 2, MinLat: 48, MaxLon: 5, MaxLat: 51}
+	require.Equal(t, []string{"paris", "brussels"}, ids(idx.Within(box, box.Center())))
+
+	dateline := v1alpha1.BBox{MinLon: 170, MinLat: -20, MaxLon: -170, MaxLat: -10}
+	require.ElementsMatch(t, []string{"fiji", "samoa"}, ids(idx.Within(dateline, dateline.Center())))
+
+	idx.Put("london", v1alpha1.GeoPoint{Lat: 40.7128, Lon: -74.006})
+	idx.Remove("paris")
+	require.Equal(t, []string{"brussels"}, ids(idx.Near(v1alpha1.GeoPoint{Lat: 51.5, Lon: 0}, 400, nil)))
+	require.Equal(t, 4, idx.Len())
+}
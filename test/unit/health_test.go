@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/readwriter/csv"
+)
+
+func TestNewHealth(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	require.Equal(t, v1alpha1.Health{
+		Status:     v1alpha1.HealthOK,
+		Components: map[string]v1alpha1.ComponentHealth{"store": {Status: v1alpha1.HealthOK}},
+	}, v1alpha1.NewHealth(map[string]error{"store": nil}))
+
+	require.Equal(t, v1alpha1.Health{
+		Status: v1alpha1.HealthFailing,
+		Components: map[string]v1alpha1.ComponentHealth{
+			"store":            {Status: v1alpha1.HealthOK},
+			"readwriter.notes": {Status: v1alpha1.HealthFailing, Error: "file is not open"},
+		},
+	}, v1alpha1.NewHealth(map[string]error{"store": nil, "readwriter.notes": errors.New("file is not open")}))
+}
+
+func TestCSVReadWriterHealth(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	layout := []v1alpha1.FieldSchema{
+		{Resource: "notes", Field: "_id", Type: "text"},
+		{Resource: "notes", Field: "_v", Type: "number"},
+		{Resource: "notes", Field: "title", Type: "text"},
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.csv")
+
+	rw := readwriter.Traced(csv.NewReadWriter(readwriter.WithLocation(path), readwriter.WithFieldSchemas(layout)), "notes")
+
+	// an empty file is fine
+	require.NoError(t, readwriter.CheckHealth(ctx, rw))
+
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"a", "", "first"}))
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"b", "", "second"}))
+	require.NoError(t, rw.Delete(ctx, "a"))
+	require.NoError(t, readwriter.CheckHealth(ctx, rw))
+
+	// the index no longer matches a file cut short behind its back
+	require.NoError(t, os.Truncate(path, 10))
+	require.ErrorContains(t, readwriter.CheckHealth(ctx, rw), "index points past the end of the file")
+
+	require.NoError(t, rw.Close(ctx))
+	require.ErrorContains(t, readwriter.CheckHealth(ctx, rw), "file is not open")
+}
@@ -0,0 +1,64 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/internal/logs"
+)
+
+func TestLogScopes(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	var buf bytes.Buffer
+
+	logger, err := logs.New(&buf, logs.FormatJSON, slog.LevelInfo)
+	require.NoError(t, err)
+
+	ctx := logs.NewScope(context.Background(), slog.String("request.id", "r1"))
+
+	// attributes added to the scope later still reach the records
+	logs.Add(ctx, slog.String("user.id", "alice"))
+
+	logger.InfoContext(ctx, "first")
+	logger.InfoContext(ctx, "second", "user.id", "bob")
+	logger.DebugContext(ctx, "hidden")
+	logger.Info("no scope")
+
+	nested := logs.NewScope(ctx, slog.String("step", "inner"))
+	logger.InfoContext(nested, "nested")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+
+	records := []map[string]any{}
+	for _, line := range lines {
+		rec := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		records = append(records, rec)
+	}
+
+	require.Equal(t, "r1", records[0]["request.id"])
+	require.Equal(t, "alice", records[0]["user.id"])
+
+	// a record keeps its own value, and the key only once
+	require.Equal(t, "bob", records[1]["user.id"])
+	require.Equal(t, 1, strings.Count(lines[1], `"user.id"`))
+
+	require.NotContains(t, records[2], "request.id")
+
+	require.Equal(t, "r1", records[3]["request.id"])
+	require.Equal(t, "inner", records[3]["step"])
+
+	_, err = logs.New(&buf, "xml", slog.LevelInfo)
+	require.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+	"github.com/w-h-a/backend/internal/clients/readwriter/csv"
+	"github.com/w-h-a/backend/internal/clients/readwriter/memory"
+	"github.com/w-h-a/backend/internal/metrics"
+)
+
+func TestReadWriterStats(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	layout := []v1alpha1.FieldSchema{
+		{Resource: "notes", Field: "_id", Type: "text"},
+		{Resource: "notes", Field: "_v", Type: "number"},
+		{Resource: "notes", Field: "title", Type: "text"},
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.csv")
+
+	rw := csv.NewReadWriter(readwriter.WithLocation(path), readwriter.WithFieldSchemas(layout))
+
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"a", "", "first"}))
+	require.NoError(t, rw.Create(ctx, v1alpha1.Record{"b", "", "second"}))
+	require.NoError(t, rw.Update(ctx, v1alpha1.Record{"a", "", "first, again"}))
+	require.NoError(t, rw.Delete(ctx, "b"))
+
+	// wrapped read/writers are looked through
+	stats, ok, err := readwriter.ReadStats(ctx, readwriter.Traced(rw, "notes"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	require.Equal(t, readwriter.Stats{Records: 1, Rows: 4, Bytes: info.Size(), IndexEntries: 2}, stats)
+	require.Equal(t, 0.75, stats.DeadRatio())
+
+	require.NoError(t, rw.Close(ctx))
+
+	// the log is read back the same
+	rw = csv.NewReadWriter(readwriter.WithLocation(path), readwriter.WithFieldSchemas(layout))
+	defer rw.Close(ctx)
+
+	reopened, _, err := readwriter.ReadStats(ctx, rw)
+	require.NoError(t, err)
+	require.Equal(t, stats, reopened)
+
+	// nothing is on disk for records kept in memory
+	mem := memory.NewReadWriter(readwriter.WithFieldSchemas(layout))
+	require.NoError(t, mem.Create(ctx, v1alpha1.Record{"a", "", "first"}))
+
+	stats, ok, err = readwriter.ReadStats(ctx, mem)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, readwriter.Stats{Records: 1, Rows: 1, Bytes: -1, IndexEntries: 1}, stats)
+	require.Zero(t, stats.DeadRatio())
+}
+
+type statsSource struct {
+	stats map[string]readwriter.Stats
+	err   error
+}
+
+func (s statsSource) Stats(context.Context) (map[string]readwriter.Stats, error) {
+	return s.stats, s.err
+}
+
+func TestResourceMetrics(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	scrape := func(source statsSource) string {
+		rsp := httptest.NewRecorder()
+		metrics.Handler(metrics.NewResourceCollector(source)).ServeHTTP(rsp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, rsp.Code)
+		return rsp.Body.String()
+	}
+
+	body := scrape(statsSource{stats: map[string]readwriter.Stats{
+		"notes":  {Records: 1, Rows: 4, Bytes: 120, IndexEntries: 2},
+		"config": {Records: 3, Rows: 3, Bytes: -1, IndexEntries: 3},
+	}})
+
+	require.Contains(t, body, `backend_resource_records{resource="notes"} 1`)
+	require.Contains(t, body, `backend_resource_rows{resource="notes"} 4`)
+	require.Contains(t, body, `backend_resource_file_size_bytes{resource="notes"} 120`)
+	require.Contains(t, body, `backend_resource_dead_row_ratio{resource="notes"} 0.75`)
+	require.Contains(t, body, `backend_resource_index_entries{resource="notes"} 2`)
+
+	// what is not stored in a file has no file to measure
+	require.Contains(t, body, `backend_resource_records{resource="config"} 3`)
+	require.NotContains(t, body, `backend_resource_file_size_bytes{resource="config"}`)
+	require.NotContains(t, body, `backend_resource_dead_row_ratio{resource="config"}`)
+
+	// the rest of the metrics are still served when some stats fail
+	body = scrape(statsSource{
+		stats: map[string]readwriter.Stats{"notes": {Records: 1, Rows: 1, Bytes: 40, IndexEntries: 1}},
+		err:   errors.New("failed to read stats of drafts"),
+	})
+
+	require.Contains(t, body, `backend_resource_records{resource="notes"} 1`)
+	require.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,183 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/internal/handlers"
+	httphandlers "github.com/w-h-a/backend/internal/handlers/http"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(h http.Handler, method string, origin string, preflight bool) *httptest.ResponseRecorder {
+		reached = false
+		req := httptest.NewRequest(method, "/api/notes", nil)
+		if len(origin) > 0 {
+			req.Header.Set("Origin", origin)
+		}
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		rsp := httptest.NewRecorder()
+		h.ServeHTTP(rsp, req)
+		return rsp
+	}
+
+	h := httphandlers.NewCORSMiddleware(
+		httphandlers.WithCORSOrigins("https://app.example.com"),
+		httphandlers.WithCORSMaxAge(time.Hour),
+	)(next)
+
+	// a preflight is answered before it reaches anything else
+	rsp := serve(h, http.MethodOptions, "https://app.example.com", true)
+	require.False(t, reached)
+	require.Equal(t, http.StatusNoContent, rsp.Code)
+	require.Equal(t, "https://app.example.com", rsp.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, POST, PUT, DELETE", rsp.Header().Get("Access-Control-Allow-Methods"))
+	require.Contains(t, rsp.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+	require.Equal(t, "3600", rsp.Header().Get("Access-Control-Max-Age"))
+	require.Empty(t, rsp.Header().Get("Access-Control-Allow-Credentials"))
+
+	// other origins get no headers, so the browser refuses them
+	rsp = serve(h, http.MethodOptions, "https://evil.example.com", true)
+	require.False(t, reached)
+	require.Equal(t, http.StatusNoContent, rsp.Code)
+	require.Empty(t, rsp.Header().Get("Access-Control-Allow-Origin"))
+
+	rsp = serve(h, http.MethodGet, "https://evil.example.com", false)
+	require.True(t, reached)
+	require.Empty(t, rsp.Header().Get("Access-Control-Allow-Origin"))
+
+	// the request itself goes through, and may read the request id and how
+	// long to wait when turned away
+	rsp = serve(h, http.MethodGet, "https://app.example.com", false)
+	require.True(t, reached)
+	require.Equal(t, "https://app.example.com", rsp.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, httphandlers.RequestIDHeader+", Retry-After", rsp.Header().Get("Access-Control-Expose-Headers"))
+	require.Equal(t, "Origin", rsp.Header().Get("Vary"))
+
+	// any origin, unless credentials are sent, which needs the origin named
+	h = httphandlers.NewCORSMiddleware(httphandlers.WithCORSOrigins("*"))(next)
+	rsp = serve(h, http.MethodGet, "https://app.example.com", false)
+	require.Equal(t, "*", rsp.Header().Get("Access-Control-Allow-Origin"))
+
+	h = httphandlers.NewCORSMiddleware(httphandlers.WithCORSOrigins("*"), httphandlers.WithCORSCredentials(true))(next)
+	rsp = serve(h, http.MethodGet, "https://app.example.com", false)
+	require.Equal(t, "https://app.example.com", rsp.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "true", rsp.Header().Get("Access-Control-Allow-Credentials"))
+
+	// no origins, no CORS
+	h = httphandlers.NewCORSMiddleware()(next)
+	rsp = serve(h, http.MethodOptions, "https://app.example.com", true)
+	require.True(t, reached)
+	require.Empty(t, rsp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	var seen string
+	h := httphandlers.NewRequestIDMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = handlers.GetRequestIDFromCtx(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/notes", nil)
+	req.Header.Set(httphandlers.RequestIDHeader, "req-1")
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	require.Equal(t, "req-1", seen)
+	require.Equal(t, "req-1", rsp.Header().Get(httphandlers.RequestIDHeader))
+
+	// ids too long to be ids are replaced
+	req = httptest.NewRequest(http.MethodGet, "/api/notes", nil)
+	req.Header.Set(httphandlers.RequestIDHeader, strings.Repeat("x", 200))
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	require.NotEmpty(t, seen)
+	require.NotEqual(t, strings.Repeat("x", 200), seen)
+	require.Equal(t, seen, rsp.Header().Get(httphandlers.RequestIDHeader))
+
+	// and so are ids with characters that have no place in headers or logs
+	for _, id := range []string{"req 1", "req-1\nlevel=ERROR", "req/1", "réq-1", "req\x00"} {
+		req = httptest.NewRequest(http.MethodGet, "/api/notes", nil)
+		req.Header[httphandlers.RequestIDHeader] = []string{id}
+		rsp = httptest.NewRecorder()
+		h.ServeHTTP(rsp, req)
+		require.NotEqual(t, id, seen)
+		require.Regexp(t, `^[A-Za-z0-9._-]+$`, seen)
+		require.Equal(t, seen, rsp.Header().Get(httphandlers.RequestIDHeader))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/notes", nil)
+	req.Header.Set(httphandlers.RequestIDHeader, "trace_01.A-z")
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	require.Equal(t, "trace_01.A-z", seen)
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	h := httphandlers.NewRecoverMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var u map[string]any
+		_ = u["_id"].(string)
+	}))
+
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest(http.MethodGet, "/api/notes", nil))
+	require.Equal(t, http.StatusInternalServerError, rsp.Code)
+
+	logged := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logged))
+	require.Equal(t, "panic serving request", logged["msg"])
+	require.Contains(t, logged["panic"], "interface conversion")
+	require.Contains(t, logged["stack"], "TestRecoverMiddleware")
+
+	// a response already under way keeps its status
+	h = httphandlers.NewRecoverMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("too late")
+	}))
+
+	rsp = httptest.NewRecorder()
+	h.ServeHTTP(rsp, httptest.NewRequest(http.MethodGet, "/api/notes", nil))
+	require.Equal(t, http.StatusAccepted, rsp.Code)
+
+	// and aborting on purpose still aborts
+	h = httphandlers.NewRecoverMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/notes", nil))
+	})
+}
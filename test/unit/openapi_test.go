@@ -0,0 +1,234 @@
+package unit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+func TestFieldJSONSchema(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	tests := []struct {
+		name   string
+		fs     v1alpha1.FieldSchema
+		schema map[string]any
+	}{
+		{
+			name:   "unbounded number",
+			fs:     v1alpha1.FieldSchema{Field: "n", Type: "number"},
+			schema: map[string]any{"type": "number"},
+		},
+		{
+			name:   "bounded number",
+			fs:     v1alpha1.FieldSchema{Field: "n", Type: "number", Min: 1, Max: 5, Default: "3"},
+			schema: map[string]any{"type": "number", "minimum": 1.0, "maximum": 5.0, "default": 3.0},
+		},
+		{
+			name:   "number with a lower bound only",
+			fs:     v1alpha1.FieldSchema{Field: "n", Type: "number", Min: 1},
+			schema: map[string]any{"type": "number", "minimum": 1.0},
+		},
+		{
+			name:   "nullable text with a pattern",
+			fs:     v1alpha1.FieldSchema{Field: "t", Type: "text", Regex: "^[a-z]+$", Nullable: true},
+			schema: map[string]any{"type": []string{"string", "null"}, "pattern": "^[a-z]+$"},
+		},
+		{
+			name:   "text defaulting to now",
+			fs:     v1alpha1.FieldSchema{Field: "t", Type: "text", Default: "now()"},
+			schema: map[string]any{"type": "string", "description": "Defaults to the current time."},
+		},
+		{
+			name:   "ref",
+			fs:     v1alpha1.FieldSchema{Field: "owner", Type: "ref:_users"},
+			schema: map[string]any{"type": "string", "description": "Id of a record of _users."},
+		},
+		{
+			name:   "list",
+			fs:     v1alpha1.FieldSchema{Field: "tags", Type: "list"},
+			schema: map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		{
+			name:   "vector",
+			fs:     v1alpha1.FieldSchema{Field: "v", Type: "vector(3)"},
+			schema: map[string]any{"type": "array", "items": map[string]any{"type": "number"}, "minItems": 3, "maxItems": 3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.schema, v1alpha1.FieldJSONSchema(test.fs))
+		})
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	require.Equal(t, "Users", v1alpha1.TypeName("_users"))
+	require.Equal(t, "LineItems", v1alpha1.TypeName("line_items"))
+	require.Equal(t, "Todo", v1alpha1.TypeName("todo"))
+}
+
+func TestOpenAPIBulk(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	schemas := map[string][]v1alpha1.FieldSchema{
+		"notes": {
+			{Resource: "notes", Field: "_id", Type: "text"},
+			{Resource: "notes", Field: "_v", Type: "number"},
+		},
+		"drafts": {
+			{Resource: "drafts", Field: "_id", Type: "text"},
+			{Resource: "drafts", Field: "_v", Type: "number"},
+		},
+	}
+
+	permissions := []v1alpha1.Resource{
+		{"resource": "notes", "action": "create", "field": "", "role": ""},
+		{"resource": "notes", "action": "delete", "field": "", "role": "admin"},
+		{"resource": "drafts", "action": "read", "field": "", "role": ""},
+	}
+
+	paths := v1alpha1.OpenAPI(schemas, permissions)["paths"].(map[string]any)
+
+	// any of create, update and delete grants it, and each op needs what
+	// it does alone
+	bulk, ok := paths["/api/notes/_bulk"].(map[string]any)
+	require.True(t, ok)
+
+	op := bulk["post"].(map[string]any)
+	require.Equal(t, "bulkNotes", op["operationId"])
+	require.Equal(t, []any{map[string]any{}, map[string]any{"basicAuth": []string{}}}, op["security"])
+	require.Equal(t, "Each op is authorized on its own. create: Allowed for anyone. delete: Allowed for users with role admin.", op["description"])
+	require.Contains(t, op["responses"], "207")
+
+	// reading grants no writes
+	require.NotContains(t, paths, "/api/drafts/_bulk")
+}
+
+func TestOpenAPIAggregate(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	schemas := map[string][]v1alpha1.FieldSchema{
+		"books": {
+			{Resource: "books", Field: "_id", Type: "text"},
+			{Resource: "books", Field: "_v", Type: "number"},
+		},
+		"drafts": {
+			{Resource: "drafts", Field: "_id", Type: "text"},
+			{Resource: "drafts", Field: "_v", Type: "number"},
+		},
+	}
+
+	permissions := []v1alpha1.Resource{
+		{"resource": "books", "action": "read", "field": "owner", "role": ""},
+		{"resource": "drafts", "action": "create", "field": "", "role": ""},
+	}
+
+	paths := v1alpha1.OpenAPI(schemas, permissions)["paths"].(map[string]any)
+
+	// reading grants it, counting only the records the caller may read
+	agg, ok := paths["/api/books/_aggregate"].(map[string]any)
+	require.True(t, ok)
+
+	op := agg["get"].(map[string]any)
+	require.Equal(t, "aggregateBooks", op["operationId"])
+	require.Equal(t, []any{map[string]any{"basicAuth": []string{}}}, op["security"])
+	require.Equal(t, "Allowed for users named in owner of the record.", op["description"])
+
+	names := []string{}
+	for _, p := range op["parameters"].([]any) {
+		names = append(names, p.(map[string]any)["name"].(string))
+	}
+	require.Equal(t, []string{"group_by", "count", "sum", "avg", "min", "max", "near", "radius", "bbox", "geo_field"}, names)
+
+	require.NotContains(t, paths, "/api/drafts/_aggregate")
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	schemas := map[string][]v1alpha1.FieldSchema{
+		"docs": {
+			{Resource: "docs", Field: "_id", Type: "text"},
+			{Resource: "docs", Field: "_v", Type: "number"},
+			{Resource: "docs", Field: "owner", Type: "ref:_users"},
+			{Resource: "docs", Field: "embedding", Type: "vector(3)"},
+			{Resource: "docs", Field: "attachment", Type: "file"},
+			{Resource: "docs", Field: "old", Type: "file", Dropped: true},
+		},
+		"notes": {
+			{Resource: "notes", Field: "_id", Type: "text"},
+			{Resource: "notes", Field: "_v", Type: "number"},
+		},
+		"drafts": {
+			{Resource: "drafts", Field: "_id", Type: "text"},
+			{Resource: "drafts", Field: "_v", Type: "number"},
+			{Resource: "drafts", Field: "embedding", Type: "vector(3)"},
+			{Resource: "drafts", Field: "attachment", Type: "file"},
+		},
+	}
+
+	permissions := []v1alpha1.Resource{
+		{"resource": "docs", "action": "read", "field": "", "role": "user"},
+		{"resource": "docs", "action": "update", "field": "owner", "role": ""},
+		{"resource": "notes", "action": "*", "field": "", "role": ""},
+		{"resource": "drafts", "action": "create", "field": "", "role": ""},
+	}
+
+	paths := v1alpha1.OpenAPI(schemas, permissions)["paths"].(map[string]any)
+
+	// reading grants the search of a resource with a vector field
+	knn, ok := paths["/api/docs/_knn"].(map[string]any)
+	require.True(t, ok)
+
+	op := knn["post"].(map[string]any)
+	require.Equal(t, "nearestDocs", op["operationId"])
+	require.Equal(t, []any{map[string]any{"basicAuth": []string{}}}, op["security"])
+
+	require.NotContains(t, paths, "/api/notes/_knn")
+	require.NotContains(t, paths, "/api/drafts/_knn")
+
+	// updating grants uploads and reading downloads, of live file fields
+	files, ok := paths["/api/docs/{id}/files/{field}"].(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, "uploadDocsFile", files["post"].(map[string]any)["operationId"])
+	require.Contains(t, files["post"].(map[string]any)["responses"], "413")
+	require.Equal(t, "downloadDocsFile", files["get"].(map[string]any)["operationId"])
+
+	field := files["parameters"].([]any)[1].(map[string]any)
+	require.Equal(t, "field", field["name"])
+	require.Equal(t, map[string]any{"enum": []string{"attachment"}}, field["schema"])
+
+	require.NotContains(t, paths, "/api/notes/{id}/files/{field}")
+	require.NotContains(t, paths, "/api/drafts/{id}/files/{field}")
+
+	// anyone may describe the resources, with or without credentials
+	meta, ok := paths["/api/_meta/resources"].(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, []any{map[string]any{}, map[string]any{"basicAuth": []string{}}}, meta["get"].(map[string]any)["security"])
+	require.Contains(t, paths, "/api/_meta/resources/{name}")
+
+	// without any permission there is nothing to describe
+	paths = v1alpha1.OpenAPI(schemas, nil)["paths"].(map[string]any)
+	require.Empty(t, paths)
+}
@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	grpchandlers "github.com/w-h-a/backend/internal/handlers/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+var projectionSchema = []v1alpha1.FieldSchema{
+	{Field: "_id", Type: "text"},
+	{Field: "_v", Type: "number"},
+	{Field: "title", Type: "text"},
+	{Field: "gone", Type: "text", Dropped: true},
+	{Field: "tags", Type: "list"},
+	{Field: "embedding", Type: "vector(2)"},
+}
+
+func TestParseProjection(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	tests := []struct {
+		in   string
+		want v1alpha1.Projection
+		err  string
+	}{
+		{in: "", want: v1alpha1.Projection{}},
+		{in: "title", want: v1alpha1.Projection{Fields: []string{"title"}}},
+		{in: "title, tags", want: v1alpha1.Projection{Fields: []string{"title", "tags"}}},
+		{in: "-embedding,-tags", want: v1alpha1.Projection{Fields: []string{"embedding", "tags"}, Exclude: true}},
+		{in: "title,-tags", err: "expected fields to keep or fields to exclude, not both"},
+		{in: "title,,tags", err: `expected a field name, like title or -title, got ""`},
+		{in: "-", err: `expected a field name, like title or -title, got "-"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			p, err := v1alpha1.ParseProjection(tt.in)
+			if len(tt.err) > 0 {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, p)
+		})
+	}
+
+	require.NoError(t, v1alpha1.Projection{Fields: []string{"_id", "title"}}.Validate(projectionSchema))
+	require.EqualError(t, v1alpha1.Projection{Fields: []string{"author"}}.Validate(projectionSchema), `unknown field "author"`)
+	require.EqualError(t, v1alpha1.Projection{Fields: []string{"gone"}}.Validate(projectionSchema), `unknown field "gone"`)
+	require.EqualError(t, v1alpha1.Projection{Fields: []string{"_id"}, Exclude: true}.Validate(projectionSchema), `field "_id" is always returned`)
+}
+
+func TestProjectResource(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	// the vector does not decode, which only matters when it is kept
+	rec := v1alpha1.Record{"a", "1", "First", "", "x,y", "not a vector"}
+
+	_, err := v1alpha1.ToResource(projectionSchema, rec)
+	require.Error(t, err)
+
+	res, err := v1alpha1.ProjectResource(projectionSchema, rec, v1alpha1.Projection{Fields: []string{"title"}})
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.Resource{"_id": "a", "title": "First"}, res)
+
+	res, err = v1alpha1.ProjectResource(projectionSchema, rec, v1alpha1.Projection{Fields: []string{"embedding", "_v"}, Exclude: true})
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.Resource{"_id": "a", "title": "First", "tags": []string{"x", "y"}}, res)
+}
+
+func TestProjectionFromMask(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	p, err := grpchandlers.ProjectionFromMask(nil, projectionSchema)
+	require.NoError(t, err)
+	require.True(t, p.IsZero())
+
+	p, err = grpchandlers.ProjectionFromMask(&fieldmaskpb.FieldMask{Paths: []string{"title", "tags"}}, projectionSchema)
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.Projection{Fields: []string{"title", "tags"}}, p)
+
+	_, err = grpchandlers.ProjectionFromMask(&fieldmaskpb.FieldMask{Paths: []string{"title.length"}}, projectionSchema)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = grpchandlers.ProjectionFromMask(&fieldmaskpb.FieldMask{Paths: []string{"author"}}, projectionSchema)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.ErrorContains(t, err, `unknown field "author"`)
+}
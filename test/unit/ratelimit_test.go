@@ -0,0 +1,153 @@
+package unit
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/internal/ratelimit"
+)
+
+// fakeClock is a clock for tests that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestParseLimit(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	for v, want := range map[string]ratelimit.Limit{
+		"0":     {},
+		"10/s":  {Count: 10, Per: time.Second},
+		"100/m": {Count: 100, Per: time.Minute},
+		"1/h":   {Count: 1, Per: time.Hour},
+		"5/10s": {Count: 5, Per: 10 * time.Second},
+	} {
+		limit, err := ratelimit.ParseLimit(v)
+		require.NoError(t, err, v)
+		require.Equal(t, want, limit, v)
+
+		// and it reads back
+		again, err := ratelimit.ParseLimit(limit.String())
+		require.NoError(t, err, v)
+		require.Equal(t, limit, again, v)
+	}
+
+	for v, msg := range map[string]string{
+		"100":    "expected COUNT/UNIT",
+		"x/s":    "expected a positive count",
+		"-1/s":   "expected a positive count",
+		"10/day": "expected a unit of s, m, h or a duration",
+		"10/0s":  "expected a unit of s, m, h or a duration",
+	} {
+		_, err := ratelimit.ParseLimit(v)
+		require.ErrorContains(t, err, msg, v)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	limiter := ratelimit.NewLimiter(ratelimit.WithClock(clock.Now))
+
+	limit := ratelimit.Limit{Count: 3, Per: time.Minute}
+
+	// a burst of the whole count goes through
+	for range 3 {
+		ok, _ := limiter.Allow("ip:a", limit)
+		require.True(t, ok)
+	}
+
+	ok, wait := limiter.Allow("ip:a", limit)
+	require.False(t, ok)
+	require.Equal(t, 20*time.Second, wait)
+
+	// other keys have buckets of their own
+	ok, _ = limiter.Allow("ip:b", limit)
+	require.True(t, ok)
+
+	// and tokens come back one at a time
+	clock.Advance(15 * time.Second)
+	ok, wait = limiter.Allow("ip:a", limit)
+	require.False(t, ok)
+	require.Equal(t, 5*time.Second, wait)
+
+	clock.Advance(5 * time.Second)
+	ok, _ = limiter.Allow("ip:a", limit)
+	require.True(t, ok)
+	ok, _ = limiter.Allow("ip:a", limit)
+	require.False(t, ok)
+
+	// never more than the count at once, however long it was
+	clock.Advance(time.Hour)
+	for range 3 {
+		ok, _ := limiter.Allow("ip:a", limit)
+		require.True(t, ok)
+	}
+	ok, _ = limiter.Allow("ip:a", limit)
+	require.False(t, ok)
+
+	// the zero limit lets everything through
+	for range 100 {
+		ok, _ := limiter.Allow("ip:a", ratelimit.Limit{})
+		require.True(t, ok)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	lockout := ratelimit.NewLockout(
+		ratelimit.WithClock(clock.Now),
+		ratelimit.WithThreshold(3),
+		ratelimit.WithLockout(time.Minute),
+		ratelimit.WithMaxLockout(5*time.Minute),
+	)
+
+	require.Zero(t, lockout.Fail("user:admin"))
+	require.Zero(t, lockout.Fail("user:admin"))
+	require.Zero(t, lockout.Locked("user:admin"))
+
+	// the third failure in a row locks the key out
+	require.Equal(t, time.Minute, lockout.Fail("user:admin"))
+	require.Equal(t, time.Minute, lockout.Locked("user:admin"))
+	require.Zero(t, lockout.Locked("user:user1"))
+
+	clock.Advance(40 * time.Second)
+	require.Equal(t, 20*time.Second, lockout.Locked("user:admin"))
+
+	// every failure after that doubles the lockout, up to the max
+	clock.Advance(20 * time.Second)
+	require.Zero(t, lockout.Locked("user:admin"))
+	require.Equal(t, 2*time.Minute, lockout.Fail("user:admin"))
+	require.Equal(t, 4*time.Minute, lockout.Fail("user:admin"))
+	require.Equal(t, 5*time.Minute, lockout.Fail("user:admin"))
+	require.Equal(t, 5*time.Minute, lockout.Fail("user:admin"))
+
+	// a success starts over
+	lockout.Reset("user:admin")
+	require.Zero(t, lockout.Locked("user:admin"))
+	require.Zero(t, lockout.Fail("user:admin"))
+}
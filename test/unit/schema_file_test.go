@@ -0,0 +1,150 @@
+package unit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+func TestSchemaFile(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	yamlFile := `resources:
+  - name: todo
+    fields:
+      - name: title
+        type: text
+        required: true
+      - id: s9
+        name: done
+        type: number
+        max: 1
+permissions:
+  - resource: todo
+    action: "*"
+    description: Public access
+`
+
+	jsonFile := `{
+  "resources": [
+    {"name": "todo", "fields": [
+      {"name": "title", "type": "text", "required": true},
+      {"id": "s9", "name": "done", "type": "number", "max": 1}
+    ]}
+  ],
+  "permissions": [
+    {"resource": "todo", "action": "*", "description": "Public access"}
+  ]
+}`
+
+	for _, data := range []string{yamlFile, jsonFile} {
+		file, err := v1alpha1.ParseSchemaFile([]byte(data))
+		require.NoError(t, err)
+
+		schemas := file.FieldSchemas()
+
+		require.Equal(t, []v1alpha1.FieldSchema{
+			{Id: "todo._id", Version: 1, Resource: "todo", Field: "_id", Type: "text", Regex: "^.+$"},
+			{Id: "todo._v", Version: 1, Resource: "todo", Field: "_v", Type: "number", Min: 1},
+			{Id: "todo.title", Version: 1, Resource: "todo", Field: "title", Type: "text", Required: true},
+			{Id: "s9", Version: 1, Resource: "todo", Field: "done", Type: "number", Max: 1},
+		}, schemas["todo"])
+		require.Equal(t, v1alpha1.UserFields, schemas["_users"])
+		require.Equal(t, v1alpha1.PermissionFields, schemas["_permissions"])
+
+		require.Equal(t, []v1alpha1.Record{{"p1", "1", "todo", "*", "", ""}}, file.PermissionRecords())
+	}
+
+	// converting to and from the rows of _schemas and _permissions keeps
+	// everything but the defaults
+	file, err := v1alpha1.ParseSchemaFile([]byte(yamlFile))
+	require.NoError(t, err)
+
+	schemas := file.FieldSchemas()
+	permissions := file.PermissionRecords()
+	permissions[0] = append(permissions[0], "Public access")
+
+	again := v1alpha1.NewSchemaFile([]string{"todo"}, schemas, permissions)
+	require.Equal(t, schemas["todo"], again.FieldSchemas()["todo"])
+	require.Equal(t, "Public access", again.Permissions[0].Description)
+
+	for _, format := range []string{"yaml", "json"} {
+		data, err := v1alpha1.MarshalSchemaFile(again, format)
+		require.NoError(t, err)
+
+		parsed, err := v1alpha1.ParseSchemaFile(data)
+		require.NoError(t, err)
+		require.Equal(t, again, parsed)
+	}
+
+	_, err = v1alpha1.ParseSchemaFile([]byte("resources:\n  - name: todo\n    colour: red\n"))
+	require.Error(t, err)
+}
+
+func TestLintSchemaFile(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		problems []string
+	}{
+		{
+			name:     "clean",
+			file:     "resources:\n  - name: todo\n    fields:\n      - name: title\n        type: text\n",
+			problems: []string{},
+		},
+		{
+			name: "bad fields",
+			file: "resources:\n  - name: todo\n    fields:\n      - name: title\n        type: colour\n      - name: owner\n        type: ref:people\n      - name: title\n        type: text\n",
+			problems: []string{
+				`4: error: todo.title: unknown type "colour"`,
+				`6: error: todo.owner: references unknown resource "people"`,
+				`8: error: todo.title: _id "todo.title" is already used on line 4`,
+			},
+		},
+		{
+			name: "no fields",
+			file: "resources:\n  - name: todo\n",
+			problems: []string{
+				`2: warning: todo: resource has no fields besides _id and _v`,
+			},
+		},
+		{
+			name: "unknown keys",
+			file: "resources:\n  - name: todo\n    colour: red\n",
+			problems: []string{
+				`3: error: field colour not found in type v1alpha1.ResourceDefinition`,
+			},
+		},
+		{
+			name: "not yaml",
+			file: "resources: [\n",
+			problems: []string{
+				`1: error: did not find expected node content`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := v1alpha1.LintSchemaFile([]byte(test.file))
+			require.NoError(t, err)
+
+			got := []string{}
+			for _, p := range problems {
+				got = append(got, p.String())
+			}
+
+			require.Equal(t, test.problems, got)
+		})
+	}
+}
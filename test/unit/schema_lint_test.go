@@ -0,0 +1,93 @@
+package unit
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+)
+
+func TestLintSchemas(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	base := `s1,1,_users,_id,text,,,^.+$
+s2,1,_users,_v,number,1,,
+s3,1,_users,password,text,,,
+s4,1,_permissions,_id,text,,,^.+$
+s5,1,_permissions,_v,number,1,,
+s6,1,_permissions,resource,text,,,
+`
+
+	tests := []struct {
+		name     string
+		schemas  string
+		problems []string
+	}{
+		{
+			name:     "clean",
+			schemas:  base + "s7,1,todo,_id,text,,,^.+$\ns8,1,todo,_v,number,1,,\ns9,1,todo,title,text,,,\n",
+			problems: []string{},
+		},
+		{
+			name:    "missing _id and _v",
+			schemas: base + "s7,1,todo,title,text,,,\n",
+			problems: []string{
+				`7: error: todo: column 1 must be _id of type text`,
+				`7: error: todo: column 2 must be _v of type number`,
+				`7: warning: todo: resource has no fields besides _id and _v`,
+			},
+		},
+		{
+			name:    "bad attributes",
+			schemas: base + "s7,1,todo,_id,text,,,^.+$\ns8,1,todo,_v,number,1,,\ns9,1,todo,title,colour,,,(\ns10,1,todo,rank,number,10,1,\ns11,1,todo,done,number,,,,maybe\n",
+			problems: []string{
+				`9: error: todo.title: unknown type "colour"`,
+				"9: error: todo.title: invalid regex \"(\": error parsing regexp: missing closing ): `(`",
+				`10: error: todo.rank: min 10 is greater than max 1`,
+				`11: error: todo.done: required "maybe" is not a boolean`,
+			},
+		},
+		{
+			name:    "duplicates and dangling references",
+			schemas: base + "s7,1,todo,_id,text,,,^.+$\ns8,1,todo,_v,number,1,,\ns9,1,todo,owner,ref:people,,,\ns9,1,todo,title,text,,,\ns10,1,todo,owner,text,,,\n",
+			problems: []string{
+				`9: error: todo.owner: references unknown resource "people"`,
+				`10: error: todo.title: _id "s9" is already used on line 9`,
+				`11: error: todo.owner: field is already defined on line 9`,
+			},
+		},
+		{
+			name:    "short rows and missing system resources",
+			schemas: "s1,1,todo\n",
+			problems: []string{
+				`1: error: expected at least 5 columns (_id, _v, resource, field, type), found 3`,
+				`error: _users: resource is required but not defined`,
+				`error: _permissions: resource is required but not defined`,
+			},
+		},
+		{
+			name:     "later versions replace a row",
+			schemas:  base + "s7,1,todo,_id,text,,,^.+$\ns8,1,todo,_v,number,1,,\ns9,1,todo,title,colour,,,\ns9,2,todo,title,text,,,\n",
+			problems: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := v1alpha1.LintSchemas(strings.NewReader(test.schemas))
+			require.NoError(t, err)
+
+			got := []string{}
+			for _, p := range problems {
+				got = append(got, p.String())
+			}
+
+			require.Equal(t, test.problems, got)
+		})
+	}
+}
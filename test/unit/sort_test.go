@@ -0,0 +1,143 @@
+package unit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter"
+)
+
+func TestParseSort(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	keys, err := v1alpha1.ParseSort("-priority, description")
+	require.NoError(t, err)
+	require.Equal(t, []v1alpha1.SortKey{{Field: "priority", Desc: true}, {Field: "description"}}, keys)
+
+	keys, err = v1alpha1.ParseSort("")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	_, err = v1alpha1.ParseSort("priority,,title")
+	require.EqualError(t, err, `expected a field name, like title or -title, got ""`)
+
+	_, err = v1alpha1.ParseSort("priority,-priority")
+	require.EqualError(t, err, `field "priority" sorted by twice`)
+
+	first, err := v1alpha1.ParseNulls("first")
+	require.NoError(t, err)
+	require.True(t, first)
+
+	_, err = v1alpha1.ParseNulls("middle")
+	require.EqualError(t, err, `expected first or last, got "middle"`)
+
+	schemas := []v1alpha1.FieldSchema{
+		{Field: "_id", Type: "text"},
+		{Field: "title", Type: "text"},
+		{Field: "location", Type: "geopoint"},
+	}
+
+	require.NoError(t, v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "title"}}, Collation: "de"}.Validate(schemas))
+	require.EqualError(t, v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "year"}}}.Validate(schemas), `unknown field "year" to sort by`)
+	require.EqualError(t, v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "location"}}}.Validate(schemas), `field "location" is a geopoint, which cannot be sorted by`)
+	require.EqualError(t, v1alpha1.Sort{Collation: "not a language"}.Validate(schemas), "expected a collation of binary, nocase or a language, like en or de")
+}
+
+func TestSortRecords(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	options := readwriter.NewOptions(readwriter.WithFieldSchemas([]v1alpha1.FieldSchema{
+		{Field: "_id", Type: "text"},
+		{Field: "_v", Type: "number"},
+		{Field: "priority", Type: "number"},
+		{Field: "description", Type: "text"},
+		{Field: "tags", Type: "list"},
+		{Field: "location", Type: "geopoint"},
+	}))
+
+	records := func() []v1alpha1.Record {
+		return []v1alpha1.Record{
+			{"e", "1", "2", "zebra", "b,a", ""},
+			{"d", "1", "", "Apfel", "a", ""},
+			{"c", "1", "10", "äpfel", "a,b", ""},
+			{"b", "1", "2", "zebra", "", ""},
+			{"a", "1", "2", v1alpha1.NullValue, "b", ""},
+		}
+	}
+
+	ids := func(rs []v1alpha1.Record) []string {
+		out := []string{}
+		for _, r := range rs {
+			out = append(out, r[0])
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		sort v1alpha1.Sort
+		want []string
+	}{
+		{
+			name: "no keys keeps the order",
+			want: []string{"e", "d", "c", "b", "a"},
+		},
+		{
+			name: "ties broken by _id",
+			sort: v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "priority"}}},
+			want: []string{"a", "b", "e", "c", "d"},
+		},
+		{
+			name: "descending keeps nulls last",
+			sort: v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "priority", Desc: true}, {Field: "description"}}},
+			want: []string{"c", "b", "e", "a", "d"},
+		},
+		{
+			name: "nulls first",
+			sort: v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "priority", Desc: true}, {Field: "description"}}, NullsFirst: true},
+			want: []string{"d", "c", "a", "b", "e"},
+		},
+		{
+			name: "binary collation",
+			sort: v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "description"}}},
+			want: []string{"d", "b", "e", "c", "a"},
+		},
+		{
+			name: "case folded",
+			sort: v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "description", Desc: true}}, Collation: v1alpha1.CollationNoCase},
+			want: []string{"c", "b", "e", "d", "a"},
+		},
+		{
+			name: "language collation",
+			sort: v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "description"}}, Collation: "de"},
+			want: []string{"d", "c", "b", "e", "a"},
+		},
+		{
+			name: "lists element by element",
+			sort: v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "tags"}}},
+			want: []string{"d", "c", "a", "e", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, err := readwriter.SortRecords(records(), options, tt.sort)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, ids(rs))
+		})
+	}
+
+	_, err := readwriter.SortRecords(records(), options, v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "location"}}})
+	require.EqualError(t, err, "field 'location' is a geopoint, which cannot be sorted by")
+
+	_, err = readwriter.SortRecords(records(), options, v1alpha1.Sort{Keys: []v1alpha1.SortKey{{Field: "nope"}}})
+	require.EqualError(t, err, "field 'nope' is not a defined schema field for sorting")
+}
@@ -0,0 +1,71 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/internal/traces"
+	"go.opentelemetry.io/otel"
+)
+
+func TestTraceFileExporter(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	ctx := context.Background()
+
+	export := func(t *testing.T, ratio float64) []map[string]any {
+		path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+		shutdown, err := traces.Setup(ctx,
+			traces.WithExporter(traces.ExporterFile),
+			traces.WithFile(path),
+			traces.WithSampleRatio(ratio),
+		)
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(ctx, "work")
+		span.End()
+
+		require.NoError(t, shutdown(ctx))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		spans := []map[string]any{}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if len(line) == 0 {
+				continue
+			}
+			span := map[string]any{}
+			require.NoError(t, json.Unmarshal([]byte(line), &span))
+			spans = append(spans, span)
+		}
+
+		return spans
+	}
+
+	spans := export(t, 1)
+	require.Len(t, spans, 1)
+	require.Equal(t, "work", spans[0]["Name"])
+
+	require.Empty(t, export(t, 0))
+
+	_, err := traces.Setup(ctx, traces.WithExporter("zipkin"))
+	require.ErrorContains(t, err, `unknown trace exporter "zipkin"`)
+
+	_, err = traces.Setup(ctx, traces.WithExporter(traces.ExporterStdout), traces.WithSampleRatio(2))
+	require.Error(t, err)
+
+	// none installs no provider and needs no shutdown
+	shutdown, err := traces.Setup(ctx)
+	require.NoError(t, err)
+	require.NoError(t, shutdown(ctx))
+}
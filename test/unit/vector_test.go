@@ -0,0 +1,188 @@
+package unit
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/w-h-a/backend/api/v1alpha1"
+	"github.com/w-h-a/backend/internal/clients/readwriter/index"
+)
+
+func TestParseVectorField(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	tests := []struct {
+		name  string
+		fs    v1alpha1.FieldSchema
+		input any
+		want  []float64
+		err   bool
+	}{
+		{
+			name:  "matching dimension",
+			fs:    v1alpha1.FieldSchema{Type: "vector(3)"},
+			input: []any{0.1, 0.2, 0.3},
+			want:  []float64{0.1, 0.2, 0.3},
+		},
+		{
+			name:  "wrong dimension",
+			fs:    v1alpha1.FieldSchema{Type: "vector(3)"},
+			input: []any{0.1, 0.2},
+			err:   true,
+		},
+		{
+			name:  "not numbers",
+			fs:    v1alpha1.FieldSchema{Type: "vector(2)"},
+			input: []any{"a", "b"},
+			err:   true,
+		},
+		{
+			name:  "invalid dimension in schema",
+			fs:    v1alpha1.FieldSchema{Type: "vector(x)"},
+			input: []any{0.1},
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := v1alpha1.ParseField[[]float64](test.fs, test.input)
+			if !test.err {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+			require.Equal(t, test.want, v)
+		})
+	}
+}
+
+func TestVectorRoundTrip(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	fs := v1alpha1.FieldSchema{Field: "embedding", Type: "vector(4)"}
+
+	s, err := v1alpha1.FormatRecordField(fs, []float64{1, -0.5, 0.25, 3})
+	require.NoError(t, err)
+	require.Len(t, s, 22)
+
+	v, err := v1alpha1.FormatResourceField(fs, s)
+	require.NoError(t, err)
+	require.Equal(t, []float64{1, -0.5, 0.25, 3}, v)
+}
+
+func TestVectorIndex(t *testing.T) {
+	if len(os.Getenv("INTEGRATION")) > 0 {
+		t.Log("SKIPPING UNIT TEST")
+		return
+	}
+
+	rng := rand.New(rand.NewPCG(7, 7))
+
+	random := func() []float32 {
+		v := make([]float32, 16)
+		for i := range v {
+			v[i] = rng.Float32()*2 - 1
+		}
+		return v
+	}
+
+	exact := index.NewVector()
+	approx := index.NewVector()
+	live := map[string][]float32{}
+
+	for i := range 3000 {
+		id, v := fmt.Sprintf("v%d", i), random()
+		approx.Put(id, v)
+		live[id] = v
+		if i < 1000 {
+			exact.Put(id, v)
+		}
+	}
+
+	for i := range 200 {
+		id := fmt.Sprintf("v%d", 2000+i)
+		approx.Remove(id)
+		delete(live, id)
+	}
+
+	t.Run("exact search below the threshold", func(t *testing.T) {
+		hits := exact.Search(random(), 5, v1alpha1.MetricL2)
+		require.Len(t, hits, 5)
+		for i := 1; i < len(hits); i++ {
+			require.LessOrEqual(t, hits[i-1].Distance, hits[i].Distance)
+		}
+	})
+
+	for _, metric := range []string{v1alpha1.MetricCosine, v1alpha1.MetricL2} {
+		t.Run("hnsw recall with "+metric, func(t *testing.T) {
+			found, total := 0, 0
+
+			for range 50 {
+				q := random()
+
+				ids := make([]string, 0, len(live))
+				for id := range live {
+					ids = append(ids, id)
+				}
+				sort.Slice(ids, func(i, j int) bool {
+					return v1alpha1.VectorDistance(metric, q, live[ids[i]]) < v1alpha1.VectorDistance(metric, q, live[ids[j]])
+				})
+
+				truth := map[string]bool{}
+				for _, id := range ids[:10] {
+					truth[id] = true
+				}
+
+				hits := approx.Search(q, 10, metric)
+				require.Len(t, hits, 10)
+
+				for _, hit := range hits {
+					_, ok := live[hit.Id]
+					require.True(t, ok, "removed vector %s returned", hit.Id)
+					if truth[hit.Id] {
+						found++
+					}
+				}
+				total += len(hits)
+			}
+
+			require.GreaterOrEqual(t, float64(found)/float64(total), 0.9)
+		})
+	}
+
+	t.Run("hnsw search past removed neighbors", func(t *testing.T) {
+		q := random()
+
+		ids := make([]string, 0, len(live))
+		for id := range live {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return v1alpha1.VectorDistance(v1alpha1.MetricL2, q, live[ids[i]]) < v1alpha1.VectorDistance(v1alpha1.MetricL2, q, live[ids[j]])
+		})
+
+		// more than a search keeps, so none of those it would find is live
+		for _, id := range ids[:100] {
+			approx.Remove(id)
+			delete(live, id)
+		}
+
+		hits := approx.Search(q, 10, v1alpha1.MetricL2)
+		require.Len(t, hits, 10)
+		for _, hit := range hits {
+			_, ok := live[hit.Id]
+			require.True(t, ok, "removed vector %s returned", hit.Id)
+		}
+	})
+}